@@ -0,0 +1,142 @@
+package commands
+
+import (
+	"fmt"
+	"gocg/slcan"
+	"strconv"
+	"strings"
+)
+
+func executeAdapterCmd(args []string, outputCh chan string) error {
+	if nil == adapter {
+		return fmt.Errorf("it might be a good idea to open a serial port first")
+	}
+
+	if nil != activeSniffer {
+		return fmt.Errorf("the sniffer owns the adapter ('sniff stop' first)")
+	}
+
+	if len(args) == 1 {
+		return adapterInfo(outputCh)
+	}
+
+	var err error
+
+	switch args[1] {
+	case "info":
+		return adapterInfo(outputCh)
+
+	case "bitrate":
+		if len(args) != 3 {
+			return fmt.Errorf("syntax error ('adapter bitrate <bit/s | S0-S8>')' Args: '%+v'", args)
+		}
+		var bitrate int
+		bitrate, err = parseBitrate(args[2])
+		if err != nil {
+			return err
+		}
+		outputCh <- fmt.Sprintf("Setting CAN bitrate to %d bit/s", bitrate)
+		err = adapter.SetBitrate(bitrate)
+
+	case "open":
+		outputCh <- "Opening CAN Channel in normal mode (send/recevie)"
+		err = adapter.Open()
+
+	case "listen":
+		outputCh <- "Opening CAN Channel in listen-only mode"
+		err = adapter.OpenListenOnly()
+
+	case "close":
+		outputCh <- "Closing CAN Channel"
+		err = adapter.CloseChannel()
+
+	case "version":
+		var version string
+		version, err = adapter.Version()
+		if err != nil {
+			return err
+		}
+		outputCh <- fmt.Sprintf("Version          : %s", version)
+
+		// Not every firmware knows 'v'
+		detailed, detailedErr := adapter.DetailedVersion()
+		outputCh <- fmt.Sprintf("Detailed version : %s", replyOrError(detailed, detailedErr))
+
+	case "serial":
+		var serialNumber string
+		serialNumber, err = adapter.SerialNumber()
+		if err == nil {
+			outputCh <- fmt.Sprintf("Serial number : %s", serialNumber)
+		}
+
+	case "status":
+		var flags slcan.StatusFlags
+		flags, err = adapter.StatusFlags()
+		if err == nil {
+			outputCh <- fmt.Sprintf("Status flags : 0x%02X (%s)", byte(flags), flags)
+		}
+
+	case "timestamp":
+		if len(args) != 3 || (args[2] != "on" && args[2] != "off") {
+			return fmt.Errorf("syntax error ('adapter timestamp <on | off>')' Args: '%+v'", args)
+		}
+		outputCh <- fmt.Sprintf("Turning timestamps %s", args[2])
+		err = adapter.SetTimestamps(args[2] == "on")
+
+	default:
+		return fmt.Errorf("unknown adapter command: '%s'", args[1])
+	}
+
+	if err != nil {
+		return err
+	}
+
+	outputCh <- fmt.Sprintf("adapter %s OK", strings.Join(args[1:], " "))
+	return nil
+}
+
+// Prints everything the adapter is willing to tell about itself
+func adapterInfo(outputCh chan string) error {
+	version, err := adapter.Version()
+	outputCh <- fmt.Sprintf("Version          : %s", replyOrError(version, err))
+
+	version, err = adapter.DetailedVersion()
+	outputCh <- fmt.Sprintf("Detailed version : %s", replyOrError(version, err))
+
+	serialNumber, err := adapter.SerialNumber()
+	outputCh <- fmt.Sprintf("Serial number    : %s", replyOrError(serialNumber, err))
+
+	flags, err := adapter.StatusFlags()
+	if err != nil {
+		outputCh <- fmt.Sprintf("Status flags     : %s", err)
+	} else {
+		outputCh <- fmt.Sprintf("Status flags     : 0x%02X (%s)", byte(flags), flags)
+	}
+
+	return nil
+}
+
+func replyOrError(reply string, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return reply
+}
+
+// Accepts a bitrate in bit/s (e.g. 500000) or as SLCAN command (e.g. S6)
+func parseBitrate(arg string) (int, error) {
+	for bitrate, cmd := range slcan.Bitrates {
+		if strings.EqualFold(arg, cmd) {
+			return bitrate, nil
+		}
+	}
+
+	bitrate, err := strconv.Atoi(arg)
+	if err != nil {
+		return 0, fmt.Errorf("invalid bitrate '%s'", arg)
+	}
+	if _, ok := slcan.Bitrates[bitrate]; !ok {
+		return 0, fmt.Errorf("unsupported CAN bitrate %d. Supported bitrates are %v", bitrate, slcan.BitrateList())
+	}
+	return bitrate, nil
+}
@@ -0,0 +1,332 @@
+package commands
+
+import (
+	"fmt"
+	"gocg/cybergear"
+	"gocg/metrics"
+	"gocg/parameters"
+	"gocg/slcan"
+	"sync"
+)
+
+// Motor operations for the remote APIs (serve and the bridges). They hold the same lock as Dispatch, so remote calls
+// and console commands take turns, one command at a time.
+var commandMutex sync.Mutex
+
+type MotorStatus struct {
+	MotorId     string   `json:"motorId"`
+	Name        string   `json:"name,omitempty"`
+	Angle       float64  `json:"angle"`       // rad
+	Speed       float64  `json:"speed"`       // rad/s
+	Torque      float64  `json:"torque"`      // Nm
+	Temperature float64  `json:"temperature"` // C
+	Mode        string   `json:"mode"`
+	Faults      []string `json:"faults,omitempty"`
+}
+
+type ParameterValue struct {
+	MotorId     string   `json:"motorId"`
+	Index       string   `json:"index"`
+	Name        string   `json:"name"`
+	Type        string   `json:"type"`
+	Writable    bool     `json:"writable"`
+	Persistence string   `json:"persistence"`
+	Value       *float64 `json:"value,omitempty"`
+}
+
+var motorModes = map[slcan.MotorMode]string{
+	slcan.ResetMode:       "reset",
+	slcan.CalibrationMode: "calibration",
+	slcan.OperatingMode:   "run",
+}
+
+func statusOf(f *slcan.MotorFeedback) MotorStatus {
+	return MotorStatus{
+		MotorId:     fmt.Sprintf("%02X", f.MotorId()),
+		Name:        parameters.MotorName(f.MotorId()),
+		Angle:       float64(f.Angle()),
+		Speed:       float64(f.Speed()),
+		Torque:      float64(f.Torque()),
+		Temperature: float64(f.Temperature()),
+		Mode:        motorModes[f.Mode()],
+		Faults:      f.Faults(),
+	}
+}
+
+// Status from the last feedback frame seen
+func cachedStatus(motorId byte) (MotorStatus, error) {
+	m, ok := metrics.Motors.Snapshot()[motorId]
+	if !ok || m.Feedback == 0 {
+		return MotorStatus{}, fmt.Errorf("no feedback from motor %02X yet", motorId)
+	}
+
+	status := MotorStatus{
+		MotorId:     fmt.Sprintf("%02X", motorId),
+		Name:        parameters.MotorName(motorId),
+		Angle:       m.Angle,
+		Speed:       m.Speed,
+		Torque:      m.Torque,
+		Temperature: m.Temperature,
+		Mode:        motorModes[slcan.MotorMode(m.Mode)],
+	}
+	if len(m.Active) > 0 {
+		status.Faults = m.Active
+	}
+	return status, nil
+}
+
+// Reads the position, speed and current of the motor (type 17, documented), the temperature, mode and faults are
+// from the last feedback
+func readStatus(motorId byte) (MotorStatus, error) {
+	status, err := cachedStatus(motorId)
+	if err != nil {
+		status = MotorStatus{MotorId: fmt.Sprintf("%02X", motorId), Name: parameters.MotorName(motorId)}
+	}
+
+	status.Angle, err = readParameter(motorId, mustLookupParameter("PARAMETER_MECH_POS"))
+	if err != nil {
+		return MotorStatus{}, err
+	}
+	status.Speed, err = readParameter(motorId, mustLookupParameter("PARAMETER_MECH_VEL"))
+	if err != nil {
+		return MotorStatus{}, err
+	}
+	current, err := readParameter(motorId, mustLookupParameter("PARAMETER_IQF"))
+	if err != nil {
+		return MotorStatus{}, err
+	}
+	status.Torque = current * TORQUE_CONSTANT
+	return status, nil
+}
+
+// Status of the motors (all known motors for nil) for the streams, after decoding the frames received meanwhile.
+// Motors that don't answer are left out.
+func pollStatus(motorIds []byte) []MotorStatus {
+	commandMutex.Lock()
+	defer commandMutex.Unlock()
+
+	// Nothing to poll with, or the sniffer owns the adapter
+	if adapter == nil || activeSniffer != nil {
+		return nil
+	}
+
+	drainFrames()
+
+	if motorIds == nil {
+		motorIds = pollMotorIds()
+	}
+	var result []MotorStatus
+	for _, motorId := range motorIds {
+		status, err := readStatus(motorId)
+		if err == nil {
+			result = append(result, status)
+		}
+	}
+	return result
+}
+
+func parameterValueOf(motorId byte, p cybergear.ParameterInfo) ParameterValue {
+	return ParameterValue{
+		MotorId:     fmt.Sprintf("%02X", motorId),
+		Index:       fmt.Sprintf("0x%04X", p.Index),
+		Name:        p.Name,
+		Type:        p.Type.String(),
+		Writable:    p.Writable,
+		Persistence: p.Persistence().String(),
+	}
+}
+
+func feedbackStatus(feedback *slcan.MotorFeedback, err error) (MotorStatus, error) {
+	if feedback == nil {
+		return MotorStatus{}, err
+	}
+	return statusOf(feedback), err
+}
+
+func EnableMotor(motorId byte) (MotorStatus, error) {
+	commandMutex.Lock()
+	defer commandMutex.Unlock()
+
+	err := checkIdle(motorId)
+	if err != nil {
+		return MotorStatus{}, err
+	}
+
+	frame, err := cybergear.EnableMotorCmd(parameters.HostId, motorId)
+	if err != nil {
+		return MotorStatus{}, err
+	}
+	return feedbackStatus(requestFeedback(motorId, frame))
+}
+
+func DisableMotor(motorId byte) (MotorStatus, error) {
+	commandMutex.Lock()
+	defer commandMutex.Unlock()
+
+	err := checkIdle(motorId)
+	if err != nil {
+		return MotorStatus{}, err
+	}
+	return feedbackStatus(stopMotor(motorId))
+}
+
+// Requests a feedback frame with communication type 15, like get_status
+func GetStatus(motorId byte) (MotorStatus, error) {
+	commandMutex.Lock()
+	defer commandMutex.Unlock()
+
+	frame, err := cybergear.GetStatusCmd(parameters.HostId, motorId)
+	if err != nil {
+		return MotorStatus{}, err
+	}
+	return feedbackStatus(requestFeedback(motorId, frame))
+}
+
+// Switches to speed, position or current mode, or to motion control (mit) with zero gains, and enables the motor
+func SetMode(motorId byte, mode string) (MotorStatus, error) {
+	commandMutex.Lock()
+	defer commandMutex.Unlock()
+
+	err := checkIdle(motorId)
+	if err != nil {
+		return MotorStatus{}, err
+	}
+
+	if mode == "mit" {
+		_, _, err = motionControlSetpoints(motorId, 0, 0)
+	} else {
+		_, _, err = startLoop(motorId, mode)
+	}
+	if err != nil {
+		return MotorStatus{}, err
+	}
+	return cachedStatus(motorId)
+}
+
+// Writes the speed (rad/s), position (rad) or current (A) setpoint, checked against the limits of the profile. The
+// motor has to be in the matching mode.
+func SetSetpoint(motorId byte, kind string, value float64) (MotorStatus, error) {
+	commandMutex.Lock()
+	defer commandMutex.Unlock()
+
+	loop, ok := controlLoops[kind]
+	if !ok {
+		return MotorStatus{}, fmt.Errorf("unknown setpoint '%s' (speed | position | current)", kind)
+	}
+
+	err := checkIdle(motorId)
+	if err != nil {
+		return MotorStatus{}, err
+	}
+	err = checkLimits(motorId, loop, value)
+	if err != nil {
+		return MotorStatus{}, err
+	}
+	return feedbackStatus(writeSetpoint(motorId, loop.setpoint, value))
+}
+
+// Sends a motion control frame (type 1). The motor has to be in motion control mode (SetMode "mit").
+func MotionControl(motorId byte, angle float64, speed float64, kp float64, kd float64, torque float64) (MotorStatus, error) {
+	commandMutex.Lock()
+	defer commandMutex.Unlock()
+
+	err := checkIdle(motorId)
+	if err != nil {
+		return MotorStatus{}, err
+	}
+
+	frame, err := cybergear.MotionControlCmd(motorId, float32(angle), float32(speed), float32(kp), float32(kd), float32(torque))
+	if err != nil {
+		return MotorStatus{}, err
+	}
+	return feedbackStatus(requestFeedback(motorId, frame))
+}
+
+func ReadParam(motorId byte, name string) (ParameterValue, error) {
+	commandMutex.Lock()
+	defer commandMutex.Unlock()
+
+	p, err := cybergear.LookupParameter(name)
+	if err != nil {
+		return ParameterValue{}, err
+	}
+	return readParameterValue(motorId, p)
+}
+
+func readParameterValue(motorId byte, p cybergear.ParameterInfo) (ParameterValue, error) {
+	result := parameterValueOf(motorId, p)
+
+	value, err := readParameter(motorId, p)
+	if err != nil {
+		return ParameterValue{}, err
+	}
+	result.Value = &value
+	return result, nil
+}
+
+// Writes a run area parameter, reads it back and returns what was read
+func WriteParam(motorId byte, name string, value float64) (ParameterValue, error) {
+	commandMutex.Lock()
+	defer commandMutex.Unlock()
+
+	p, err := cybergear.LookupParameter(name)
+	if err != nil {
+		return ParameterValue{}, err
+	}
+
+	if !p.Writable {
+		return ParameterValue{}, fmt.Errorf("%s is read only", p.Name)
+	}
+	err = writeParameter(motorId, p, value)
+	if err != nil {
+		return ParameterValue{}, err
+	}
+
+	return readParameterValue(motorId, p)
+}
+
+// Output of the commands run by the remote APIs. Lines are collected while Run waits for its command and go to the
+// event subscribers otherwise (background tasks like teach keep writing after their command returned).
+var remoteOutput = struct {
+	once    sync.Once
+	ch      chan string
+	startCh chan struct{}
+	doneCh  chan chan []string
+}{ch: make(chan string), startCh: make(chan struct{}), doneCh: make(chan chan []string)}
+
+func routeRemoteOutput() {
+	var output []string
+	collecting := false
+
+	for {
+		select {
+		case line := <-remoteOutput.ch:
+			if collecting {
+				output = append(output, line)
+			} else {
+				publishOutput(line)
+			}
+		case <-remoteOutput.startCh:
+			output, collecting = nil, true
+		case reply := <-remoteOutput.doneCh:
+			// remoteOutput.ch is unbuffered, so every line the command wrote has been received by now
+			reply <- output
+			output, collecting = nil, false
+		}
+	}
+}
+
+// Runs a console command and returns its output
+func Run(command string) ([]string, error) {
+	remoteOutput.once.Do(func() { go routeRemoteOutput() })
+
+	commandMutex.Lock()
+	defer commandMutex.Unlock()
+
+	remoteOutput.startCh <- struct{}{}
+	err := dispatch(command, remoteOutput.ch)
+
+	reply := make(chan []string)
+	remoteOutput.doneCh <- reply
+	return <-reply, err
+}
@@ -0,0 +1,181 @@
+package commands
+
+import (
+	"fmt"
+	"gocg/control"
+	"gocg/cybergear"
+	"gocg/parameters"
+	"math"
+	"strconv"
+	"time"
+)
+
+const (
+	AUTOTUNE_PRE_STEP      = 300 * time.Millisecond
+	AUTOTUNE_STEP          = 1500 * time.Millisecond
+	AUTOTUNE_TARGET_ZETA   = 0.8
+	AUTOTUNE_MAX_FACTOR    = 4.0 // Max change of a gain in one autotune run
+	AUTOTUNE_SPEED_STEP    = 2.0 // rad/s
+	AUTOTUNE_POSITION_STEP = 0.5 // rad
+)
+
+type gainChange struct {
+	parameter cybergear.ParameterInfo
+	current   float64
+	proposed  float64
+}
+
+// Gains proposed by the last autotune run, written by 'autotune apply'
+var pendingAutotune struct {
+	motorId byte
+	gains   []gainChange
+}
+
+func clamp(value float64, min float64, max float64) float64 {
+	return math.Max(min, math.Min(max, value))
+}
+
+// autotune 7F speed|position [step] - step test with the current gains, proposes new gains
+// autotune apply - writes the proposed gains
+func executeAutotuneCmd(args []string, outputCh chan string) error {
+	if len(args) == 2 && args[1] == "apply" {
+		return applyAutotune(outputCh)
+	}
+
+	if len(args) != 3 && len(args) != 4 {
+		return fmt.Errorf("syntax error ('autotune <motor ID> <speed | position> [step]' or 'autotune apply')' Args: '%+v'", args)
+	}
+
+	motorId, err := parameters.MotorId(args[1])
+	if err != nil {
+		return err
+	}
+	err = checkIdle(motorId)
+	if err != nil {
+		return err
+	}
+
+	position := false
+	step := AUTOTUNE_SPEED_STEP
+	switch args[2] {
+	case "speed":
+	case "position":
+		position = true
+		step = AUTOTUNE_POSITION_STEP
+	default:
+		return fmt.Errorf("unknown loop '%s' (speed | position)", args[2])
+	}
+
+	if len(args) == 4 {
+		step, err = strconv.ParseFloat(args[3], 64)
+		if err != nil || step == 0 {
+			return fmt.Errorf("invalid step '%s'", args[3])
+		}
+	}
+
+	err = checkLimits(motorId, controlLoops[args[2]], step)
+	if err != nil {
+		return err
+	}
+
+	pendingAutotune.gains = nil
+
+	var gains []cybergear.ParameterInfo
+	if position {
+		gains = []cybergear.ParameterInfo{mustLookupParameter("PARAMETER_LOC_KP")}
+	} else {
+		gains = []cybergear.ParameterInfo{mustLookupParameter("PARAMETER_SPD_KP"), mustLookupParameter("PARAMETER_SPD_KI")}
+	}
+
+	current := make([]float64, len(gains))
+	for i, p := range gains {
+		current[i], err = readParameter(motorId, p)
+		if err != nil {
+			return err
+		}
+	}
+
+	loop, initial, err := startLoop(motorId, args[2])
+	if err != nil {
+		stopMotor(motorId)
+		return err
+	}
+
+	outputCh <- fmt.Sprintf("%s step %.3f -> %.3f %s on motor %02X", loop.name, initial, initial+step, loop.unit, motorId)
+
+	stepTime := AUTOTUNE_PRE_STEP.Seconds()
+	samples, err := recordResponse(motorId, loop, AUTOTUNE_PRE_STEP+AUTOTUNE_STEP, func(t float64) float64 {
+		if t < stepTime {
+			return initial
+		}
+		return initial + step
+	})
+	stopLoop(motorId, loop)
+	if err != nil {
+		return err
+	}
+
+	response, err := control.AnalyzeStep(samples, stepTime, initial+step)
+	if err != nil {
+		return err
+	}
+	zeta, wn, err := control.SecondOrderModel(response)
+	if err != nil {
+		return err
+	}
+
+	outputCh <- fmt.Sprintf("Samples        : %d (%.0f Hz)", len(samples), float64(len(samples))/samples[len(samples)-1].Time)
+	outputCh <- fmt.Sprintf("Overshoot      : %.1f %%", 100*response.Overshoot)
+	outputCh <- fmt.Sprintf("Rise time      : %.3f s", response.RiseTime)
+	outputCh <- fmt.Sprintf("Settling time  : %.3f s (%.0f %% band)", response.SettlingTime, 100*control.SETTLING_BAND)
+	outputCh <- fmt.Sprintf("Steady state   : %.4f error", response.SteadyStateError)
+	outputCh <- fmt.Sprintf("Model          : zeta %.2f, wn %.1f rad/s", zeta, wn)
+
+	// Speed loop: PI on an integrating plant, zeta grows with kp when wn (set by ki) is kept.
+	// Position loop: P on the speed loop as a first order lag, zeta goes with 1 / sqrt(kp).
+	var factor float64
+	if position {
+		factor = (zeta / AUTOTUNE_TARGET_ZETA) * (zeta / AUTOTUNE_TARGET_ZETA)
+	} else {
+		factor = AUTOTUNE_TARGET_ZETA / zeta
+	}
+	factor = clamp(factor, 1/AUTOTUNE_MAX_FACTOR, AUTOTUNE_MAX_FACTOR)
+
+	pendingAutotune.motorId = motorId
+	for i, p := range gains {
+		proposed := current[i]
+		if i == 0 {
+			proposed = clamp(current[i]*factor, 0, 200)
+		}
+		pendingAutotune.gains = append(pendingAutotune.gains, gainChange{parameter: p, current: current[i], proposed: proposed})
+		outputCh <- fmt.Sprintf("%-16s : %s -> %s", p.Name, p.Format(current[i]), p.Format(proposed))
+	}
+
+	// The model is fitted to the closed loop response, not to the plant, and only the damping is corrected
+	outputCh <- "[yellow]The model is a second order fit of the closed loop step response, not a plant estimate. The plant isn't identified.[-]"
+	if !position {
+		outputCh <- "[yellow]Only PARAMETER_SPD_KP is tuned, PARAMETER_SPD_KI is kept as is.[-]"
+	}
+
+	outputCh <- fmt.Sprintf("autotune %02X %s OK. Type 'autotune apply' to write the proposed gains (target zeta %.1f)", motorId, args[2], AUTOTUNE_TARGET_ZETA)
+	return nil
+}
+
+func applyAutotune(outputCh chan string) error {
+	if len(pendingAutotune.gains) == 0 {
+		return fmt.Errorf("nothing to apply, run 'autotune <motor ID> <speed | position>' first")
+	}
+
+	for _, gain := range pendingAutotune.gains {
+		err := writeParameter(pendingAutotune.motorId, gain.parameter, gain.proposed)
+		if err != nil {
+			return err
+		}
+		outputCh <- fmt.Sprintf("%s = %s", gain.parameter.Name, gain.parameter.Format(gain.proposed))
+		warnVolatile(pendingAutotune.motorId, gain.parameter, outputCh)
+	}
+
+	outputCh <- fmt.Sprintf("autotune apply %02X OK", pendingAutotune.motorId)
+	pendingAutotune.gains = nil
+	return nil
+}
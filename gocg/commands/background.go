@@ -0,0 +1,173 @@
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// A control loop running in the background at a fixed rate, one per motor (impedance, knob). Teach sessions and the
+// joint bridge register their motors too, with a loop they share and stop themselves.
+type backgroundLoop struct {
+	name        string
+	stopCommand string // e.g. 'knob 7F stop'
+	shared      bool   // Stopped by its owner, not by stopBackgroundLoop
+	stopCh      chan struct{}
+	doneCh      chan struct{}
+
+	// Written by the loop goroutine, read after it's done
+	started  time.Time
+	steps    int
+	overruns int // Steps that took longer than the loop period
+	total    time.Duration
+	min      time.Duration
+	max      time.Duration
+}
+
+var backgroundLoops = map[byte]*backgroundLoop{}
+
+func (l *backgroundLoop) running() bool {
+	select {
+	case <-l.doneCh:
+		return false
+	default:
+		return true
+	}
+}
+
+// Error if a background loop is running on the motor
+func checkIdle(motorId byte) error {
+	if l, ok := backgroundLoops[motorId]; ok && l.running() {
+		return fmt.Errorf("motor %02X is busy with %s ('%s' first)", motorId, l.name, l.stopCommand)
+	}
+	return nil
+}
+
+// Error if anything drives the motors in the background: loops, teach, the joint bridge or the exporter poller.
+// Commands that take the adapter away from them (sniff) or change which motor a name means (profile) refuse to run.
+func checkNoBackgroundTasks() error {
+	stops := map[string]bool{}
+	for _, l := range backgroundLoops {
+		if l.running() {
+			stops["'"+l.stopCommand+"'"] = true
+		}
+	}
+	if activeExporter != nil && activeExporter.polling() {
+		stops["'exporter stop'"] = true
+	}
+
+	if len(stops) == 0 {
+		return nil
+	}
+	commands := make([]string, 0, len(stops))
+	for command := range stops {
+		commands = append(commands, command)
+	}
+	sort.Strings(commands)
+	return fmt.Errorf("background tasks are driving the motors (%s first)", strings.Join(commands, ", "))
+}
+
+// Calls step at the given rate until the loop is stopped or step fails. A failing step disables the motor.
+func startBackgroundLoop(motorId byte, name string, rate float64, step func() error, outputCh chan string) error {
+	err := checkIdle(motorId)
+	if err != nil {
+		return err
+	}
+	if rate <= 0 {
+		return fmt.Errorf("invalid rate %g Hz", rate)
+	}
+
+	l := &backgroundLoop{
+		name:        name,
+		stopCommand: fmt.Sprintf("%s %02X stop", name, motorId),
+		stopCh:      make(chan struct{}),
+		doneCh:      make(chan struct{}),
+		started:     time.Now(),
+	}
+	backgroundLoops[motorId] = l
+
+	go func() {
+		defer close(l.doneCh)
+
+		period := time.Duration(float64(time.Second) / rate)
+		ticker := time.NewTicker(period)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-l.stopCh:
+				return
+			case <-ticker.C:
+			}
+
+			start := time.Now()
+			err := step()
+			l.record(time.Since(start), period)
+			if err != nil {
+				stopMotor(motorId)
+				outputCh <- fmt.Sprintf("[red]%s %02X stopped: %s. Motor disabled[-]", name, motorId, err)
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (l *backgroundLoop) record(duration time.Duration, period time.Duration) {
+	if l.steps == 0 || duration < l.min {
+		l.min = duration
+	}
+	if duration > l.max {
+		l.max = duration
+	}
+	if duration > period {
+		l.overruns++
+	}
+	l.steps++
+	l.total += duration
+}
+
+// Achieved loop rate and step (request to feedback) times
+func (l *backgroundLoop) summary() string {
+	if l.steps == 0 {
+		return "no steps"
+	}
+	return fmt.Sprintf("%d steps at %.0f Hz, step time min %s, mean %s, max %s, %d overruns", l.steps,
+		float64(l.steps)/time.Since(l.started).Seconds(), l.min, l.total/time.Duration(l.steps), l.max, l.overruns)
+}
+
+// Stops the loop of the motor, if any, and disables the motor. Returns the loop summary.
+func stopBackgroundLoop(motorId byte) (string, error) {
+	l, ok := backgroundLoops[motorId]
+	if !ok {
+		return "", fmt.Errorf("no background loop running on motor %02X", motorId)
+	}
+	if l.shared {
+		return "", fmt.Errorf("motor %02X is busy with %s ('%s' first)", motorId, l.name, l.stopCommand)
+	}
+
+	if l.running() {
+		close(l.stopCh)
+		<-l.doneCh
+	}
+	delete(backgroundLoops, motorId)
+
+	_, err := stopMotor(motorId)
+	return l.summary(), err
+}
+
+func stopBackgroundLoops(outputCh chan string) {
+	for motorId, l := range backgroundLoops {
+		if l.shared {
+			continue
+		}
+		summary, err := stopBackgroundLoop(motorId)
+		if err != nil {
+			outputCh <- fmt.Sprintf("[red]%s %02X: %s[-]", l.name, motorId, err)
+			continue
+		}
+		outputCh <- fmt.Sprintf("%s %02X stopped: %s", l.name, motorId, summary)
+	}
+}
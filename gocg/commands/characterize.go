@@ -0,0 +1,231 @@
+package commands
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"gocg/control"
+	"gocg/parameters"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	CHARACTERIZE_PRE_STEP         = 300 * time.Millisecond
+	CHARACTERIZE_STEP_DURATION    = 2.0  // s
+	CHARACTERIZE_CHIRP_DURATION   = 10.0 // s
+	CHARACTERIZE_CHIRP_F0         = 0.5  // Hz
+	CHARACTERIZE_CHIRP_F1         = 10.0 // Hz
+	CHARACTERIZE_BODE_FREQUENCIES = 30
+)
+
+// Default step / chirp amplitude per mode
+var characterizeAmplitudes = map[string]float64{
+	"speed":    2,   // rad/s
+	"position": 0.5, // rad
+	"current":  1,   // A
+}
+
+func writeCsv(path string, header []string, rows [][]string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w := csv.NewWriter(file)
+	w.Write(header)
+	w.WriteAll(rows)
+	return w.Error()
+}
+
+// Empty for NaN, e.g. the settling time of a response that didn't settle
+func formatFloat(value float64) string {
+	if math.IsNaN(value) {
+		return ""
+	}
+	return strconv.FormatFloat(value, 'g', 6, 64)
+}
+
+func writeSamplesCsv(path string, samples []control.Sample) error {
+	rows := make([][]string, len(samples))
+	for i, s := range samples {
+		rows[i] = []string{formatFloat(s.Time), formatFloat(s.Reference), formatFloat(s.Value)}
+	}
+	return writeCsv(path, []string{"time_s", "reference", "value"}, rows)
+}
+
+// characterize 7F speed step [file] [--amplitude a] [--duration s] [--f0 Hz] [--f1 Hz]
+func executeCharacterizeCmd(args []string, outputCh chan string) error {
+	positional, opts, err := parseOptions(args, "amplitude", "duration", "f0", "f1")
+	if err != nil {
+		return err
+	}
+
+	if len(positional) != 4 && len(positional) != 5 {
+		return fmt.Errorf("syntax error ('characterize <motor ID> <speed | position | current> <step | chirp> [file] [--amplitude a] [--duration s] [--f0 Hz] [--f1 Hz]')' Args: '%+v'", args)
+	}
+
+	motorId, err := parameters.MotorId(positional[1])
+	if err != nil {
+		return err
+	}
+	err = checkIdle(motorId)
+	if err != nil {
+		return err
+	}
+
+	mode, test := positional[2], positional[3]
+	if _, ok := controlLoops[mode]; !ok {
+		return fmt.Errorf("unknown mode '%s' (speed | position | current)", mode)
+	}
+
+	chirp := false
+	duration := CHARACTERIZE_STEP_DURATION
+	switch test {
+	case "step":
+	case "chirp":
+		chirp = true
+		duration = CHARACTERIZE_CHIRP_DURATION
+	default:
+		return fmt.Errorf("unknown test '%s' (step | chirp)", test)
+	}
+
+	amplitude, err := opts.float("amplitude", characterizeAmplitudes[mode])
+	if err != nil {
+		return err
+	}
+	duration, err = opts.float("duration", duration)
+	if err != nil {
+		return err
+	}
+	f0, err := opts.float("f0", CHARACTERIZE_CHIRP_F0)
+	if err != nil {
+		return err
+	}
+	f1, err := opts.float("f1", CHARACTERIZE_CHIRP_F1)
+	if err != nil {
+		return err
+	}
+
+	if duration <= 0 || amplitude == 0 || f0 <= 0 || f1 <= f0 {
+		return fmt.Errorf("invalid options: amplitude %g, duration %g s, f0 %g Hz, f1 %g Hz", amplitude, duration, f0, f1)
+	}
+
+	err = checkLimits(motorId, controlLoops[mode], amplitude)
+	if err != nil {
+		return err
+	}
+
+	path := fmt.Sprintf("characterize-%02X-%s-%s.csv", motorId, mode, test)
+	if len(positional) == 5 {
+		path = positional[4]
+	}
+	base := strings.TrimSuffix(path, ".csv")
+
+	loop, initial, err := startLoop(motorId, mode)
+	if err != nil {
+		stopMotor(motorId)
+		return err
+	}
+
+	preStep := CHARACTERIZE_PRE_STEP.Seconds()
+	total := CHARACTERIZE_PRE_STEP + time.Duration(duration*float64(time.Second))
+
+	outputCh <- fmt.Sprintf("%s %s on motor %02X: amplitude %g %s, %.1f s", mode, test, motorId, amplitude, loop.unit, duration)
+
+	samples, err := recordResponse(motorId, loop, total, func(t float64) float64 {
+		if t < preStep {
+			return initial
+		}
+		if chirp {
+			return initial + amplitude*control.Chirp(t-preStep, f0, f1, duration)
+		}
+		return initial + amplitude
+	})
+	stopLoop(motorId, loop)
+	if err != nil {
+		return err
+	}
+
+	err = writeSamplesCsv(path, samples)
+	if err != nil {
+		return err
+	}
+
+	rate := float64(len(samples)) / samples[len(samples)-1].Time
+	outputCh <- fmt.Sprintf("Samples        : %d (%.0f Hz) -> %s", len(samples), rate, path)
+
+	if !chirp {
+		response, err := control.AnalyzeStep(samples, preStep, initial+amplitude)
+		if errors.Is(err, control.ErrNotSettled) {
+			// The recording is still worth a look, the settling time stays empty
+			outputCh <- fmt.Sprintf("[yellow]%s, try a longer --duration[-]", err)
+		} else if err != nil {
+			return err
+		}
+
+		metrics := [][]string{
+			{"rise_time", formatFloat(response.RiseTime), "s"},
+			{"peak_time", formatFloat(response.PeakTime), "s"},
+			{"overshoot", formatFloat(100 * response.Overshoot), "%"},
+			{"settling_time", formatFloat(response.SettlingTime), "s"},
+			{"steady_state_error", formatFloat(response.SteadyStateError), loop.unit},
+			{"sample_rate", formatFloat(rate), "Hz"},
+		}
+		for _, m := range metrics {
+			outputCh <- fmt.Sprintf("%-18s : %s %s", m[0], m[1], m[2])
+		}
+
+		err = writeCsv(base+"-metrics.csv", []string{"metric", "value", "unit"}, metrics)
+		if err != nil {
+			return err
+		}
+
+		outputCh <- fmt.Sprintf("characterize %02X %s step OK -> %s, %s-metrics.csv", motorId, mode, path, base)
+		return nil
+	}
+
+	// Only frequencies well below the Nyquist frequency of the achieved sample rate
+	f1 = math.Min(f1, rate/4)
+	if f0 >= f1 {
+		return fmt.Errorf("f0 %g Hz is not below %g Hz, a quarter of the %.0f Hz sample rate", f0, f1, rate)
+	}
+	points, err := control.EstimateFrequencyResponse(samples[firstSampleAfter(samples, preStep):], control.LogFrequencies(f0, f1, CHARACTERIZE_BODE_FREQUENCIES))
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, len(points))
+	for i, p := range points {
+		rows[i] = []string{formatFloat(p.Frequency), formatFloat(p.Magnitude), formatFloat(p.Phase)}
+		outputCh <- fmt.Sprintf("%8.2f Hz : %7.2f dB %7.1f deg", p.Frequency, p.Magnitude, p.Phase)
+	}
+
+	err = writeCsv(base+"-bode.csv", []string{"frequency_hz", "magnitude_db", "phase_deg"}, rows)
+	if err != nil {
+		return err
+	}
+
+	bandwidth := control.Bandwidth(points)
+	if math.IsNaN(bandwidth) {
+		outputCh <- fmt.Sprintf("Bandwidth      : above %.1f Hz", f1)
+	} else {
+		outputCh <- fmt.Sprintf("Bandwidth      : %.2f Hz (-3 dB)", bandwidth)
+	}
+
+	outputCh <- fmt.Sprintf("characterize %02X %s chirp OK -> %s, %s-bode.csv", motorId, mode, path, base)
+	return nil
+}
+
+func firstSampleAfter(samples []control.Sample, t float64) int {
+	for i, s := range samples {
+		if s.Time >= t {
+			return i
+		}
+	}
+	return len(samples)
+}
@@ -3,128 +3,294 @@ package commands
 import (
 	"fmt"
 	"gocg/cybergear"
+	"gocg/metrics"
 	"gocg/parameters"
 	"gocg/slcan"
+	"io"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/tarm/serial"
 )
 
-var serialPort *serial.Port
+var adapter *slcan.Adapter
 
+// Opens the serial port of the adapter. Tests put the host port of a virtual bus here.
+var openPort = func(config *serial.Config) (io.ReadWriteCloser, error) {
+	return serial.OpenPort(config)
+}
+
+// Serializes requests from the console and from background loops (teach etc), one request and its reply at a time
+var busMutex sync.Mutex
+
+// How long drainFrames waits for more frames
+const DRAIN_TIMEOUT = time.Millisecond
+
+// Reads and decodes frames until the bus has been quiet for the read timeout of the active profile
 func ReadFrame(outputCh chan string) error {
-	var frameBuffer []byte
-	readBuffer := make([]byte, 32)
-	var n int
+	return readFrames(outputCh, nil)
+}
+
+// ReadFrame, calling received when the first frame arrives
+func readFrames(outputCh chan string, received func()) error {
+	_, profile := parameters.ActiveProfile()
+
 	for {
-		n, _ = serialPort.Read(readBuffer)
-		if n > 0 {
-			frameBuffer = append(frameBuffer, readBuffer[:n]...)
-		} else {
-			break
+		frameBuffer, err := readFrameLine(profile.ReadTimeout)
+		if err == slcan.ErrNoReply {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if received != nil {
+			received()
+			received = nil
 		}
-	}
 
-	if len(frameBuffer) > 0 {
 		// outputCh <- fmt.Sprintf("RX (hex)  : %+v", frameBuffer)
 		// outputCh <- fmt.Sprintf("RX (ascii): %s", frameBuffer)
 
 		frame, err := slcan.HandleIncomingFrame(frameBuffer)
 		if err != nil {
+			metrics.Bus.DecodeFailed()
 			outputCh <- ">>> Not able to decode response frame - yet <<<"
 			outputCh <- fmt.Sprintf(">>> %s <<<", err.Error())
+			if info, err := slcan.Inspect(frameBuffer); err == nil {
+				outputCh <- fmt.Sprintf(">>> %s <<<", info.Describe(parameters.HostId))
+			}
 		} else {
+			observeFrame(frame)
 			outputCh <- frame.String()
 		}
 	}
-
-	return nil
 }
 
-func SendSLCommand(txBuffer []byte, outputCh chan string) error {
-	if nil == serialPort {
-		return fmt.Errorf("it might be a good idea to open a serial port first")
+// Keeps the motor state for the metrics exporter and hands the frame to the event subscribers
+func observeFrame(frame slcan.Frame) {
+	publishFrame(frame)
+
+	switch f := frame.(type) {
+	case *slcan.MotorFeedback:
+		metrics.Motors.Feedback(f.MotorId(), float64(f.Angle()), float64(f.Speed()), float64(f.Torque()), float64(f.Temperature()),
+			int(f.Mode()), f.Faults())
+	case *slcan.ParameterFrame:
+		if value, ok := f.Value(); ok {
+			if p, ok := cybergear.ParameterByIndex(f.Index()); ok {
+				metrics.Motors.Parameter(f.MotorId(), p.Name, value)
+			}
+		}
 	}
+}
 
-	outputCh <- "Setting CAN bitrate to 1Mbit"
-	outputCh <- fmt.Sprintf("TX (hex)   : %+v", txBuffer)
-	outputCh <- fmt.Sprintf("TX (ascii) : %+s", txBuffer)
-
-	serialPort.Write(txBuffer)
-
-	err := ReadFrame(outputCh)
+// Reads the next frame line and counts it in the bus statistics
+func readFrameLine(timeout time.Duration) ([]byte, error) {
+	line, err := adapter.ReadFrameLine(timeout)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return nil
+
+	metrics.Bus.FrameReceived(slcan.FrameBits(line))
+	if slcan.Truncated(line) {
+		metrics.Bus.Overrun()
+	}
+	return line, nil
 }
 
-func SendFrame(frame *cybergear.SLCanFrame, outputCh chan string) error {
-	bytesToSend := frame.Serialize()
-	bytesToSend = append(bytesToSend, '\r')
+func SendFrame(frame *cybergear.Frame, outputCh chan string) error {
+	return SendLine(slcan.Encode(frame), outputCh)
+}
 
+// Sends an SLCAN frame line (without CR) and decodes the response
+func SendLine(bytesToSend []byte, outputCh chan string) error {
 	// outputCh <- fmt.Sprintf("Sending frame : %+v", bytesToSend)
 
-	if nil == serialPort {
+	if nil == adapter {
 		return fmt.Errorf("it might be a good idea to open a serial port first")
 	}
 
+	if nil != activeSniffer {
+		return fmt.Errorf("the adapter is in listen-only mode ('sniff stop' first)")
+	}
+
 	// outputCh <- fmt.Sprintf("TX (hex)  : %+v", bytesToSend)
 	// outputCh <- fmt.Sprintf("TX (ascii): %+s", bytesToSend)
 
-	n, err := serialPort.Write(bytesToSend)
+	busMutex.Lock()
+	defer busMutex.Unlock()
+
+	// Drop stale input so that what we read next is the response to this frame
+	metrics.Bus.Dropped(adapter.Flush())
+
+	start := metrics.Bus.Request()
+	err := adapter.WriteLine(bytesToSend)
 	if err != nil {
 		return err
 	}
-	if n != len(bytesToSend) {
-		return fmt.Errorf("error sending frame. %d bytes sent of %d", n, len(bytesToSend))
-	}
-	serialPort.Flush()
+	metrics.Bus.FrameSent(slcan.FrameBits(bytesToSend))
 
-	err = ReadFrame(outputCh)
+	answered := false
+	err = readFrames(outputCh, func() {
+		metrics.Bus.Answered(start)
+		answered = true
+	})
 
 	if err != nil {
 		return err
 	}
 
+	if !answered {
+		metrics.Bus.Unanswered()
+	}
+
 	return nil
 }
 
+// Sends a frame and waits for the first decoded frame accepted by match. Frames that don't match are dropped.
+func Request(frame *cybergear.Frame, match func(slcan.Frame) bool) (slcan.Frame, error) {
+	var reply slcan.Frame
+
+	err := Collect(frame, func(f slcan.Frame) bool {
+		if match(f) {
+			reply = f
+			return true
+		}
+		return false
+	})
+
+	return reply, err
+}
+
+// Sends a frame and hands every decoded frame to handle until it returns true. Returns an error if the bus goes quiet
+// before that.
+func Collect(frame *cybergear.Frame, handle func(slcan.Frame) bool) error {
+	if nil == adapter {
+		return fmt.Errorf("it might be a good idea to open a serial port first")
+	}
+
+	if nil != activeSniffer {
+		return fmt.Errorf("the adapter is in listen-only mode ('sniff stop' first)")
+	}
+
+	_, profile := parameters.ActiveProfile()
+
+	busMutex.Lock()
+	defer busMutex.Unlock()
+
+	metrics.Bus.Dropped(adapter.Flush())
+
+	line := slcan.Encode(frame)
+	start := metrics.Bus.Request()
+	err := adapter.WriteLine(line)
+	if err != nil {
+		return err
+	}
+	metrics.Bus.FrameSent(slcan.FrameBits(line))
+
+	for {
+		frameBuffer, err := readFrameLine(profile.ReadTimeout)
+		if err == slcan.ErrNoReply {
+			metrics.Bus.Unanswered()
+			return fmt.Errorf("no reply from motor %02X", frame.TargetId())
+		}
+		if err != nil {
+			return err
+		}
+
+		reply, err := slcan.HandleIncomingFrame(frameBuffer)
+		if err != nil {
+			metrics.Bus.DecodeFailed()
+			continue
+		}
+		observeFrame(reply)
+		if handle(reply) {
+			metrics.Bus.Answered(start)
+			return nil
+		}
+	}
+}
+
+// Decodes the frames received since the last request instead of flushing them unseen. That's how frames the motors
+// send on their own, like fault reports (type 21), reach the event subscribers.
+func drainFrames() {
+	busMutex.Lock()
+	defer busMutex.Unlock()
+
+	for {
+		frameBuffer, err := readFrameLine(DRAIN_TIMEOUT)
+		if err != nil {
+			return
+		}
+
+		frame, err := slcan.HandleIncomingFrame(frameBuffer)
+		if err != nil {
+			metrics.Bus.DecodeFailed()
+			continue
+		}
+		observeFrame(frame)
+	}
+}
+
 type dispatchFunc func(args []string, outputCh chan string) error
 
 func executeHelpCmd(args []string, outputCh chan string) error {
 	outputCh <- "Commands:"
-	outputCh <- "\topen <serial port name> - opens serial port"
+	outputCh <- "\topen [serial port name] - opens serial port (defaults to the adapter of the active profile)"
 	outputCh <- "\tclose - close serial port"
 	outputCh <- "\tenable <motor CAN id> - enable motor."
 	outputCh <- "\tdisable <motor CAN id> - disable / stop motor."
 	outputCh <- "\tset_speed <motor CAN id> <rad/s> - set motor speed (-30~30rad/s)."
-	outputCh <- "\tread_current <motor CAN id>"
+	outputCh <- "\tset_current <motor CAN id> <A> - set motor current (-23~23A)."
+	outputCh <- "\tget_status <motor CAN id> - poll motor status."
+	outputCh <- "\tprofile [name] - show or switch configuration profile."
+	outputCh <- "\tadapter [info | version | serial | status] - query the SLCAN adapter."
+	outputCh <- "\tadapter bitrate <bit/s | S0-S8> - set CAN bitrate (CAN channel must be closed)."
+	outputCh <- "\tadapter <open | listen | close> - open CAN channel (normal / listen-only) or close it."
+	outputCh <- "\tadapter timestamp <on | off> - turn frame timestamps on or off (CAN channel must be closed)."
+	outputCh <- "\tsniff [serial port name] [--motor <id>]... [--type <n>]... - monitor the bus in listen-only mode."
+	outputCh <- "\tsniff <stats | stop> - show frame counters or stop sniffing."
+	outputCh <- "\traw <T<id><dlc><data> | t<id><dlc><data>> - send an SLCAN frame line as is."
+	outputCh <- "\tcg <type> <host id> <motor id> <data16> [payload hex] - send a CyberGear frame built from its fields."
+	outputCh <- "\tdump <motor CAN id> <file> - save the run area parameters (0x70xx) to a JSON (or .yaml) file."
+	outputCh <- "\trestore <motor CAN id> <file> [--dry-run] - write back the run area parameters that differ from the file (volatile)."
+	outputCh <- "\tdiff <motor CAN id> <motor CAN id> [motor CAN id]... - compare the run area configuration of two or more motors."
+	outputCh <- "\tinfo <motor CAN id> - show the MCU id (device id, type 0)."
+	outputCh <- "\tparam <motor CAN id> <name | index> [value] - read or write a single run area parameter (0x70xx)."
+	outputCh <- "\tautotune <motor CAN id> <speed | position> [step] - step test and gain proposal. 'autotune apply' writes the gains."
+	outputCh <- "\tcharacterize <motor CAN id> <speed | position | current> <step | chirp> [file] [--amplitude a] [--duration s] [--f0 Hz] [--f1 Hz] - measure the loop response to CSV."
+	outputCh <- "\tmove <motor CAN id> <rad> --vmax <rad/s> --amax <rad/s2> [--jmax <rad/s3>] [--rate Hz] [--via loc | mit] - profiled move to an angle."
+	outputCh <- "\tplay <file> [--interp linear | cubic] [--loop N] [--speed S] [--rate Hz] [--via loc | mit] - play back a waypoint CSV file."
+	outputCh <- "\tteach <motor CAN id>... [--file f] [--rate Hz] [--mode disabled | mit] [--kp kp] [--kd kd] - record hand guided motion to a waypoint file, 'teach mark' or '.' marks a keyframe, 'teach stop' saves."
+	outputCh <- "\timpedance <motor CAN id> [--at rad] [--kp kp] [--kd kd] [--offset Nm] [--coulomb Nm] [--viscous Nm/(rad/s)] [--rate Hz] - virtual spring, damper and friction compensation in the background, 'impedance <id> stop' stops."
+	outputCh <- "\tknob <motor CAN id> --detents N --strength Nm [--endstops a,b] [--stiffness Nm/rad] [--friction Nm] [--damping Nm/(rad/s)] [--via iq | mit] [--rate Hz] - haptic knob in the background, 'knob <id> stop' stops and prints the loop timing."
+	outputCh <- "\tstats [reset | export <file.json | file.yaml>] - request latency, jitter, frame rates, unanswered requests and overruns."
+	outputCh <- "\texporter start [address] [--poll s] | exporter stop - Prometheus /metrics endpoint (default 127.0.0.1:9180), the temperature from feedback frames only."
+	outputCh <- "\tserve [address] | serve stop - REST API on /api/ and WebSocket event stream on /api/events (default 127.0.0.1:8080)."
+	outputCh <- "\tgrpc [address] | grpc stop - CyberGear gRPC service (default 127.0.0.1:50051)."
+	outputCh <- "\tmqtt start <broker> [--prefix p] [--qos n] [--client id] [--user u --password p] | mqtt stop - MQTT bridge."
+	outputCh <- "\tjoints start [address|unix:path] [--peer address] [--rate hz] [--kp kp] [--kd kd] | joints stop - joint state and trajectory bridge (default 127.0.0.1:9870)."
+	outputCh <- "Motors can be given by CAN id (hex) or by name from the active profile."
 	//	outputCh <- "\tmode <motor CAN id> <speed | position | current> - set operation mode"
 
 	return nil
 }
 
 func executeEnableCmd(args []string, outputCh chan string) error {
-	var frame *cybergear.SLCanFrame
+	var frame *cybergear.Frame
 
 	if len(args) != 2 {
 		return fmt.Errorf("syntax error ('enable <motor ID>')' Args: '%+v'", args)
 	}
 
-	motorId, err := strconv.ParseUint(args[1], 16, 8)
-	if err != nil {
-		return fmt.Errorf("syntax error: <motor ID>: '%s'", args[1])
-	}
-
+	motorId, err := parameters.MotorId(args[1])
 	if err != nil {
-		return err
+		return fmt.Errorf("syntax error: <motor ID>: %s", err)
 	}
 
 	outputCh <- fmt.Sprintf("Enabling motor (CAN id: %02X)", motorId)
-	frame, err = cybergear.EnableMotorCmd(parameters.HostId, byte(motorId))
+	frame, err = cybergear.EnableMotorCmd(parameters.HostId, motorId)
 	if err != nil {
 		return err
 	}
@@ -144,14 +310,14 @@ func executeDisableCmd(args []string, outputCh chan string) error {
 		return fmt.Errorf("syntax error ('disable <motor ID>')' Args: '%+v'", args)
 	}
 
-	motorId, err := strconv.ParseUint(args[1], 16, 8)
+	motorId, err := parameters.MotorId(args[1])
 	if err != nil {
-		return fmt.Errorf("syntax error: disable <motor ID>: '%s'", args[1])
+		return fmt.Errorf("syntax error: disable <motor ID>: %s", err)
 	}
 
 	outputCh <- fmt.Sprintf("Disabling %02X", motorId)
 
-	frame, err := cybergear.DisableMotorCmd(parameters.HostId, byte(motorId))
+	frame, err := cybergear.DisableMotorCmd(parameters.HostId, motorId)
 
 	if err != nil {
 		return err
@@ -168,37 +334,123 @@ func executeDisableCmd(args []string, outputCh chan string) error {
 }
 
 func executeOpenCmd(args []string, outputCh chan string) error {
-	var err error
+	_, profile := parameters.ActiveProfile()
 
-	if len(args) != 2 {
-		return fmt.Errorf("syntax error ('open <serial port name>')' Args: '%+v'", args)
+	portName := profile.Adapter
+	switch len(args) {
+	case 1:
+		if portName == "" {
+			return fmt.Errorf("no adapter in the active profile ('open <serial port name>')")
+		}
+	case 2:
+		portName = args[1]
+	default:
+		return fmt.Errorf("syntax error ('open [serial port name]')' Args: '%+v'", args)
 	}
 
-	serialConfig := &serial.Config{Name: args[1], Baud: 115200, Size: 8, Parity: serial.ParityNone, StopBits: 1, ReadTimeout: time.Millisecond * 100}
+	if nil != adapter {
+		return fmt.Errorf("serial port already open. Close it first")
+	}
 
-	outputCh <- fmt.Sprintf("Opening %s", args[1])
+	err := openAdapter(portName, outputCh)
+	if err != nil {
+		return err
+	}
 
-	serialPort, err = serial.OpenPort(serialConfig)
+	outputCh <- "Opening CAN Channel in normal mode (send/recevie)"
 
+	err = adapter.Open()
 	if err != nil {
-		return fmt.Errorf("unable to open %s. Error %s", args[0], err)
+		closeAdapter()
+		return err
+	}
+
+	outputCh <- fmt.Sprintf("Open %s OK", portName)
+
+	return nil
+}
+
+// Opens the serial port, sets the CAN bitrate and reports the adapter version. The CAN channel is left closed.
+func openAdapter(portName string, outputCh chan string) error {
+	_, profile := parameters.ActiveProfile()
+
+	if _, ok := slcan.Bitrates[profile.Bitrate]; !ok {
+		return fmt.Errorf("unsupported CAN bitrate %d. Supported bitrates are %v", profile.Bitrate, slcan.BitrateList())
 	}
 
-	err = SendSLCommand([]byte{'S', '8', '\r'}, outputCh)
+	serialConfig := &serial.Config{Name: portName, Baud: profile.SerialBaud, Size: 8, Parity: serial.ParityNone, StopBits: 1, ReadTimeout: profile.ReadTimeout}
+
+	outputCh <- fmt.Sprintf("Opening %s", portName)
+
+	serialPort, err := openPort(serialConfig)
 	if err != nil {
-		return err
+		return fmt.Errorf("unable to open %s. Error %s", portName, err)
 	}
 
-	time.Sleep(20 * time.Millisecond)
+	adapter = slcan.NewAdapter(serialPort)
+	adapter.SetReplyTimeout(profile.ReadTimeout)
 
-	outputCh <- "Opening CAN Channel in normal mode (send/recevie)"
+	// Make sure we start from a known state in case the channel was left open
+	adapter.CloseChannel()
+
+	version, err := adapter.Version()
+	if err != nil {
+		outputCh <- fmt.Sprintf("Adapter version : unknown (%s)", err)
+	} else {
+		outputCh <- fmt.Sprintf("Adapter version : %s", version)
+	}
+
+	outputCh <- fmt.Sprintf("Setting CAN bitrate to %d bit/s", profile.Bitrate)
 
-	err = SendSLCommand([]byte{'O', '\r'}, outputCh)
+	err = adapter.SetBitrate(profile.Bitrate)
 	if err != nil {
+		closeAdapter()
 		return err
 	}
 
-	outputCh <- fmt.Sprintf("Open %s OK", args[1])
+	time.Sleep(20 * time.Millisecond)
+
+	return nil
+}
+
+func closeAdapter() {
+	adapter.Close()
+	adapter = nil
+}
+
+func executeProfileCmd(args []string, outputCh chan string) error {
+	switch len(args) {
+	case 1:
+	case 2:
+		if nil != adapter {
+			return fmt.Errorf("close the serial port before switching profile")
+		}
+		err := checkNoBackgroundTasks()
+		if err != nil {
+			return err
+		}
+		err = parameters.UseProfile(args[1])
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("syntax error ('profile [name]')' Args: '%+v'", args)
+	}
+
+	if parameters.LoadedConfig != nil {
+		outputCh <- fmt.Sprintf("Profiles  : %s", strings.Join(parameters.LoadedConfig.ProfileNames(), ", "))
+	}
+
+	name, profile := parameters.ActiveProfile()
+	outputCh <- fmt.Sprintf("Profile   : %s", name)
+	outputCh <- fmt.Sprintf("Adapter   : %s (%s, %d baud)", profile.Adapter, profile.Transport, profile.SerialBaud)
+	outputCh <- fmt.Sprintf("Bitrate   : %d bit/s", profile.Bitrate)
+	outputCh <- fmt.Sprintf("Host id   : 0x%02X", profile.HostId)
+	for _, motorName := range parameters.MotorNames() {
+		motor := profile.Motors[motorName]
+		outputCh <- fmt.Sprintf("Motor     : %-12s 0x%02X  limits: speed %.2f rad/s, current %.2f A, torque %.2f Nm",
+			motorName, motor.Id, motor.Limits.Speed, motor.Limits.Current, motor.Limits.Torque)
+	}
 
 	return nil
 }
@@ -208,39 +460,74 @@ func executeCloseCmd(args []string, outputCh chan string) error {
 		return fmt.Errorf("syntax error ('close')' Args: '%s'", args)
 	}
 
+	if nil == adapter {
+		outputCh <- "No worries, I'll close the serial port you never bothered to open in the first place..."
+		return nil
+	}
+
+	stopBackgroundLoops(outputCh)
+
+	if nil != activeJoints {
+		stopJoints(outputCh)
+		outputCh <- "joint bridge stopped"
+	}
+
+	if nil != activeExporter {
+		stopExporter()
+		outputCh <- "exporter stopped"
+	}
+
+	if nil != activeTeach {
+		err := stopTeach(outputCh)
+		if err != nil {
+			outputCh <- fmt.Sprintf("[red]%s[-]", err)
+		}
+	}
+
+	if nil != activeSniffer {
+		stopSniffer(outputCh)
+		if nil == adapter {
+			outputCh <- "Close serial port OK"
+			return nil
+		}
+	}
+
 	outputCh <- "Closing CAN Channel"
 
-	openCANcmd := []byte{'C', '\r'}
-	serialPort.Write(openCANcmd)
+	err := adapter.CloseChannel()
+	if err != nil {
+		outputCh <- err.Error()
+	}
 
 	outputCh <- "Closing serial port"
 
-	if serialPort != nil {
-		serialPort.Close()
-	} else {
-		outputCh <- "No worries, I'll close the serial port you never bothered to open in the first place..."
-	}
+	closeAdapter()
 
 	outputCh <- "Close serial port OK"
 	return nil
 }
 
 func executeSetSpeedCmd(args []string, outputCh chan string) error {
-	var frame *cybergear.SLCanFrame
+	var frame *cybergear.Frame
 	var err error
-	var motorId int64
+	var motorId byte
 
 	if len(args) != 3 {
 		return fmt.Errorf("syntax error ('set_speed <motorId> <rad/s>')' Args: '%+v'", args)
 	}
 
-	motorId, err = strconv.ParseInt(args[1], 16, 8)
+	motorId, err = parameters.MotorId(args[1])
+	if err != nil {
+		return err
+	}
+
+	err = checkIdle(motorId)
 	if err != nil {
 		return err
 	}
 
 	outputCh <- fmt.Sprintf("Setting run mode to [red]SPEED MODE[-] for motor %02X", motorId)
-	frame, err = cybergear.SetRunMode(parameters.HostId, byte(motorId), cybergear.SPEED_MODE)
+	frame, err = cybergear.SetRunMode(parameters.HostId, motorId, cybergear.SPEED_MODE)
 	if err != nil {
 		return err
 	}
@@ -260,8 +547,13 @@ func executeSetSpeedCmd(args []string, outputCh chan string) error {
 		return fmt.Errorf("invalid speed parameter: %2.2f. Valid values are in the interval [-30,30] rad/s", speed)
 	}
 
+	limits := parameters.MotorLimits(motorId)
+	if limits.Speed > 0 && (speed < -limits.Speed || speed > limits.Speed) {
+		return fmt.Errorf("speed %2.2f rad/s exceeds the configured limit of %2.2f rad/s for motor %02X", speed, limits.Speed, motorId)
+	}
+
 	outputCh <- fmt.Sprintf("Setting current speed to %2.2f rad/s", speed)
-	frame, err = cybergear.WriteParameterCmd(parameters.HostId, byte(motorId), cybergear.PARAMETER_SPD_REF, speed)
+	frame, err = cybergear.WriteParameterCmd(parameters.HostId, motorId, cybergear.PARAMETER_SPD_REF, speed)
 	if err != nil {
 		return err
 	}
@@ -277,21 +569,26 @@ func executeSetSpeedCmd(args []string, outputCh chan string) error {
 }
 
 func executeSetCurrentCmd(args []string, outputCh chan string) error {
-	var frame *cybergear.SLCanFrame
+	var frame *cybergear.Frame
 	var err error
-	var motorId int64
+	var motorId byte
 
 	if len(args) != 3 {
 		return fmt.Errorf("syntax error ('set_current <motorId> <rad/s>')' Args: '%+v'", args)
 	}
 
-	motorId, err = strconv.ParseInt(args[1], 16, 8)
+	motorId, err = parameters.MotorId(args[1])
+	if err != nil {
+		return err
+	}
+
+	err = checkIdle(motorId)
 	if err != nil {
 		return err
 	}
 
 	outputCh <- fmt.Sprintf("Setting run mode to [red]SPEED MODE[-] for motor %02X", motorId)
-	frame, err = cybergear.SetRunMode(parameters.HostId, byte(motorId), cybergear.CURRENT_MODE)
+	frame, err = cybergear.SetRunMode(parameters.HostId, motorId, cybergear.CURRENT_MODE)
 	if err != nil {
 		return err
 	}
@@ -311,8 +608,13 @@ func executeSetCurrentCmd(args []string, outputCh chan string) error {
 		return fmt.Errorf("invalid current parameter: %2.2f. Valid values are in the interval [-23,23] A", current)
 	}
 
+	limits := parameters.MotorLimits(motorId)
+	if limits.Current > 0 && (current < -limits.Current || current > limits.Current) {
+		return fmt.Errorf("current %2.2f A exceeds the configured limit of %2.2f A for motor %02X", current, limits.Current, motorId)
+	}
+
 	outputCh <- fmt.Sprintf("Setting current to %2.2f A", current)
-	frame, err = cybergear.WriteParameterCmd(parameters.HostId, byte(motorId), cybergear.PARAMETER_IQ_REF, current)
+	frame, err = cybergear.WriteParameterCmd(parameters.HostId, motorId, cybergear.PARAMETER_IQ_REF, current)
 	if err != nil {
 		return err
 	}
@@ -329,23 +631,19 @@ func executeSetCurrentCmd(args []string, outputCh chan string) error {
 
 func executeGetStatusCmd(args []string, outputCh chan string) error {
 
-	var frame *cybergear.SLCanFrame
+	var frame *cybergear.Frame
 
 	if len(args) != 2 {
 		return fmt.Errorf("syntax error ('get_status <motor ID>')' Args: '%+v'", args)
 	}
 
-	motorId, err := strconv.ParseUint(args[1], 16, 8)
+	motorId, err := parameters.MotorId(args[1])
 	if err != nil {
-		return fmt.Errorf("syntax error: <motor ID>: '%s'", args[1])
-	}
-
-	if err != nil {
-		return err
+		return fmt.Errorf("syntax error: <motor ID>: %s", err)
 	}
 
 	outputCh <- fmt.Sprintf("Enabling motor (CAN id: %02X)", motorId)
-	frame, err = cybergear.GetStatusCmd(parameters.HostId, byte(motorId))
+	frame, err = cybergear.GetStatusCmd(parameters.HostId, motorId)
 	if err != nil {
 		return err
 	}
@@ -363,19 +661,48 @@ func executeGetStatusCmd(args []string, outputCh chan string) error {
 }
 
 var dispatchMap = map[string]dispatchFunc{
-	"help":        executeHelpCmd,
-	"enable":      executeEnableCmd,
-	"disable":     executeDisableCmd,
-	"open":        executeOpenCmd,
-	"close":       executeCloseCmd,
-	"set_speed":   executeSetSpeedCmd,
-	"set_current": executeSetCurrentCmd,
-	"get_status":  executeGetStatusCmd,
+	"help":         executeHelpCmd,
+	"enable":       executeEnableCmd,
+	"disable":      executeDisableCmd,
+	"open":         executeOpenCmd,
+	"close":        executeCloseCmd,
+	"set_speed":    executeSetSpeedCmd,
+	"set_current":  executeSetCurrentCmd,
+	"get_status":   executeGetStatusCmd,
+	"profile":      executeProfileCmd,
+	"adapter":      executeAdapterCmd,
+	"sniff":        executeSniffCmd,
+	"raw":          executeRawCmd,
+	"cg":           executeCgCmd,
+	"dump":         executeDumpCmd,
+	"restore":      executeRestoreCmd,
+	"diff":         executeDiffCmd,
+	"info":         executeInfoCmd,
+	"param":        executeParamCmd,
+	"autotune":     executeAutotuneCmd,
+	"characterize": executeCharacterizeCmd,
+	"move":         executeMoveCmd,
+	"play":         executePlayCmd,
+	"teach":        executeTeachCmd,
+	"impedance":    executeImpedanceCmd,
+	"knob":         executeKnobCmd,
+	"stats":        executeStatsCmd,
+	"exporter":     executeExporterCmd,
+	"grpc":         executeGrpcCmd,
+	"mqtt":         executeMqttCmd,
+	"joints":       executeJointsCmd,
+	".":            executeKeyframeCmd,
 	// "limit_torque": executeLimitTorqueCmd,
 }
 
 func Dispatch(command string, outputCh chan string) error {
+	commandMutex.Lock()
+	defer commandMutex.Unlock()
+
+	return dispatch(command, outputCh)
+}
 
+func dispatch(command string, outputCh chan string) error {
 	command = strings.TrimSpace(command)
 	for key, value := range dispatchMap {
 		if len(command) >= len(key) && command[:len(key)] == key {
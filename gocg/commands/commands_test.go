@@ -0,0 +1,1109 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"gocg/cybergear"
+	"gocg/joints"
+	"gocg/parameters"
+	"gocg/rpc"
+	"gocg/rpc/pb"
+	"gocg/slcan"
+	"gocg/virtualbus"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/gorilla/websocket"
+	"github.com/tarm/serial"
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
+)
+
+const (
+	TEST_READ_TIMEOUT      = 15 * time.Millisecond
+	STREAMING_READ_TIMEOUT = 100 * time.Millisecond // For tests that stream requests for a while, see streaming
+)
+
+// Virtual bus with two motors (shoulder 7F and elbow 10) in place of the serial port of the adapter
+type testBench struct {
+	bus    *virtualbus.Bus
+	motors map[byte]*virtualbus.Motor
+	port   *virtualbus.HostPort // Last port opened
+	dir    string
+}
+
+func newTestBench(t *testing.T, config virtualbus.Config) *testBench {
+	b := &testBench{bus: virtualbus.New(config), motors: map[byte]*virtualbus.Motor{}, dir: t.TempDir()}
+	b.motors[0x7F] = b.bus.AddMotor(0x7F)
+	b.motors[0x10] = b.bus.AddMotor(0x10)
+
+	profile := parameters.DefaultProfile()
+	profile.Adapter = "vbus"
+	profile.ReadTimeout = TEST_READ_TIMEOUT
+	profile.HostId = 0x01
+	profile.Motors = map[string]parameters.Motor{"shoulder": {Id: 0x7F}, "elbow": {Id: 0x10}}
+	parameters.Use("test", profile)
+
+	defaultOpenPort := openPort
+	openPort = func(config *serial.Config) (io.ReadWriteCloser, error) {
+		b.port = b.bus.NewHostPort()
+		b.port.SetReadTimeout(config.ReadTimeout)
+		return b.port, nil
+	}
+
+	t.Cleanup(func() {
+		for _, stop := range []struct {
+			running bool
+			command string
+		}{
+			{activeServer != nil, "serve stop"},
+			{activeGrpcServer != nil, "grpc stop"},
+			{activeBridge != nil, "mqtt stop"},
+			{activeSniffer != nil, "sniff stop"},
+			{adapter != nil, "close"},
+		} {
+			if stop.running {
+				Run(stop.command)
+			}
+		}
+		openPort = defaultOpenPort
+		parameters.Use("default", parameters.DefaultProfile())
+		b.bus.Close()
+	})
+
+	return b
+}
+
+// Bench with the adapter open in normal mode
+func openTestBench(t *testing.T, config virtualbus.Config) *testBench {
+	b := newTestBench(t, config)
+	b.run(t, "open")
+	return b
+}
+
+// Longer read timeout for the commands that send hundreds of requests in a row (autotune, characterize, move, play).
+// One of them stalled by the scheduler for longer than TEST_READ_TIMEOUT would fail the command.
+func (b *testBench) streaming() *testBench {
+	name, profile := parameters.ActiveProfile()
+	profile.ReadTimeout = STREAMING_READ_TIMEOUT
+	parameters.Use(name, profile)
+	return b
+}
+
+func (b *testBench) path(name string) string {
+	return filepath.Join(b.dir, name)
+}
+
+// Runs the command and fails the test if it returns an error
+func (b *testBench) run(t *testing.T, command string) []string {
+	t.Helper()
+	output, err := Run(command)
+	if err != nil {
+		t.Fatalf("%s: %s\n%s", command, err, strings.Join(output, "\n"))
+	}
+	return output
+}
+
+// Runs the command and fails the test unless the error contains the text
+func (b *testBench) fail(t *testing.T, command string, text string) {
+	t.Helper()
+	_, err := Run(command)
+	if err == nil || !strings.Contains(err.Error(), text) {
+		t.Fatalf("%s: expected error with '%s', got %v", command, text, err)
+	}
+}
+
+// The first line of the output that contains the text
+func find(t *testing.T, output []string, text string) string {
+	t.Helper()
+	for _, line := range output {
+		if strings.Contains(line, text) {
+			return line
+		}
+	}
+	t.Fatalf("No line with '%s' in\n%s", text, strings.Join(output, "\n"))
+	return ""
+}
+
+// The address a server reports in its start line
+func listenAddress(t *testing.T, output []string, pattern string) string {
+	t.Helper()
+	for _, line := range output {
+		if m := regexp.MustCompile(pattern).FindStringSubmatch(line); m != nil {
+			return m[1]
+		}
+	}
+	t.Fatalf("No address matching '%s' in\n%s", pattern, strings.Join(output, "\n"))
+	return ""
+}
+
+func near(a float64, b float64, tolerance float64) bool {
+	return math.Abs(a-b) <= tolerance
+}
+
+func (b *testBench) parameter(t *testing.T, motorId byte, name string) float64 {
+	t.Helper()
+	p, err := cybergear.LookupParameter(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, ok := b.motors[motorId].Parameter(p.Index)
+	if !ok {
+		t.Fatalf("%s is not a numeric parameter", name)
+	}
+	return value
+}
+
+// Waits until the condition holds, for up to a second
+func eventually(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timeout waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// MQTT client that keeps what the bridge publishes, in place of a broker
+type recordingMqttClient struct {
+	mqtt.Client
+	mutex     sync.Mutex
+	published map[string][]string // Payloads by topic
+	handler   mqtt.MessageHandler // Of the last subscription
+}
+
+type doneToken struct{}
+
+func (doneToken) Wait() bool                       { return true }
+func (doneToken) WaitTimeout(d time.Duration) bool { return true }
+func (doneToken) Done() <-chan struct{}            { ch := make(chan struct{}); close(ch); return ch }
+func (doneToken) Error() error                     { return nil }
+
+type mqttTestMessage struct {
+	mqtt.Message
+	topic   string
+	payload string
+}
+
+func (m mqttTestMessage) Topic() string   { return m.topic }
+func (m mqttTestMessage) Payload() []byte { return []byte(m.payload) }
+
+func (c *recordingMqttClient) Publish(topic string, qos byte, retained bool, payload interface{}) mqtt.Token {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if data, ok := payload.([]byte); ok {
+		payload = string(data)
+	}
+	c.published[topic] = append(c.published[topic], payload.(string))
+	return doneToken{}
+}
+
+func (c *recordingMqttClient) Subscribe(topic string, qos byte, callback mqtt.MessageHandler) mqtt.Token {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.handler = callback
+	return doneToken{}
+}
+
+func (c *recordingMqttClient) Disconnect(quiesce uint) {}
+
+// Hands a message to the subscription, as the broker would
+func (c *recordingMqttClient) deliver(topic string, payload string) {
+	c.mutex.Lock()
+	handler := c.handler
+	c.mutex.Unlock()
+	handler(c, mqttTestMessage{topic: topic, payload: payload})
+}
+
+// Waits for a payload on the topic that contains the text
+func (c *recordingMqttClient) expect(t *testing.T, topic string, text string) {
+	t.Helper()
+	eventually(t, fmt.Sprintf("'%s' on %s", text, topic), func() bool {
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		for _, payload := range c.published[topic] {
+			if strings.Contains(payload, text) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// One test per console command, run against the virtual bus
+var commandTests = map[string]func(t *testing.T){
+	"help": func(t *testing.T) {
+		b := newTestBench(t, virtualbus.Config{})
+		output := b.run(t, "help")
+		for command := range dispatchMap {
+			if command != "help" && command != TEACH_KEYFRAME_KEY {
+				find(t, output, "\t"+command)
+			}
+		}
+	},
+
+	"enable": func(t *testing.T) {
+		b := openTestBench(t, virtualbus.Config{Latency: time.Millisecond})
+		output := b.run(t, "enable shoulder")
+		find(t, output, "Enable motor (CAN id: 7F) OK")
+		find(t, output, "angle :")
+		if !b.motors[0x7F].State().Enabled || b.motors[0x10].State().Enabled {
+			t.Error("Expected only motor 7F enabled")
+		}
+		b.fail(t, "enable", "syntax error")
+	},
+
+	"disable": func(t *testing.T) {
+		b := openTestBench(t, virtualbus.Config{Latency: time.Millisecond})
+		b.run(t, "enable 7F")
+		find(t, b.run(t, "disable 7F"), "Disable 7F OK")
+		if b.motors[0x7F].State().Enabled {
+			t.Error("Expected motor 7F disabled")
+		}
+		b.fail(t, "disable nobody", "neither a motor name")
+	},
+
+	"open": func(t *testing.T) {
+		b := newTestBench(t, virtualbus.Config{})
+		output := b.run(t, "open")
+		find(t, output, "Adapter version : "+virtualbus.HOST_PORT_VERSION)
+		find(t, output, "Open vbus OK")
+		if b.port.Bitrate() != "S8" {
+			t.Errorf("Unexpected bitrate %s", b.port.Bitrate())
+		}
+		b.fail(t, "open", "already open")
+	},
+
+	"close": func(t *testing.T) {
+		b := openTestBench(t, virtualbus.Config{})
+		find(t, b.run(t, "close"), "Close serial port OK")
+		if adapter != nil {
+			t.Error("Adapter still open")
+		}
+		find(t, b.run(t, "close"), "never bothered to open")
+		b.fail(t, "enable 7F", "open a serial port first")
+	},
+
+	"set_speed": func(t *testing.T) {
+		b := openTestBench(t, virtualbus.Config{Latency: time.Millisecond})
+		find(t, b.run(t, "set_speed shoulder 3"), "set_speed 7f 3.00 OK")
+		b.run(t, "enable shoulder")
+		time.Sleep(300 * time.Millisecond)
+		state := b.motors[0x7F].State()
+		if state.RunMode != int(cybergear.SPEED_MODE) || !near(state.Speed, 3, 0.1) {
+			t.Errorf("Unexpected state %+v", state)
+		}
+		b.fail(t, "set_speed 7F 31", "invalid speed")
+	},
+
+	"set_current": func(t *testing.T) {
+		b := openTestBench(t, virtualbus.Config{Latency: time.Millisecond})
+		find(t, b.run(t, "set_current 10 1"), "set_current 10 1.00A OK")
+		b.run(t, "enable 10")
+		time.Sleep(20 * time.Millisecond)
+		state := b.motors[0x10].State()
+		if state.RunMode != int(cybergear.CURRENT_MODE) || !near(state.Torque, virtualbus.TORQUE_CONSTANT, 0.01) {
+			t.Errorf("Unexpected state %+v", state)
+		}
+		b.fail(t, "set_current 10 24", "invalid current")
+	},
+
+	"get_status": func(t *testing.T) {
+		b := openTestBench(t, virtualbus.Config{})
+		output := b.run(t, "get_status elbow")
+		find(t, output, "get_status (CAN id: 10) OK")
+		feedback := 0
+		for _, line := range output {
+			if strings.HasPrefix(line, "angle :") {
+				feedback++
+			}
+		}
+		if feedback != 100 {
+			t.Errorf("Expected 100 feedback frames, got %d", feedback)
+		}
+	},
+
+	"profile": func(t *testing.T) {
+		b := newTestBench(t, virtualbus.Config{})
+		output := b.run(t, "profile")
+		find(t, output, "Profile   : test")
+		find(t, output, "Host id   : 0x01")
+		find(t, output, "shoulder     0x7F")
+		b.run(t, "open")
+		b.fail(t, "profile bench", "close the serial port")
+	},
+
+	"adapter": func(t *testing.T) {
+		b := openTestBench(t, virtualbus.Config{})
+		output := b.run(t, "adapter")
+		find(t, output, "Version          : "+virtualbus.HOST_PORT_VERSION)
+		find(t, output, "Status flags     : 0x00 (OK)")
+
+		b.port.SetStatusFlags(0x20)
+		find(t, b.run(t, "adapter status"), "error passive")
+		find(t, b.run(t, "adapter serial"), "Serial number : VBUS")
+
+		b.fail(t, "adapter bitrate 500000", "rejected")
+		b.run(t, "adapter close")
+		b.run(t, "adapter bitrate S6")
+		if b.port.Bitrate() != "S6" {
+			t.Errorf("Unexpected bitrate %s", b.port.Bitrate())
+		}
+
+		// Frames with timestamps still decode
+		b.run(t, "adapter timestamp on")
+		b.run(t, "adapter open")
+		find(t, b.run(t, "enable 7F"), "angle :")
+		b.fail(t, "adapter reset", "unknown adapter command")
+	},
+
+	"sniff": func(t *testing.T) {
+		b := openTestBench(t, virtualbus.Config{})
+		find(t, b.run(t, "sniff"), "Sniffing")
+		b.fail(t, "enable 7F", "listen-only")
+
+		// Another host on the bus asks for the status of motor 7F
+		other := b.bus.NewHostPort()
+		defer other.Close()
+		frame, _ := cybergear.GetStatusCmd(0x02, 0x7F)
+		fmt.Fprintf(other, "O\r%s\r", virtualbus.FormatLine(virtualbus.Frame{Id: frame.Id(), Extended: true}))
+
+		eventually(t, "request and reply", func() bool {
+			output, _ := Run("sniff stats")
+			return len(output) > 0 && strings.HasPrefix(output[0], "Frames received : 2 ")
+		})
+		output := b.run(t, "sniff stats")
+		find(t, output, "15 get status     : 1")
+		find(t, output, "02 feedback       : 1")
+
+		find(t, b.run(t, "sniff stop"), "sniff stop OK")
+		b.run(t, "enable 7F")
+
+		// Without an open adapter, the sniffer opens and closes the port itself
+		b.run(t, "close")
+		b.run(t, "sniff --motor 7F --type 2")
+		b.run(t, "sniff stop")
+		if adapter != nil {
+			t.Error("Sniffer left the port open")
+		}
+	},
+
+	"raw": func(t *testing.T) {
+		b := openTestBench(t, virtualbus.Config{})
+		frame, _ := cybergear.EnableMotorCmd(0x01, 0x7F)
+		line := virtualbus.FormatLine(virtualbus.Frame{Id: frame.Id(), Extended: true})
+		output := b.run(t, "raw "+line)
+		find(t, output, "raw "+line+" OK")
+		find(t, output, "angle :")
+		if !b.motors[0x7F].State().Enabled {
+			t.Error("Expected motor 7F enabled")
+		}
+		b.fail(t, "raw T0300017F1", "frame length")
+	},
+
+	"cg": func(t *testing.T) {
+		b := openTestBench(t, virtualbus.Config{})
+		find(t, b.run(t, "cg 3 01 elbow 0000"), "cg OK")
+		if !b.motors[0x10].State().Enabled {
+			t.Error("Expected motor 10 enabled")
+		}
+
+		// Clear faults: disable with byte 0 = 1
+		b.motors[0x10].SetFaults(0x0400)
+		find(t, b.run(t, "get_status 10"), "overtemperature")
+		b.run(t, "cg 4 0x01 10 0000 0100000000000000")
+		if state := b.motors[0x10].State(); state.Faults != 0 || state.Enabled {
+			t.Errorf("Unexpected state %+v", state)
+		}
+		b.fail(t, "cg 32 01 10 0000", "invalid communication type")
+		b.fail(t, "cg 3 01 10 0002", "would overwrite 02")
+		find(t, b.run(t, "cg 3 - 10 0001"), "cg OK")
+	},
+
+	"dump": func(t *testing.T) {
+		b := openTestBench(t, virtualbus.Config{})
+		path := b.path("shoulder.json")
+		output := b.run(t, "dump shoulder "+path)
+		run := 0
+		for _, p := range cybergear.Parameters() {
+			if !p.Config() {
+				run++
+			}
+		}
+		find(t, output, fmt.Sprintf("dump 7F %s OK (%d of %d parameters)", path, run, run))
+
+		dump, err := loadDump(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if dump.MotorId != "0x7F" || dump.MotorName != "shoulder" {
+			t.Errorf("Unexpected dump header %+v", dump)
+		}
+		for _, entry := range dump.Parameters {
+			switch {
+			case strings.HasPrefix(entry.Name, "CONFIG_"):
+				t.Errorf("Config area parameter %+v dumped", entry)
+			case entry.Name == "PARAMETER_LOC_KP" && (entry.Value == nil || *entry.Value != 30):
+				t.Errorf("Unexpected loc_kp %+v", entry)
+			}
+		}
+
+		b.run(t, "dump 7F "+b.path("shoulder.yaml"))
+		if _, err := loadDump(b.path("shoulder.yaml")); err != nil {
+			t.Error(err)
+		}
+	},
+
+	"restore": func(t *testing.T) {
+		b := openTestBench(t, virtualbus.Config{})
+		path := b.path("shoulder.json")
+		b.run(t, "dump 7F "+path)
+		b.run(t, "param 10 PARAMETER_SPD_KP 2")
+
+		output := b.run(t, "restore 10 "+path+" --dry-run")
+		find(t, output, "was dumped from motor 0x7F")
+		find(t, output, "(dry run): 1 to change")
+		if b.parameter(t, 0x10, "PARAMETER_SPD_KP") != 2 {
+			t.Error("Dry run wrote")
+		}
+
+		find(t, b.run(t, "restore 10 "+path), "restore 10 "+path+" OK: 1 changed")
+		if b.parameter(t, 0x10, "PARAMETER_SPD_KP") != 1 {
+			t.Error("PARAMETER_SPD_KP not restored")
+		}
+		b.fail(t, "restore 10 "+b.path("missing.json"), "no such file")
+	},
+
+	"diff": func(t *testing.T) {
+		b := openTestBench(t, virtualbus.Config{})
+		b.run(t, "param elbow PARAMETER_LIMIT_SPD 5")
+		output := b.run(t, "diff shoulder elbow")
+		find(t, output, "PARAMETER_LIMIT_SPD")
+		for _, line := range output {
+			if strings.Contains(line, "PARAMETER_SPD_KP") {
+				t.Errorf("Unexpected difference: %s", line)
+			}
+		}
+		b.fail(t, "diff 7F shoulder", "given twice")
+	},
+
+	"info": func(t *testing.T) {
+		b := openTestBench(t, virtualbus.Config{})
+		output := b.run(t, "info shoulder")
+		find(t, output, "shoulder (7F)")
+		find(t, output, "MCU id          : 7F00000053554256")
+		find(t, output, "info 7F OK")
+		b.fail(t, "info 7F name", "syntax error")
+		b.fail(t, "info 33", "no reply")
+	},
+
+	"param": func(t *testing.T) {
+		b := openTestBench(t, virtualbus.Config{})
+		find(t, b.run(t, "param 7F PARAMETER_LOC_KP"), "0x701E PARAMETER_LOC_KP = 30 (float, volatile)")
+
+		output := b.run(t, "param 7F PARAMETER_LOC_KP 25")
+		find(t, output, "motor 7F starts with CONFIG_WR_LOC_KP")
+		if b.parameter(t, 0x7F, "PARAMETER_LOC_KP") != 25 {
+			t.Error("PARAMETER_LOC_KP not written")
+		}
+
+		b.fail(t, "param 7F PARAMETER_CUR_FILT_GAIN 2", "outside the valid range")
+		b.fail(t, "param 7F 0x2009", "reading it isn't supported")
+		b.fail(t, "param 7F CONFIG_WR_LOC_KP 25", "read only")
+		b.fail(t, "param 7F CONFIG_WR_NAME left-shoulder", "read only")
+		b.fail(t, "param 7F PARAMETER_MECH_VBUS 1", "read only")
+		b.fail(t, "param 7F NO_SUCH_PARAMETER", "unknown parameter")
+	},
+
+	"autotune": func(t *testing.T) {
+		b := openTestBench(t, virtualbus.Config{Latency: time.Millisecond}).streaming()
+		b.fail(t, "autotune apply", "nothing to apply")
+
+		output := b.run(t, "autotune shoulder position")
+		find(t, output, "Model          : zeta")
+		find(t, output, "not a plant estimate")
+		find(t, output, "autotune 7F position OK")
+		if b.motors[0x7F].State().Enabled {
+			t.Error("Motor left enabled")
+		}
+
+		proposed := pendingAutotune.gains[0].proposed
+		find(t, b.run(t, "autotune apply"), "autotune apply 7F OK")
+		if !near(b.parameter(t, 0x7F, "PARAMETER_LOC_KP"), proposed, 1e-4) {
+			t.Errorf("PARAMETER_LOC_KP is %g, expected %g", b.parameter(t, 0x7F, "PARAMETER_LOC_KP"), proposed)
+		}
+		b.fail(t, "autotune 7F torque", "unknown loop")
+	},
+
+	"characterize": func(t *testing.T) {
+		b := openTestBench(t, virtualbus.Config{Latency: time.Millisecond}).streaming()
+		path := b.path("speed.csv")
+		find(t, b.run(t, "characterize 7F speed step "+path+" --duration 0.3"), "characterize 7F speed step OK")
+		for _, file := range []string{path, b.path("speed-metrics.csv")} {
+			if _, err := os.Stat(file); err != nil {
+				t.Error(err)
+			}
+		}
+
+		path = b.path("position.csv")
+		b.run(t, "characterize 7F position chirp "+path+" --duration 0.5 --f0 1 --f1 5 --amplitude 0.2")
+		if _, err := os.Stat(b.path("position-bode.csv")); err != nil {
+			t.Error(err)
+		}
+		b.fail(t, "characterize 7F position chirp "+path+" --duration 0.3 --f0 1000 --f1 2000", "a quarter of the")
+		b.fail(t, "characterize 7F speed ramp", "unknown test")
+	},
+
+	"move": func(t *testing.T) {
+		b := openTestBench(t, virtualbus.Config{Latency: time.Millisecond}).streaming()
+		find(t, b.run(t, "move shoulder 1 --vmax 2 --amax 5"), "move 7F 1.000 OK")
+		eventually(t, "the shoulder to settle at 1 rad in position mode", func() bool {
+			state := b.motors[0x7F].State()
+			return near(state.Angle, 1, 0.02) && state.RunMode == int(cybergear.LOCATION_MODE)
+		})
+
+		b.run(t, "move shoulder 0 --vmax 2 --amax 5 --jmax 50 --via mit")
+		eventually(t, "the shoulder to settle at 0 rad in motion control mode", func() bool {
+			state := b.motors[0x7F].State()
+			return near(state.Angle, 0, 0.02) && state.RunMode == int(cybergear.OPEARATION_CONTROL_MODE)
+		})
+		b.fail(t, "move 7F 1 --vmax 2", "syntax error")
+	},
+
+	"play": func(t *testing.T) {
+		b := openTestBench(t, virtualbus.Config{Latency: time.Millisecond}).streaming()
+		path := b.path("arm.csv")
+		err := os.WriteFile(path, []byte("time_s,shoulder,elbow\n0,0,0\n0.3,0.5,-0.5\n"), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b.run(t, "play "+path+" --interp cubic --rate 50")
+		eventually(t, "the motors to settle at the last waypoint", func() bool {
+			return near(b.motors[0x7F].State().Angle, 0.5, 0.02) && near(b.motors[0x10].State().Angle, -0.5, 0.02)
+		})
+		b.fail(t, "play "+path+" --speed 0", "invalid options")
+	},
+
+	"teach": func(t *testing.T) {
+		b := openTestBench(t, virtualbus.Config{})
+		path := b.path("taught.csv")
+		b.fail(t, "teach mark", "not teaching")
+
+		b.run(t, "teach shoulder 10 --file "+path+" --rate 50")
+		time.Sleep(100 * time.Millisecond)
+		b.motors[0x7F].SetAngle(0.3)
+		time.Sleep(100 * time.Millisecond)
+		b.run(t, "teach mark")
+		b.fail(t, "impedance elbow", "busy with teach ('teach stop' first)")
+		b.fail(t, "knob 10 stop", "busy with teach")
+		time.Sleep(100 * time.Millisecond)
+		find(t, b.run(t, "teach stop"), "teach stop OK")
+		if err := checkIdle(0x10); err != nil {
+			t.Error(err)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		if lines[0] != "time_s,shoulder,elbow,keyframe" || len(lines) < 5 {
+			t.Errorf("Unexpected waypoints\n%s", data)
+		}
+		if !strings.Contains(lines[len(lines)-1], ",0.3") {
+			t.Errorf("Hand guided angle not recorded\n%s", data)
+		}
+
+		// Marking a keyframe after the recorder gave up reports why
+		b.run(t, "teach shoulder 10 --file "+path)
+		b.motors[0x10].Remove()
+		eventually(t, "the recorder to stop", func() bool {
+			select {
+			case <-activeTeach.doneCh:
+				return true
+			default:
+				return false
+			}
+		})
+		b.fail(t, "teach mark", "motor 10")
+		b.fail(t, "teach stop", "nothing recorded")
+	},
+
+	".": func(t *testing.T) {
+		b := openTestBench(t, virtualbus.Config{})
+		b.fail(t, ".", "not teaching")
+
+		path := b.path("taught.csv")
+		b.run(t, "teach 7F --file "+path)
+		time.Sleep(100 * time.Millisecond)
+		b.run(t, ".")
+		time.Sleep(50 * time.Millisecond)
+		b.run(t, "teach stop")
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), ",1\n") {
+			t.Errorf("No keyframe in\n%s", data)
+		}
+	},
+
+	"impedance": func(t *testing.T) {
+		b := openTestBench(t, virtualbus.Config{Latency: time.Millisecond})
+		find(t, b.run(t, "impedance shoulder --kp 5 --kd 0.1 --at 0.2"), "'impedance 7F stop' stops")
+		b.fail(t, "knob 7F --detents 4 --strength 0.1", "busy with impedance")
+		for _, command := range []string{"move 7F 1 --vmax 2 --amax 5", "autotune 7F speed", "characterize 7F speed step", "set_speed 7F 1"} {
+			b.fail(t, command, "busy with impedance")
+		}
+		b.fail(t, "sniff", "('impedance 7F stop' first)")
+
+		eventually(t, "the spring to pull the shaft to 0.2 rad", func() bool {
+			return near(b.motors[0x7F].State().Angle, 0.2, 0.01)
+		})
+		find(t, b.run(t, "impedance 7F stop"), "impedance 7F stopped, motor disabled")
+		if b.motors[0x7F].State().Enabled {
+			t.Error("Motor left enabled")
+		}
+		b.fail(t, "impedance 7F stop", "no background loop")
+
+		// A set point out of range is refused before the motor is enabled
+		b.fail(t, "impedance 7F --at 13", "invalid angle")
+		if b.motors[0x7F].State().Enabled {
+			t.Error("Motor enabled for a set point out of range")
+		}
+
+		// The feed forward torque stays within the torque limit of the profile
+		name, profile := parameters.ActiveProfile()
+		profile.Motors["shoulder"] = parameters.Motor{Id: 0x7F, Limits: parameters.Limits{Torque: 0.5}}
+		parameters.Use(name, profile)
+		b.run(t, "impedance shoulder --offset 3")
+		time.Sleep(50 * time.Millisecond)
+		if torque := b.motors[0x7F].State().Torque; !near(torque, 0.5, 0.01) {
+			t.Errorf("Torque %g Nm, expected the 0.5 Nm limit", torque)
+		}
+		b.run(t, "impedance shoulder stop")
+	},
+
+	"knob": func(t *testing.T) {
+		b := openTestBench(t, virtualbus.Config{Latency: time.Millisecond})
+		b.run(t, "knob 10 --detents 12 --strength 0.2 --endstops -1,1 --rate 100")
+		time.Sleep(200 * time.Millisecond)
+		if !b.motors[0x10].State().Enabled {
+			t.Error("Expected motor 10 enabled")
+		}
+		find(t, b.run(t, "knob 10 stop"), "knob 10 stopped, motor disabled")
+		b.fail(t, "knob 10 --strength 0.2", "missing --detents")
+		b.fail(t, "knob 10 --detents 12", "missing --strength")
+		b.fail(t, "knob 10 --detents 12 --strength -0.2", "invalid or missing --strength")
+	},
+
+	"stats": func(t *testing.T) {
+		b := openTestBench(t, virtualbus.Config{})
+		b.run(t, "stats reset")
+		b.run(t, "enable 7F")
+		b.run(t, "param 7F PARAMETER_LOC_KP")
+
+		output := b.run(t, "stats")
+		find(t, output, "2 requests, 0 unanswered")
+		find(t, output, "Latency (request to reply): 2 samples")
+
+		path := b.path("stats.json")
+		b.run(t, "stats export "+path)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var exported map[string]interface{}
+		if err := json.Unmarshal(data, &exported); err != nil {
+			t.Error(err)
+		}
+		b.fail(t, "stats clear", "syntax error")
+	},
+
+	"exporter": func(t *testing.T) {
+		b := openTestBench(t, virtualbus.Config{})
+		b.run(t, "enable 7F")
+		address := listenAddress(t, b.run(t, "exporter start 127.0.0.1:0 --poll 0.05"), `http://(\S+)/metrics`)
+
+		eventually(t, "polled motor metrics", func() bool {
+			response, err := http.Get("http://" + address + "/metrics")
+			if err != nil {
+				return false
+			}
+			defer response.Body.Close()
+			body, _ := io.ReadAll(response.Body)
+			return strings.Contains(string(body), `gocg_motor_angle_radians{motor_id="7F",motor_name="shoulder"}`) &&
+				strings.Contains(string(body), `gocg_motor_parameter{motor_id="10",motor_name="elbow",parameter="PARAMETER_MECH_VBUS"}`)
+		})
+
+		b.fail(t, "sniff", "('exporter stop' first)")
+		find(t, b.run(t, "exporter stop"), "exporter stop OK")
+		b.fail(t, "exporter stop", "not running")
+	},
+
+	"serve": func(t *testing.T) {
+		b := openTestBench(t, virtualbus.Config{})
+		address := listenAddress(t, b.run(t, "serve 127.0.0.1:0"), `http://(\S+)/api/`)
+
+		response, err := http.Post("http://"+address+"/api/motors/shoulder/enable", "application/json", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var status MotorStatus
+		json.NewDecoder(response.Body).Decode(&status)
+		response.Body.Close()
+		if response.StatusCode != http.StatusOK || status.MotorId != "7F" || !b.motors[0x7F].State().Enabled {
+			t.Errorf("Unexpected reply %d %+v", response.StatusCode, status)
+		}
+
+		response, err = http.Post("http://"+address+"/api/command", "application/json", strings.NewReader(`{"command": "param 10 PARAMETER_LIMIT_SPD"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var result struct {
+			Output []string `json:"output"`
+		}
+		json.NewDecoder(response.Body).Decode(&result)
+		response.Body.Close()
+		find(t, result.Output, "PARAMETER_LIMIT_SPD = 2")
+
+		// Form posts, as a page from another origin could send them, are refused before the command runs
+		response, err = http.Post("http://"+address+"/api/command", "text/plain", strings.NewReader(`{"command": "enable 10"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		response.Body.Close()
+		if response.StatusCode != http.StatusUnsupportedMediaType || b.motors[0x10].State().Enabled {
+			t.Errorf("Unexpected reply %d to a text/plain command, elbow enabled %v", response.StatusCode, b.motors[0x10].State().Enabled)
+		}
+
+		// Only the commands driving the motors, nothing writing files
+		dump := b.path("dump.json")
+		response, err = http.Post("http://"+address+"/api/command", "application/json", strings.NewReader(`{"command": "dump 7F `+dump+`"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		response.Body.Close()
+		if _, err := os.Stat(dump); response.StatusCode != http.StatusForbidden || err == nil {
+			t.Errorf("Unexpected reply %d to a dump, file written %v", response.StatusCode, err == nil)
+		}
+
+		// Background loops keep their motors
+		response, err = http.Post("http://"+address+"/api/command", "application/json", strings.NewReader(`{"command": "impedance shoulder --kp 1"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		response.Body.Close()
+		response, err = http.Post("http://"+address+"/api/motors/shoulder/disable", "application/json", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var failure struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(response.Body).Decode(&failure)
+		response.Body.Close()
+		if !strings.Contains(failure.Error, "busy with impedance") || !b.motors[0x7F].State().Enabled {
+			t.Errorf("Disable not refused while impedance runs: %d %+v", response.StatusCode, failure)
+		}
+		b.run(t, "impedance 7F stop")
+
+		stream := "ws://" + address + "/api/events?motor=shoulder&kind=feedback"
+		_, response, err = websocket.DefaultDialer.Dial(stream, http.Header{"Origin": {"http://example.com"}})
+		if err == nil || response == nil || response.StatusCode != http.StatusForbidden {
+			t.Errorf("WebSocket from another origin not refused: %v", err)
+		}
+
+		conn, _, err := websocket.DefaultDialer.Dial(stream, http.Header{"Origin": {"http://" + address}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		eventually(t, "the event subscription", events.active)
+		b.run(t, "get_status 10")
+		b.run(t, "get_status 7F")
+		var e Event
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		err = conn.ReadJSON(&e)
+		if err != nil || e.Kind != "feedback" || e.MotorId != "7F" {
+			t.Errorf("Unexpected event %+v (%v)", e, err)
+		}
+
+		find(t, b.run(t, "serve stop"), "serve stop OK")
+		b.fail(t, "serve stop", "not serving")
+	},
+
+	"grpc": func(t *testing.T) {
+		b := openTestBench(t, virtualbus.Config{Latency: time.Millisecond})
+		address := listenAddress(t, b.run(t, "grpc 127.0.0.1:0"), `Serving gRPC on (\S+)`)
+
+		client, err := rpc.Dial(address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer client.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		status, err := client.SetSpeed(ctx, "elbow", 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status.MotorId != "10" || status.Name != "elbow" {
+			t.Errorf("Unexpected status %+v", status)
+		}
+		parameter, err := client.Read(ctx, "elbow", "PARAMETER_SPD_REF")
+		if err != nil || parameter.GetNumber() != 2 {
+			t.Errorf("Unexpected PARAMETER_SPD_REF %+v (%v)", parameter, err)
+		}
+		text := &pb.WriteParameterRequest{Motor: "elbow", Parameter: "CONFIG_WR_NAME", Value: &pb.WriteParameterRequest_Text{Text: "knee"}}
+		if _, err := client.WriteParameter(ctx, text); grpcstatus.Code(err) != codes.InvalidArgument {
+			t.Errorf("Expected InvalidArgument for a text value, got %v", err)
+		}
+		if _, err := client.DisableMotor(ctx, "elbow"); err != nil {
+			t.Error(err)
+		}
+		if _, err := client.Status(ctx, "wrist"); grpcstatus.Code(err) != codes.InvalidArgument {
+			t.Errorf("Expected InvalidArgument for an unknown motor, got %v", err)
+		}
+
+		// The feedback stream reads the status itself, nothing else talks to the shoulder
+		b.motors[0x7F].SetAngle(0.5)
+		feedback, err := client.StreamFeedback(ctx, &pb.StreamRequest{Motor: "shoulder"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		status, err = feedback.Recv()
+		if err != nil || status.MotorId != "7F" || !near(status.Angle, 0.5, 0.01) {
+			t.Errorf("Unexpected streamed status %+v (%v)", status, err)
+		}
+
+		// Fault reports (type 21) are forwarded when raised and when cleared
+		faults, err := client.StreamFaults(ctx, &pb.StreamRequest{Motor: "shoulder"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(2 * STREAM_POLL_PERIOD)
+		b.motors[0x7F].ReportFaults(slcan.FAULT_OVERVOLTAGE, 0)
+		event, err := faults.Recv()
+		if err != nil || strings.Join(event.Raised, ",") != "overvoltage" || strings.Join(event.Active, ",") != "overvoltage" {
+			t.Errorf("Unexpected fault event %+v (%v)", event, err)
+		}
+		b.motors[0x7F].ReportFaults(0, 0)
+		event, err = faults.Recv()
+		if err != nil || strings.Join(event.Cleared, ",") != "overvoltage" || len(event.Active) != 0 {
+			t.Errorf("Unexpected fault event %+v (%v)", event, err)
+		}
+
+		// One poller for all streams, motors that don't answer are read less often
+		all, err := client.StreamFeedback(ctx, &pb.StreamRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := all.Recv(); err != nil {
+			t.Fatal(err)
+		}
+		b.motors[0x10].Remove()
+		eventually(t, "the elbow to be read less often", func() bool {
+			statusPoller.mutex.Lock()
+			defer statusPoller.mutex.Unlock()
+			return len(statusPoller.subscribers) == 3 && statusPoller.backoff[0x10] > STREAM_POLL_PERIOD
+		})
+
+		// Stops with the streams still open
+		find(t, b.run(t, "grpc stop"), "grpc stop OK")
+		eventually(t, "the poller to stop", func() bool {
+			statusPoller.mutex.Lock()
+			defer statusPoller.mutex.Unlock()
+			return len(statusPoller.subscribers) == 0
+		})
+		b.fail(t, "grpc stop", "not running")
+	},
+
+	"mqtt": func(t *testing.T) {
+		b := openTestBench(t, virtualbus.Config{})
+		// Nothing listens on port 1
+		if _, err := Run("mqtt start 127.0.0.1:1"); err == nil {
+			t.Error("Expected connect error")
+		}
+		if activeBridge != nil {
+			t.Error("Bridge running without broker")
+		}
+		b.fail(t, "mqtt start 127.0.0.1:1 --qos 3", "invalid QoS")
+		b.fail(t, "mqtt stop", "not running")
+		b.fail(t, "mqtt", "syntax error")
+
+		client := &recordingMqttClient{published: map[string][]string{}}
+		bridge := &mqttBridge{client: client, prefix: "robot", qos: 1, stopCh: make(chan struct{}),
+			queue: make(chan mqtt.Message, MQTT_COMMAND_QUEUE), enabled: map[byte]bool{}}
+		outputCh := make(chan string, 16)
+		bridge.connected(outputCh)
+		client.expect(t, "robot/bridge/status", "online")
+		activeBridge = bridge
+		bridge.start(outputCh)
+		eventually(t, "the event subscription", events.active)
+
+		client.deliver("robot/shoulder/cmd", `{"id": "1", "op": "enable"}`)
+		client.expect(t, "robot/shoulder/reply", `{"id":"1","op":"enable","result":{"motorId":"7F"`)
+		client.expect(t, "robot/shoulder/state", `"motorId":"7F"`)
+		client.expect(t, "robot/shoulder/faults", `"active":[]`)
+		if !b.motors[0x7F].State().Enabled {
+			t.Error("Shoulder not enabled")
+		}
+
+		client.deliver("robot/elbow/cmd", `{"id": "2", "op": "read", "param": "PARAMETER_LIMIT_SPD"}`)
+		client.expect(t, "robot/elbow/reply", `"id":"2","op":"read","result":{"motorId":"10","index":"0x7017"`)
+		client.expect(t, "robot/elbow/param", `"name":"PARAMETER_LIMIT_SPD"`)
+		client.deliver("robot/elbow/cmd", `{"id": "3", "op": "speed"}`)
+		client.expect(t, "robot/elbow/reply", `{"id":"3","op":"speed","error":"missing value"}`)
+		client.deliver("robot/elbow/cmd", `{"id": "4", "op": "spin"}`)
+		client.expect(t, "robot/elbow/reply", `"error":"unknown op 'spin'`)
+
+		// Faults from the feedback and from fault reports (type 21)
+		b.motors[0x7F].SetFaults(slcan.FEEDBACK_OVERTEMPERATURE)
+		b.run(t, "get_status 7F")
+		client.expect(t, "robot/shoulder/faults", `"active":["overtemperature"],"raised":["overtemperature"]`)
+		b.motors[0x7F].ReportFaults(slcan.FAULT_OVERVOLTAGE, 0)
+		time.Sleep(10 * time.Millisecond)
+		pollStatus([]byte{0x7F})
+		client.expect(t, "robot/shoulder/faults", `"active":["overtemperature","overvoltage"],"raised":["overvoltage"]`)
+		b.motors[0x7F].ReportFaults(0, 0)
+		time.Sleep(10 * time.Millisecond)
+		pollStatus([]byte{0x7F})
+		client.expect(t, "robot/shoulder/faults", `"active":["overtemperature"],"cleared":["overvoltage"]`)
+
+		// Motors enabled through the bridge are disabled when the broker goes away
+		bridge.connectionLost(fmt.Errorf("broker gone"), outputCh)
+		if b.motors[0x7F].State().Enabled {
+			t.Error("Shoulder left enabled after the connection was lost")
+		}
+
+		// Stopping joins the bridge goroutines, including one waiting for the command mutex 'mqtt stop' holds
+		client.deliver("robot/shoulder/cmd", `{"id": "5", "op": "status"}`)
+		find(t, b.run(t, "mqtt stop"), "mqtt stop OK")
+		client.expect(t, "robot/bridge/status", "offline")
+	},
+
+	"joints": func(t *testing.T) {
+		b := openTestBench(t, virtualbus.Config{Latency: time.Millisecond})
+		name, profile := parameters.ActiveProfile()
+		profile.Motors["shoulder"] = parameters.Motor{Id: 0x7F, Limits: parameters.Limits{Speed: 2}}
+		parameters.Use(name, profile)
+
+		peer, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer peer.Close()
+
+		output := b.run(t, "joints start 127.0.0.1:0 --peer "+peer.LocalAddr().String()+" --rate 50")
+		bridge, err := net.ResolveUDPAddr("udp", listenAddress(t, output, `Joint bridge on (\S+)`))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		buf := make([]byte, JOINTS_MAX_MESSAGE)
+		var state joints.JointState
+		peer.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := peer.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(buf[:n], &state); err != nil || strings.Join(state.Name, ",") != "elbow,shoulder" {
+			t.Fatalf("Unexpected joint state %s (%v)", buf[:n], err)
+		}
+
+		trajectory, _ := json.Marshal(joints.JointTrajectory{
+			JointNames: []string{"shoulder"},
+			Points:     []joints.JointTrajectoryPoint{{Positions: []float64{0.4}, TimeFromStart: joints.Duration{Nanosec: 500000000}}},
+		})
+		peer.WriteTo(trajectory, bridge)
+
+		eventually(t, "the shoulder to follow the trajectory", func() bool {
+			return near(b.motors[0x7F].State().Angle, 0.4, 0.02)
+		})
+
+		// Nothing moves for trajectories that would jump or go too fast
+		for _, c := range []struct {
+			point joints.JointTrajectoryPoint
+			text  string
+		}{
+			{joints.JointTrajectoryPoint{Positions: []float64{1}}, "give it a time_from_start"},
+			{joints.JointTrajectoryPoint{Positions: []float64{1.2}, TimeFromStart: joints.Duration{Nanosec: 300000000}}, "exceeds the configured limit"},
+			{joints.JointTrajectoryPoint{Positions: []float64{2}, TimeFromStart: joints.Duration{Sec: 2}}, ""},
+		} {
+			err := activeJoints.follow(&joints.JointTrajectory{JointNames: []string{"shoulder"}, Points: []joints.JointTrajectoryPoint{c.point}}, nil)
+			if (c.text == "" && err != nil) || (c.text != "" && (err == nil || !strings.Contains(err.Error(), c.text))) {
+				t.Errorf("%+v: expected error with '%s', got %v", c.point, c.text, err)
+			}
+		}
+
+		// The bridge owns its joints
+		b.fail(t, "move shoulder 0 --vmax 2 --amax 5", "busy with joints ('joints stop' first)")
+		b.fail(t, "set_speed elbow 1", "'joints stop' first")
+		b.fail(t, "knob elbow stop", "'joints stop' first")
+
+		find(t, b.run(t, "joints stop"), "joints stop OK")
+		if err := checkIdle(0x10); err != nil {
+			t.Error(err)
+		}
+		if b.motors[0x7F].State().Enabled {
+			t.Error("Motor left enabled")
+		}
+		b.fail(t, "joints stop", "not running")
+	},
+}
+
+func TestCommands(t *testing.T) {
+	for command := range dispatchMap {
+		test, ok := commandTests[command]
+		if !ok {
+			t.Errorf("No test for command '%s'", command)
+			continue
+		}
+		t.Run(command, test)
+	}
+}
+
+// Requests go unanswered on a lossy bus, but nothing hangs and the statistics show it
+func TestLossyBus(t *testing.T) {
+	b := openTestBench(t, virtualbus.Config{Latency: time.Millisecond, Loss: 0.3, Seed: 1})
+	b.run(t, "stats reset")
+	for i := 0; i < 50; i++ {
+		Run("enable 7F")
+	}
+
+	output := b.run(t, "stats")
+	var requests, unanswered int
+	fmt.Sscanf(strings.TrimPrefix(find(t, output, "unanswered"), "[yellow]"), "%d requests, %d unanswered", &requests, &unanswered)
+	if requests != 50 || unanswered < 10 || unanswered > 40 {
+		t.Errorf("Unexpected counters: %d requests, %d unanswered", requests, unanswered)
+	}
+}
+
+// Replies that come out of order still reach the request they answer
+func TestReorderingBus(t *testing.T) {
+	b := openTestBench(t, virtualbus.Config{Latency: time.Millisecond, Reorder: 0.5, Seed: 1})
+	for i, name := range []string{"PARAMETER_LOC_KP", "PARAMETER_SPD_KP", "PARAMETER_SPD_KI", "PARAMETER_LIMIT_SPD"} {
+		value := fmt.Sprintf("%d", i+2)
+		find(t, b.run(t, "param 10 "+name+" "+value), name+" = "+value+" ")
+	}
+	if b.bus.Stats().Reordered == 0 {
+		t.Error("Nothing reordered")
+	}
+}
@@ -0,0 +1,129 @@
+package commands
+
+import (
+	"fmt"
+	"gocg/cybergear"
+	"gocg/parameters"
+)
+
+// diff 7F 01 [02...] - reads the run area configuration of two or more motors and prints the parameters that differ.
+// Measurements and setpoints are left out, they differ all the time, and so is the config area (undocumented type 9).
+func executeDiffCmd(args []string, outputCh chan string) error {
+	if len(args) < 3 {
+		return fmt.Errorf("syntax error ('diff <motor ID> <motor ID> [motor ID]...')' Args: '%+v'", args)
+	}
+
+	var motorIds []byte
+	for _, arg := range args[1:] {
+		motorId, err := parameters.MotorId(arg)
+		if err != nil {
+			return err
+		}
+		for _, id := range motorIds {
+			if id == motorId {
+				return fmt.Errorf("motor %02X given twice", motorId)
+			}
+		}
+		motorIds = append(motorIds, motorId)
+	}
+
+	var params []cybergear.ParameterInfo
+	for _, p := range cybergear.Parameters() {
+		if !p.Config() && !p.Measurement() && !p.Command {
+			params = append(params, p)
+		}
+	}
+
+	// values[motor][parameter], "" if the parameter couldn't be read
+	values := make([][]string, len(motorIds))
+	outOfRange := make([][]bool, len(motorIds))
+	for m, motorId := range motorIds {
+		outputCh <- fmt.Sprintf("Reading %d parameters from motor %02X", len(params), motorId)
+
+		values[m] = make([]string, len(params))
+		outOfRange[m] = make([]bool, len(params))
+		failed := 0
+		for i, p := range params {
+			value, err := readParameterString(motorId, p)
+			if err != nil {
+				failed++
+				continue
+			}
+			values[m][i] = value.text
+			outOfRange[m][i] = !value.inRange
+		}
+
+		if failed == len(params) {
+			return fmt.Errorf("no parameters could be read from motor %02X", motorId)
+		}
+		if failed > 0 {
+			outputCh <- fmt.Sprintf("[yellow]%d parameters could not be read from motor %02X[-]", failed, motorId)
+		}
+	}
+
+	header := fmt.Sprintf("%-6s %-26s", "index", "parameter")
+	for _, motorId := range motorIds {
+		header += fmt.Sprintf(" %14s", motorLabel(motorId))
+	}
+	outputCh <- header
+
+	differ := 0
+	for i, p := range params {
+		same := true
+		flagged := false
+		for m := range motorIds {
+			same = same && values[m][i] == values[0][i]
+			flagged = flagged || outOfRange[m][i]
+		}
+		if same && !flagged {
+			continue
+		}
+		if !same {
+			differ++
+		}
+
+		row := fmt.Sprintf("0x%04X %-26s", p.Index, p.Name)
+		for m := range motorIds {
+			value := values[m][i]
+			if value == "" {
+				value = "-"
+			}
+			if outOfRange[m][i] {
+				row += fmt.Sprintf(" [red]%14s[-]", value)
+			} else {
+				row += fmt.Sprintf(" %14s", value)
+			}
+		}
+		if flagged {
+			row += " " + p.RangeString()
+		}
+		outputCh <- row
+	}
+
+	outputCh <- fmt.Sprintf("diff OK: %d of %d parameters differ (values outside the documented range in red)", differ, len(params))
+	return nil
+}
+
+// Motor name from the active profile, or the CAN id
+func motorLabel(motorId byte) string {
+	name := parameters.MotorName(motorId)
+	if name == "" {
+		return fmt.Sprintf("%02X", motorId)
+	}
+	return fmt.Sprintf("%s (%02X)", name, motorId)
+}
+
+type parameterValue struct {
+	text    string
+	inRange bool
+}
+
+// Reads a parameter and formats it for display
+func readParameterString(motorId byte, p cybergear.ParameterInfo) (parameterValue, error) {
+	value, err := readParameter(motorId, p)
+	if err != nil {
+		return parameterValue{}, err
+	}
+
+	return parameterValue{text: p.Format(value), inRange: p.InRange(value)}, nil
+}
@@ -0,0 +1,152 @@
+package commands
+
+import (
+	"fmt"
+	"gocg/cybergear"
+	"gocg/slcan"
+	"sort"
+	"sync"
+	"time"
+)
+
+// A decoded frame, or a line of command output, for the remote APIs
+type Event struct {
+	Time      time.Time       `json:"time"`
+	Kind      string          `json:"kind"` // feedback, parameter, fault, frame or output
+	MotorId   string          `json:"motorId,omitempty"`
+	Feedback  *MotorStatus    `json:"feedback,omitempty"`
+	Parameter *ParameterValue `json:"parameter,omitempty"`
+	Faults    []string        `json:"faults,omitempty"` // Faults and warnings of a fault report
+	Text      string          `json:"text,omitempty"`
+}
+
+// Hands events to the subscribers. Slow subscribers lose events rather than holding up the bus.
+type eventHub struct {
+	mutex       sync.Mutex
+	subscribers map[chan Event]bool
+}
+
+var events = &eventHub{subscribers: map[chan Event]bool{}}
+
+// Returns a channel with the events from now on and a function that ends the subscription
+func SubscribeEvents(buffer int) (<-chan Event, func()) {
+	ch := make(chan Event, buffer)
+
+	events.mutex.Lock()
+	events.subscribers[ch] = true
+	events.mutex.Unlock()
+
+	return ch, func() {
+		events.mutex.Lock()
+		defer events.mutex.Unlock()
+		if events.subscribers[ch] {
+			delete(events.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+func (h *eventHub) publish(e Event) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for ch := range h.subscribers {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+func (h *eventHub) active() bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return len(h.subscribers) > 0
+}
+
+func publishFrame(frame slcan.Frame) {
+	if !events.active() {
+		return
+	}
+
+	e := Event{Time: time.Now(), Kind: "frame", MotorId: fmt.Sprintf("%02X", frame.MotorId()), Text: frame.String()}
+	switch f := frame.(type) {
+	case *slcan.MotorFeedback:
+		status := statusOf(f)
+		e.Kind, e.Feedback = "feedback", &status
+	case *slcan.ParameterFrame:
+		if p, ok := cybergear.ParameterByIndex(f.Index()); ok {
+			value := parameterValueOf(f.MotorId(), p)
+			if v, ok := f.Value(); ok {
+				value.Value = &v
+			}
+			e.Kind, e.Parameter = "parameter", &value
+		}
+	case *slcan.FaultFrame:
+		e.Kind, e.Faults = "fault", append(f.Faults(), f.Warnings()...)
+	}
+	events.publish(e)
+}
+
+// Faults of the motors as reported by their feedback (type 2) and their fault reports (type 21). Each of them has
+// the complete list of its faults, which differ between the two.
+type faultTracker map[string]map[string][]string // Motor id, event kind
+
+type faultChange struct {
+	Active  []string
+	Raised  []string
+	Cleared []string
+	First   bool // First feedback or fault report of the motor
+}
+
+// Faults raised and cleared by a feedback or fault event, false for other events
+func (t faultTracker) update(e Event) (faultChange, bool) {
+	var faults []string
+	switch e.Kind {
+	case "feedback":
+		faults = e.Feedback.Faults
+	case "fault":
+		faults = e.Faults
+	default:
+		return faultChange{}, false
+	}
+
+	_, seen := t[e.MotorId]
+	if !seen {
+		t[e.MotorId] = map[string][]string{}
+	}
+	previous := t.active(e.MotorId)
+	t[e.MotorId][e.Kind] = faults
+	current := t.active(e.MotorId)
+
+	change := faultChange{Active: []string{}, First: !seen}
+	for fault := range current {
+		change.Active = append(change.Active, fault)
+		if !previous[fault] {
+			change.Raised = append(change.Raised, fault)
+		}
+	}
+	for fault := range previous {
+		if !current[fault] {
+			change.Cleared = append(change.Cleared, fault)
+		}
+	}
+	sort.Strings(change.Active)
+	sort.Strings(change.Raised)
+	sort.Strings(change.Cleared)
+	return change, true
+}
+
+func (t faultTracker) active(motorId string) map[string]bool {
+	active := map[string]bool{}
+	for _, faults := range t[motorId] {
+		for _, fault := range faults {
+			active[fault] = true
+		}
+	}
+	return active
+}
+
+func publishOutput(line string) {
+	events.publish(Event{Time: time.Now(), Kind: "output", Text: line})
+}
@@ -0,0 +1,182 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gocg/metrics"
+	"gocg/parameters"
+	"net"
+	"net/http"
+	"sort"
+	"time"
+)
+
+const (
+	EXPORTER_DEFAULT_ADDRESS = "127.0.0.1:9180" // Local only, like serve
+	EXPORTER_DEFAULT_POLL    = 5.0              // s
+)
+
+// Parameters the exporter polls from every known motor. Only run area parameters (type 17), config area reads aren't
+// documented. The temperature isn't polled, it comes from the feedback frames only: motors that send none have no
+// temperature.
+var exporterParameters = []string{"PARAMETER_MECH_VBUS"}
+
+// Prometheus /metrics endpoint with a parameter poller
+type exporter struct {
+	server *http.Server
+	stopCh chan struct{}
+	doneCh chan struct{}
+}
+
+var activeExporter *exporter
+
+// exporter start [address] [--poll s] | exporter stop
+func executeExporterCmd(args []string, outputCh chan string) error {
+	positional, opts, err := parseOptions(args, "poll")
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case len(positional) == 2 && positional[1] == "stop":
+		if activeExporter == nil {
+			return fmt.Errorf("exporter not running")
+		}
+		stopExporter()
+		outputCh <- "exporter stop OK"
+		return nil
+	case (len(positional) == 2 || len(positional) == 3) && positional[1] == "start":
+	default:
+		return fmt.Errorf("syntax error ('exporter start [address] [--poll s]' or 'exporter stop')' Args: '%+v'", args)
+	}
+
+	if activeExporter != nil {
+		return fmt.Errorf("exporter already running ('exporter stop' first)")
+	}
+
+	address := EXPORTER_DEFAULT_ADDRESS
+	if len(positional) == 3 {
+		address = positional[2]
+	}
+	poll, err := opts.float("poll", EXPORTER_DEFAULT_POLL)
+	if err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler(metrics.Bus, metrics.Motors, parameters.MotorName))
+
+	e := &exporter{
+		server: &http.Server{Handler: mux},
+		stopCh: make(chan struct{}),
+		doneCh: make(chan struct{}),
+	}
+	activeExporter = e
+
+	go func() {
+		err := e.server.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			outputCh <- fmt.Sprintf("[red]exporter: %s[-]", err)
+		}
+	}()
+
+	if poll > 0 {
+		go e.poll(time.Duration(poll * float64(time.Second)))
+	} else {
+		close(e.doneCh)
+	}
+
+	outputCh <- fmt.Sprintf("Serving metrics on http://%s/metrics, polling %v every %g s", listener.Addr(), exporterParameters, poll)
+	return nil
+}
+
+// Reads the exporter parameters of the known motors. The replies end up in the motor cache like all others. Holds
+// commandMutex while it talks to the motors, so polls never interleave with commands.
+func (e *exporter) poll(interval time.Duration) {
+	defer close(e.doneCh)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.stopCh:
+			return
+		case <-ticker.C:
+		}
+
+		commandMutex.Lock()
+		select {
+		case <-e.stopCh:
+			commandMutex.Unlock()
+			return
+		default:
+		}
+
+		// Nothing to poll with, or the sniffer owns the adapter
+		if adapter == nil || activeSniffer != nil {
+			commandMutex.Unlock()
+			continue
+		}
+
+		for _, motorId := range pollMotorIds() {
+			for _, name := range exporterParameters {
+				readParameter(motorId, mustLookupParameter(name))
+			}
+		}
+		commandMutex.Unlock()
+	}
+}
+
+func (e *exporter) polling() bool {
+	select {
+	case <-e.doneCh:
+		return false
+	default:
+		return true
+	}
+}
+
+// Called with commandMutex held, so the poller is between polls and returns when it gets the lock
+func stopExporter() {
+	e := activeExporter
+	close(e.stopCh)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	e.server.Shutdown(ctx)
+
+	activeExporter = nil
+}
+
+// CAN ids of the motors in the active profile and of the motors seen on the bus, ascending
+func pollMotorIds() []byte {
+	seen := map[int]bool{}
+	for _, name := range parameters.MotorNames() {
+		motorId, err := parameters.MotorId(name)
+		if err == nil {
+			seen[int(motorId)] = true
+		}
+	}
+	for motorId := range metrics.Motors.Snapshot() {
+		seen[int(motorId)] = true
+	}
+
+	ids := make([]int, 0, len(seen))
+	for id := range seen {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	motorIds := make([]byte, len(ids))
+	for i, id := range ids {
+		motorIds[i] = byte(id)
+	}
+	return motorIds
+}
@@ -0,0 +1,400 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"gocg/parameters"
+	"gocg/rpc/pb"
+	"net"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	GRPC_DEFAULT_ADDRESS    = "127.0.0.1:50051"      // Local only, like serve
+	STREAM_POLL_PERIOD      = 100 * time.Millisecond // Status reads of the streamed motors
+	STREAM_POLL_MAX_BACKOFF = 5 * time.Second        // Longest wait between reads of a motor that doesn't answer
+)
+
+// The CyberGear gRPC service on top of the API operations
+type grpcService struct {
+	pb.UnimplementedCyberGearServer
+}
+
+var activeGrpcServer *grpc.Server
+
+var grpcModes = map[pb.Mode]string{
+	pb.Mode_MODE_SPEED:          "speed",
+	pb.Mode_MODE_POSITION:       "position",
+	pb.Mode_MODE_CURRENT:        "current",
+	pb.Mode_MODE_MOTION_CONTROL: "mit",
+}
+
+// grpc [address] | grpc stop
+func executeGrpcCmd(args []string, outputCh chan string) error {
+	switch {
+	case len(args) == 2 && args[1] == "stop":
+		if activeGrpcServer == nil {
+			return fmt.Errorf("gRPC server not running")
+		}
+		// Not GracefulStop: streams only end when cancelled, and calls waiting for commandMutex (held here) never would
+		activeGrpcServer.Stop()
+		activeGrpcServer = nil
+		outputCh <- "grpc stop OK"
+		return nil
+	case len(args) > 2:
+		return fmt.Errorf("syntax error ('grpc [address]' or 'grpc stop')' Args: '%+v'", args)
+	}
+
+	if activeGrpcServer != nil {
+		return fmt.Errorf("gRPC server already running ('grpc stop' first)")
+	}
+
+	address := GRPC_DEFAULT_ADDRESS
+	if len(args) == 2 {
+		address = args[1]
+	}
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+
+	activeGrpcServer = grpc.NewServer()
+	pb.RegisterCyberGearServer(activeGrpcServer, &grpcService{})
+	go func(server *grpc.Server) {
+		err := server.Serve(listener)
+		if err != nil {
+			outputCh <- fmt.Sprintf("[red]grpc: %s[-]", err)
+		}
+	}(activeGrpcServer)
+
+	outputCh <- fmt.Sprintf("Serving gRPC on %s", listener.Addr())
+	return nil
+}
+
+func grpcMotorId(motor string) (byte, error) {
+	motorId, err := parameters.MotorId(motor)
+	if err != nil {
+		return 0, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return motorId, nil
+}
+
+func grpcMode(mode pb.Mode) (string, error) {
+	name, ok := grpcModes[mode]
+	if !ok {
+		return "", status.Errorf(codes.InvalidArgument, "invalid mode %s", mode)
+	}
+	return name, nil
+}
+
+func toPbStatus(s MotorStatus) *pb.MotorStatus {
+	return &pb.MotorStatus{
+		MotorId:     s.MotorId,
+		Name:        s.Name,
+		Angle:       s.Angle,
+		Speed:       s.Speed,
+		Torque:      s.Torque,
+		Temperature: s.Temperature,
+		Mode:        s.Mode,
+		Faults:      s.Faults,
+	}
+}
+
+// Result of a status operation. Failed operations are Unavailable: the motor didn't answer or refused.
+func grpcStatus(s MotorStatus, err error) (*pb.MotorStatus, error) {
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	return toPbStatus(s), nil
+}
+
+func grpcParameter(p ParameterValue, err error) (*pb.Parameter, error) {
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+
+	result := &pb.Parameter{
+		MotorId:     p.MotorId,
+		Index:       p.Index,
+		Name:        p.Name,
+		Type:        p.Type,
+		Writable:    p.Writable,
+		Persistence: p.Persistence,
+	}
+	if p.Value != nil {
+		result.Value = &pb.Parameter_Number{Number: *p.Value}
+	}
+	return result, nil
+}
+
+func (s *grpcService) ListMotors(ctx context.Context, req *pb.ListMotorsRequest) (*pb.ListMotorsResponse, error) {
+	response := &pb.ListMotorsResponse{}
+	for _, motorId := range pollMotorIds() {
+		status, err := cachedStatus(motorId)
+		if err != nil {
+			status = MotorStatus{MotorId: fmt.Sprintf("%02X", motorId), Name: parameters.MotorName(motorId)}
+		}
+		response.Motors = append(response.Motors, toPbStatus(status))
+	}
+	return response, nil
+}
+
+func (s *grpcService) Enable(ctx context.Context, req *pb.MotorRequest) (*pb.MotorStatus, error) {
+	motorId, err := grpcMotorId(req.Motor)
+	if err != nil {
+		return nil, err
+	}
+	return grpcStatus(EnableMotor(motorId))
+}
+
+func (s *grpcService) Disable(ctx context.Context, req *pb.MotorRequest) (*pb.MotorStatus, error) {
+	motorId, err := grpcMotorId(req.Motor)
+	if err != nil {
+		return nil, err
+	}
+	return grpcStatus(DisableMotor(motorId))
+}
+
+func (s *grpcService) GetStatus(ctx context.Context, req *pb.MotorRequest) (*pb.MotorStatus, error) {
+	motorId, err := grpcMotorId(req.Motor)
+	if err != nil {
+		return nil, err
+	}
+	return grpcStatus(GetStatus(motorId))
+}
+
+func (s *grpcService) SetMode(ctx context.Context, req *pb.SetModeRequest) (*pb.MotorStatus, error) {
+	motorId, err := grpcMotorId(req.Motor)
+	if err != nil {
+		return nil, err
+	}
+	mode, err := grpcMode(req.Mode)
+	if err != nil {
+		return nil, err
+	}
+	return grpcStatus(SetMode(motorId, mode))
+}
+
+func (s *grpcService) SetSetpoint(ctx context.Context, req *pb.SetpointRequest) (*pb.MotorStatus, error) {
+	motorId, err := grpcMotorId(req.Motor)
+	if err != nil {
+		return nil, err
+	}
+	mode, err := grpcMode(req.Mode)
+	if err != nil || mode == "mit" {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid setpoint mode %s (speed, position or current)", req.Mode)
+	}
+	return grpcStatus(SetSetpoint(motorId, mode, req.Value))
+}
+
+func (s *grpcService) ReadParameter(ctx context.Context, req *pb.ParameterRequest) (*pb.Parameter, error) {
+	motorId, err := grpcMotorId(req.Motor)
+	if err != nil {
+		return nil, err
+	}
+	return grpcParameter(ReadParam(motorId, req.Parameter))
+}
+
+func (s *grpcService) WriteParameter(ctx context.Context, req *pb.WriteParameterRequest) (*pb.Parameter, error) {
+	motorId, err := grpcMotorId(req.Motor)
+	if err != nil {
+		return nil, err
+	}
+
+	switch value := req.Value.(type) {
+	case *pb.WriteParameterRequest_Number:
+		return grpcParameter(WriteParam(motorId, req.Parameter, value.Number))
+	case *pb.WriteParameterRequest_Text:
+		return nil, status.Error(codes.InvalidArgument, "string parameters are read only")
+	}
+	return nil, status.Error(codes.InvalidArgument, "missing value")
+}
+
+// Reads the status of the streamed motors for all streams together: a motor is read once per STREAM_POLL_PERIOD
+// however many streams cover it, and less and less often while it doesn't answer.
+type streamPoller struct {
+	mutex       sync.Mutex
+	subscribers map[chan Event][]byte // Motors of each stream, nil for all
+	backoff     map[byte]time.Duration
+	next        map[byte]time.Time // Next read of the motors that didn't answer
+	stopCh      chan struct{}
+}
+
+var statusPoller = &streamPoller{subscribers: map[chan Event][]byte{}, backoff: map[byte]time.Duration{}, next: map[byte]time.Time{}}
+
+// Returns a channel with the polled status of the motors (all motors for nil) and a function that ends the
+// subscription. The poller runs while there are subscribers.
+func (p *streamPoller) subscribe(motorIds []byte) (<-chan Event, func()) {
+	ch := make(chan Event, EVENT_BUFFER)
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.subscribers[ch] = motorIds
+	if len(p.subscribers) == 1 {
+		p.backoff, p.next = map[byte]time.Duration{}, map[byte]time.Time{}
+		p.stopCh = make(chan struct{})
+		go p.run(p.stopCh)
+	}
+
+	return ch, func() {
+		p.mutex.Lock()
+		defer p.mutex.Unlock()
+		if _, ok := p.subscribers[ch]; !ok {
+			return
+		}
+		delete(p.subscribers, ch)
+		if len(p.subscribers) == 0 {
+			close(p.stopCh)
+		}
+	}
+}
+
+func (p *streamPoller) run(stopCh chan struct{}) {
+	ticker := time.NewTicker(STREAM_POLL_PERIOD)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+		}
+
+		motorIds := p.due(time.Now())
+		if len(motorIds) == 0 {
+			continue
+		}
+		p.publish(motorIds, pollStatus(motorIds))
+	}
+}
+
+// Motors of all streams whose next read is due
+func (p *streamPoller) due(now time.Time) []byte {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	streamed := map[byte]bool{}
+	for _, motorIds := range p.subscribers {
+		if motorIds == nil {
+			motorIds = pollMotorIds()
+		}
+		for _, motorId := range motorIds {
+			streamed[motorId] = true
+		}
+	}
+
+	due := []byte{}
+	for motorId := range streamed {
+		if next, ok := p.next[motorId]; !ok || !now.Before(next) {
+			due = append(due, motorId)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i] < due[j] })
+	return due
+}
+
+// Hands the statuses to the streams covering the motor and doubles the wait for the motors that didn't answer
+func (p *streamPoller) publish(motorIds []byte, statuses []MotorStatus) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	polled := map[string]byte{}
+	for _, motorId := range motorIds {
+		polled[fmt.Sprintf("%02X", motorId)] = motorId
+	}
+
+	answered := map[byte]bool{}
+	for _, status := range statuses {
+		status := status
+		motorId := polled[status.MotorId]
+		answered[motorId] = true
+		e := Event{Time: time.Now(), Kind: "feedback", MotorId: status.MotorId, Feedback: &status}
+		for ch, streamed := range p.subscribers {
+			if streamed != nil && !slices.Contains(streamed, motorId) {
+				continue
+			}
+			select {
+			case ch <- e:
+			default:
+			}
+		}
+	}
+
+	for _, motorId := range motorIds {
+		if answered[motorId] {
+			delete(p.backoff, motorId)
+			delete(p.next, motorId)
+			continue
+		}
+		p.backoff[motorId] = min(2*max(p.backoff[motorId], STREAM_POLL_PERIOD), STREAM_POLL_MAX_BACKOFF)
+		p.next[motorId] = time.Now().Add(p.backoff[motorId])
+	}
+}
+
+// Calls send for the feedback and fault events of the motor (all motors for an empty motor) until the client goes
+// away. The status of the motors is polled too, so the stream doesn't depend on other commands talking to them.
+func streamEvents(ctx context.Context, motor string, send func(e Event) error) error {
+	filter := ""
+	var motorIds []byte
+	if motor != "" {
+		motorId, err := grpcMotorId(motor)
+		if err != nil {
+			return err
+		}
+		filter = fmt.Sprintf("%02X", motorId)
+		motorIds = []byte{motorId}
+	}
+
+	eventCh, unsubscribe := SubscribeEvents(EVENT_BUFFER)
+	defer unsubscribe()
+	statusCh, unsubscribeStatus := statusPoller.subscribe(motorIds)
+	defer unsubscribeStatus()
+
+	for {
+		var e Event
+		select {
+		case <-ctx.Done():
+			return nil
+		case e = <-statusCh:
+		case e = <-eventCh:
+			if (e.Kind != "feedback" && e.Kind != "fault") || (filter != "" && e.MotorId != filter) {
+				continue
+			}
+		}
+
+		err := send(e)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *grpcService) StreamFeedback(req *pb.StreamRequest, stream pb.CyberGear_StreamFeedbackServer) error {
+	return streamEvents(stream.Context(), req.Motor, func(e Event) error {
+		if e.Kind != "feedback" {
+			return nil
+		}
+		return stream.Send(toPbStatus(*e.Feedback))
+	})
+}
+
+func (s *grpcService) StreamFaults(req *pb.StreamRequest, stream pb.CyberGear_StreamFaultsServer) error {
+	faults := faultTracker{}
+
+	return streamEvents(stream.Context(), req.Motor, func(e Event) error {
+		change, ok := faults.update(e)
+		if !ok || (len(change.Raised) == 0 && len(change.Cleared) == 0) {
+			return nil
+		}
+		return stream.Send(&pb.FaultEvent{MotorId: e.MotorId, TimeUnixNano: e.Time.UnixNano(), Active: change.Active,
+			Raised: change.Raised, Cleared: change.Cleared})
+	})
+}
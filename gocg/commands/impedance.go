@@ -0,0 +1,125 @@
+package commands
+
+import (
+	"fmt"
+	"gocg/control"
+	"gocg/cybergear"
+	"gocg/parameters"
+)
+
+const (
+	IMPEDANCE_DEFAULT_RATE = 200.0 // Hz
+	MAX_TORQUE             = 12.0  // Nm, motion control torque range
+)
+
+// Largest feed forward torque for the motor, the torque limit of the profile if it has one
+func maxTorque(motorId byte) float64 {
+	if limit := parameters.MotorLimits(motorId).Torque; limit > 0 && float64(limit) < MAX_TORQUE {
+		return float64(limit)
+	}
+	return MAX_TORQUE
+}
+
+// impedance 7F [--at rad] [--kp Nm/rad] [--kd Nm/(rad/s)] [--offset Nm] [--coulomb Nm] [--viscous Nm/(rad/s)] [--rate Hz]
+// impedance 7F stop
+func executeImpedanceCmd(args []string, outputCh chan string) error {
+	positional, opts, err := parseOptions(args, "at", "kp", "kd", "offset", "coulomb", "viscous", "rate")
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 && !(len(positional) == 3 && positional[2] == "stop") {
+		return fmt.Errorf("syntax error ('impedance <motor ID> [--at rad] [--kp kp] [--kd kd] [--offset Nm] [--coulomb Nm] [--viscous Nm/(rad/s)] [--rate Hz]' or 'impedance <motor ID> stop')' Args: '%+v'", args)
+	}
+
+	motorId, err := parameters.MotorId(positional[1])
+	if err != nil {
+		return err
+	}
+
+	if len(positional) == 3 {
+		summary, err := stopBackgroundLoop(motorId)
+		if err != nil {
+			return err
+		}
+		outputCh <- fmt.Sprintf("impedance %02X stopped, motor disabled: %s", motorId, summary)
+		return nil
+	}
+
+	var impedance control.Impedance
+	for _, o := range []struct {
+		name  string
+		value *float64
+	}{
+		{"kp", &impedance.Stiffness},
+		{"kd", &impedance.Damping},
+		{"offset", &impedance.Offset},
+		{"coulomb", &impedance.Coulomb},
+		{"viscous", &impedance.Viscous},
+	} {
+		*o.value, err = opts.float(o.name, 0)
+		if err != nil {
+			return err
+		}
+	}
+	rate, err := opts.float("rate", IMPEDANCE_DEFAULT_RATE)
+	if err != nil {
+		return err
+	}
+	_, at := opts["at"]
+	impedance.SetPoint, err = opts.float("at", 0)
+	if err != nil {
+		return err
+	}
+
+	err = checkIdle(motorId)
+	if err != nil {
+		return err
+	}
+	if rate <= 0 {
+		return fmt.Errorf("invalid rate %g Hz", rate)
+	}
+
+	// Checked before the motor is enabled: the set point is within the motion control range (±4π rad), the feed forward
+	// torque as well. The profile has no position limits.
+	_, err = cybergear.MotionControlCmd(motorId, float32(impedance.SetPoint), 0, float32(impedance.Stiffness), float32(impedance.Damping), float32(impedance.Offset))
+	if err != nil {
+		return err
+	}
+
+	// Enable with zero gains first, the set point defaults to where the motor is now
+	_, initial, err := motionControlSetpoints(motorId, 0, 0)
+	if err != nil {
+		stopMotor(motorId)
+		return err
+	}
+	if !at {
+		impedance.SetPoint = initial
+	}
+
+	velocity := 0.0
+	limit := maxTorque(motorId)
+	step := func() error {
+		torque := clamp(impedance.FeedForward(velocity), -limit, limit)
+		frame, err := cybergear.MotionControlCmd(motorId, float32(impedance.SetPoint), 0, float32(impedance.Stiffness), float32(impedance.Damping), float32(torque))
+		if err != nil {
+			return err
+		}
+
+		feedback, err := requestFeedback(motorId, frame)
+		if err != nil {
+			return err
+		}
+		velocity = float64(feedback.Speed())
+		return nil
+	}
+
+	err = startBackgroundLoop(motorId, "impedance", rate, step, outputCh)
+	if err != nil {
+		stopMotor(motorId)
+		return err
+	}
+
+	outputCh <- fmt.Sprintf("impedance %s: spring %g Nm/rad and damper %g Nm/(rad/s) around %.3f rad, offset %g Nm, friction %g Nm + %g Nm/(rad/s) at %.0f Hz. 'impedance %02X stop' stops",
+		motorLabel(motorId), impedance.Stiffness, impedance.Damping, impedance.SetPoint, impedance.Offset, impedance.Coulomb, impedance.Viscous, rate, motorId)
+	return nil
+}
@@ -0,0 +1,47 @@
+package commands
+
+import (
+	"fmt"
+	"gocg/cybergear"
+	"gocg/parameters"
+	"gocg/slcan"
+)
+
+func mustLookupParameter(name string) cybergear.ParameterInfo {
+	p, err := cybergear.LookupParameter(name)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// info 7F - device identity card. The manual only documents the MCU id (type 0), the name, bar code and firmware
+// versions are in the config area and would take the undocumented type 9.
+func executeInfoCmd(args []string, outputCh chan string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("syntax error ('info <motor ID>')' Args: '%+v'", args)
+	}
+
+	motorId, err := parameters.MotorId(args[1])
+	if err != nil {
+		return err
+	}
+
+	frame, err := cybergear.FetchDeviceIdCmd(parameters.HostId, motorId)
+	if err != nil {
+		return err
+	}
+
+	reply, err := Request(frame, func(f slcan.Frame) bool {
+		id, ok := f.(*slcan.DeviceIdFrame)
+		return ok && id.MotorId() == motorId
+	})
+	if err != nil {
+		return err
+	}
+
+	outputCh <- fmt.Sprintf("Motor           : %s", motorLabel(motorId))
+	outputCh <- fmt.Sprintf("MCU id          : %s", reply.(*slcan.DeviceIdFrame).McuId())
+	outputCh <- fmt.Sprintf("info %02X OK", motorId)
+	return nil
+}
@@ -0,0 +1,399 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gocg/control"
+	"gocg/cybergear"
+	"gocg/joints"
+	"gocg/parameters"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	JOINTS_DEFAULT_ADDRESS = "127.0.0.1:9870"
+	JOINTS_DEFAULT_RATE    = 50.0 // Hz
+	JOINTS_MAX_MESSAGE     = 65536
+	UNIX_SOCKET_PREFIX     = "unix:"
+)
+
+// A joint following a trajectory
+type trajectoryPath struct {
+	path  control.Interpolator
+	start time.Time
+}
+
+// Publishes joint states of the profile's motors and runs the joint trajectories it receives, one JSON message per
+// datagram over UDP or a Unix socket
+type jointBridge struct {
+	conn   net.PacketConn
+	joints []parameters.Joint
+	rate   float64
+	kp     float64
+	kd     float64
+	stopCh chan struct{}
+	doneCh chan struct{} // Closed when the control loop returns
+
+	// Guarded by commandMutex, the control loop holds it while it talks to the motors
+	controlled map[byte]bool // Motors in motion control mode
+	errors     map[string]string
+
+	// Guarded by mutex, shared with the receiver
+	mutex     sync.Mutex
+	peer      net.Addr
+	fixedPeer bool
+	paths     map[string]trajectoryPath
+	positions map[string]float64
+}
+
+var activeJoints *jointBridge
+
+// joints start [address] [--peer address] [--rate hz] [--kp kp] [--kd kd] | joints stop
+func executeJointsCmd(args []string, outputCh chan string) error {
+	positional, opts, err := parseOptions(args, "peer", "rate", "kp", "kd")
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case len(positional) == 2 && positional[1] == "stop":
+		if activeJoints == nil {
+			return fmt.Errorf("joint bridge not running")
+		}
+		stopJoints(outputCh)
+		outputCh <- "joints stop OK"
+		return nil
+	case (len(positional) == 2 || len(positional) == 3) && positional[1] == "start":
+	default:
+		return fmt.Errorf("syntax error ('joints start [address] [--peer address] [--rate hz] [--kp kp] [--kd kd]' or 'joints stop')' Args: '%+v'", args)
+	}
+
+	if activeJoints != nil {
+		return fmt.Errorf("joint bridge already running ('joints stop' first)")
+	}
+
+	b := &jointBridge{
+		joints:     parameters.Joints(),
+		stopCh:     make(chan struct{}),
+		doneCh:     make(chan struct{}),
+		controlled: map[byte]bool{},
+		errors:     map[string]string{},
+		paths:      map[string]trajectoryPath{},
+		positions:  map[string]float64{},
+	}
+	if len(b.joints) == 0 {
+		return fmt.Errorf("no motors in profile, the joints are the motors of the profile")
+	}
+
+	for _, o := range []struct {
+		name  string
+		value *float64
+		def   float64
+	}{{"rate", &b.rate, JOINTS_DEFAULT_RATE}, {"kp", &b.kp, MOVE_DEFAULT_KP}, {"kd", &b.kd, MOVE_DEFAULT_KD}} {
+		*o.value, err = opts.float(o.name, o.def)
+		if err != nil {
+			return err
+		}
+	}
+	if b.rate <= 0 {
+		return fmt.Errorf("invalid rate %g Hz", b.rate)
+	}
+	// Checks the gains, the motion control frame has a range for them
+	_, err = cybergear.MotionControlCmd(0, 0, 0, float32(b.kp), float32(b.kd), 0)
+	if err != nil {
+		return err
+	}
+
+	address := JOINTS_DEFAULT_ADDRESS
+	if len(positional) == 3 {
+		address = positional[2]
+	}
+
+	if peer, ok := opts["peer"]; ok {
+		b.peer, err = resolvePacketAddr(peer)
+		if err != nil {
+			return err
+		}
+		b.fixedPeer = true
+	}
+
+	// The bridge owns the joints until it stops
+	for _, joint := range b.joints {
+		err = checkIdle(joint.MotorId)
+		if err != nil {
+			return err
+		}
+	}
+
+	b.conn, err = listenPacket(address)
+	if err != nil {
+		return err
+	}
+
+	activeJoints = b
+	for _, joint := range b.joints {
+		backgroundLoops[joint.MotorId] = &backgroundLoop{
+			name:        "joints",
+			stopCommand: "joints stop",
+			shared:      true,
+			stopCh:      b.stopCh,
+			doneCh:      b.doneCh,
+			started:     time.Now(),
+		}
+	}
+	go b.receive(outputCh)
+	go b.control(outputCh)
+
+	names := make([]string, len(b.joints))
+	for i, joint := range b.joints {
+		names[i] = fmt.Sprintf("%s=%02X", joint.Name, joint.MotorId)
+	}
+	outputCh <- fmt.Sprintf("Joint bridge on %s at %g Hz, joints %s", b.conn.LocalAddr(), b.rate, strings.Join(names, " "))
+	return nil
+}
+
+// UDP host:port, or unix:<path> for a Unix datagram socket
+func listenPacket(address string) (net.PacketConn, error) {
+	if strings.HasPrefix(address, UNIX_SOCKET_PREFIX) {
+		path := strings.TrimPrefix(address, UNIX_SOCKET_PREFIX)
+		// Left over from an earlier run
+		os.Remove(path)
+		return net.ListenPacket("unixgram", path)
+	}
+	return net.ListenPacket("udp", address)
+}
+
+func resolvePacketAddr(address string) (net.Addr, error) {
+	if strings.HasPrefix(address, UNIX_SOCKET_PREFIX) {
+		return net.ResolveUnixAddr("unixgram", strings.TrimPrefix(address, UNIX_SOCKET_PREFIX))
+	}
+	return net.ResolveUDPAddr("udp", address)
+}
+
+// Disables the motors the bridge controlled. Called with commandMutex held, so the control loop is between ticks and
+// returns when it gets the lock.
+func stopJoints(outputCh chan string) {
+	b := activeJoints
+	close(b.stopCh)
+	b.conn.Close()
+	if addr, ok := b.conn.LocalAddr().(*net.UnixAddr); ok {
+		os.Remove(addr.Name)
+	}
+
+	for _, joint := range b.joints {
+		if l, ok := backgroundLoops[joint.MotorId]; ok && l.stopCh == b.stopCh {
+			delete(backgroundLoops, joint.MotorId)
+		}
+	}
+
+	for motorId := range b.controlled {
+		_, err := stopMotor(motorId)
+		if err != nil {
+			outputCh <- fmt.Sprintf("[red]joints: disabling motor %02X failed: %s[-]", motorId, err)
+		}
+	}
+
+	activeJoints = nil
+}
+
+// Reads trajectories until the socket is closed. A message without joint names only registers the sender as the
+// peer, a trajectory without points holds the joints where they are.
+func (b *jointBridge) receive(outputCh chan string) {
+	buf := make([]byte, JOINTS_MAX_MESSAGE)
+
+	for {
+		n, addr, err := b.conn.ReadFrom(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				outputCh <- fmt.Sprintf("[red]joints: %s[-]", err)
+			}
+			return
+		}
+
+		var trajectory joints.JointTrajectory
+		err = json.Unmarshal(buf[:n], &trajectory)
+		if err == nil {
+			err = b.follow(&trajectory, addr)
+		}
+		if err != nil {
+			outputCh <- fmt.Sprintf("[red]joints: %s[-]", err)
+		}
+	}
+}
+
+func (b *jointBridge) follow(trajectory *joints.JointTrajectory, sender net.Addr) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if !b.fixedPeer && sender != nil {
+		b.peer = sender
+	}
+
+	for _, name := range trajectory.JointNames {
+		if _, ok := b.positions[name]; !ok {
+			if b.motorId(name) < 0 {
+				return fmt.Errorf("unknown joint '%s'", name)
+			}
+			return fmt.Errorf("no feedback from joint '%s' yet", name)
+		}
+	}
+
+	if len(trajectory.Points) == 0 {
+		// Hold the current positions
+		now := []float64{}
+		for _, name := range trajectory.JointNames {
+			now = append(now, b.positions[name])
+		}
+		trajectory.Points = []joints.JointTrajectoryPoint{{Positions: now}}
+	}
+
+	paths, duration, err := trajectory.Plan(b.positions)
+	if err != nil {
+		return err
+	}
+
+	// Like play, nothing moves if the speed along any of the paths is too high
+	for name, path := range paths {
+		err = checkPathSpeed(byte(b.motorId(name)), path, duration, 1)
+		if err != nil {
+			return fmt.Errorf("joint '%s' %s", name, err)
+		}
+	}
+
+	start := time.Now()
+	for name, path := range paths {
+		b.paths[name] = trajectoryPath{path, start}
+	}
+	return nil
+}
+
+// Motor id of the joint, -1 for unknown joints
+func (b *jointBridge) motorId(name string) int {
+	for _, joint := range b.joints {
+		if joint.Name == name {
+			return int(joint.MotorId)
+		}
+	}
+	return -1
+}
+
+// Sends the setpoints of the joints following a trajectory, requests the status of the others and publishes the joint
+// state to the peer, at the bridge rate
+func (b *jointBridge) control(outputCh chan string) {
+	defer close(b.doneCh)
+
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / b.rate))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-b.stopCh:
+			return
+		case <-ticker.C:
+		}
+
+		commandMutex.Lock()
+		select {
+		case <-b.stopCh:
+			commandMutex.Unlock()
+			return
+		default:
+		}
+
+		// Nothing to talk to, or the sniffer owns the adapter
+		if adapter == nil || activeSniffer != nil {
+			commandMutex.Unlock()
+			continue
+		}
+
+		state := b.step(outputCh)
+		commandMutex.Unlock()
+
+		b.mutex.Lock()
+		peer := b.peer
+		b.mutex.Unlock()
+		if peer == nil {
+			continue
+		}
+
+		data, err := json.Marshal(state)
+		if err == nil {
+			b.conn.WriteTo(data, peer)
+		}
+	}
+}
+
+func (b *jointBridge) step(outputCh chan string) joints.JointState {
+	now := time.Now()
+	state := joints.JointState{
+		Header:   joints.Header{Stamp: joints.NewTime(now)},
+		Name:     []string{},
+		Position: []float64{},
+		Velocity: []float64{},
+		Effort:   []float64{},
+	}
+
+	for _, joint := range b.joints {
+		b.mutex.Lock()
+		p, following := b.paths[joint.Name]
+		b.mutex.Unlock()
+
+		var status MotorStatus
+		var err error
+		if following {
+			status, err = b.setpoint(joint.MotorId, p, now)
+		} else {
+			status, err = b.status(joint.MotorId)
+		}
+
+		if err != nil {
+			if b.errors[joint.Name] != err.Error() {
+				outputCh <- fmt.Sprintf("[red]joints: %s: %s[-]", joint.Name, err)
+			}
+			b.errors[joint.Name] = err.Error()
+			continue
+		}
+		delete(b.errors, joint.Name)
+
+		state.Name = append(state.Name, joint.Name)
+		state.Position = append(state.Position, status.Angle)
+		state.Velocity = append(state.Velocity, status.Speed)
+		state.Effort = append(state.Effort, status.Torque)
+
+		b.mutex.Lock()
+		b.positions[joint.Name] = status.Angle
+		b.mutex.Unlock()
+	}
+
+	return state
+}
+
+func (b *jointBridge) setpoint(motorId byte, p trajectoryPath, now time.Time) (MotorStatus, error) {
+	if !b.controlled[motorId] {
+		_, _, err := motionControlSetpoints(motorId, 0, 0)
+		if err != nil {
+			return MotorStatus{}, err
+		}
+		b.controlled[motorId] = true
+	}
+
+	position, velocity := p.path.At(now.Sub(p.start).Seconds())
+	frame, err := cybergear.MotionControlCmd(motorId, float32(position), float32(velocity), float32(b.kp), float32(b.kd), 0)
+	if err != nil {
+		return MotorStatus{}, err
+	}
+	return feedbackStatus(requestFeedback(motorId, frame))
+}
+
+func (b *jointBridge) status(motorId byte) (MotorStatus, error) {
+	frame, err := cybergear.GetStatusCmd(parameters.HostId, motorId)
+	if err != nil {
+		return MotorStatus{}, err
+	}
+	return feedbackStatus(requestFeedback(motorId, frame))
+}
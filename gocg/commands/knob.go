@@ -0,0 +1,163 @@
+package commands
+
+import (
+	"fmt"
+	"gocg/control"
+	"gocg/cybergear"
+	"gocg/parameters"
+	"gocg/slcan"
+	"strconv"
+	"strings"
+)
+
+const (
+	KNOB_DEFAULT_RATE      = 200.0 // Hz
+	KNOB_DEFAULT_STIFFNESS = 10.0  // End stop spring, Nm/rad
+	KNOB_DEFAULT_FRICTION  = 0.02  // Nm
+	KNOB_DEFAULT_DAMPING   = 0.01  // Nm/(rad/s)
+	TORQUE_CONSTANT        = 0.87  // Nm/A, from the CyberGear data sheet
+)
+
+// knob 7F --detents N --strength Nm [--endstops a,b] [--stiffness Nm/rad] [--friction Nm] [--damping Nm/(rad/s)] [--via iq | mit] [--rate Hz]
+// knob 7F stop
+func executeKnobCmd(args []string, outputCh chan string) error {
+	positional, opts, err := parseOptions(args, "detents", "strength", "endstops", "stiffness", "friction", "damping", "via", "rate")
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 && !(len(positional) == 3 && positional[2] == "stop") {
+		return fmt.Errorf("syntax error ('knob <motor ID> --detents N --strength Nm [--endstops a,b] [--stiffness Nm/rad] [--friction Nm] [--damping Nm/(rad/s)] [--via iq | mit] [--rate Hz]' or 'knob <motor ID> stop')' Args: '%+v'", args)
+	}
+
+	motorId, err := parameters.MotorId(positional[1])
+	if err != nil {
+		return err
+	}
+
+	if len(positional) == 3 {
+		summary, err := stopBackgroundLoop(motorId)
+		if err != nil {
+			return err
+		}
+		outputCh <- fmt.Sprintf("knob %02X stopped, motor disabled: %s", motorId, summary)
+		return nil
+	}
+
+	knob := control.Knob{}
+	detents, err := strconv.Atoi(opts["detents"])
+	if err != nil || detents < 0 {
+		return fmt.Errorf("invalid or missing --detents '%s'", opts["detents"])
+	}
+	knob.Detents = detents
+
+	for _, o := range []struct {
+		name  string
+		value *float64
+		def   float64
+	}{
+		{"strength", &knob.Strength, 0}, // Required
+		{"stiffness", &knob.Stiffness, KNOB_DEFAULT_STIFFNESS},
+		{"friction", &knob.Friction, KNOB_DEFAULT_FRICTION},
+		{"damping", &knob.Damping, KNOB_DEFAULT_DAMPING},
+	} {
+		*o.value, err = opts.float(o.name, o.def)
+		if err != nil {
+			return err
+		}
+	}
+	if _, ok := opts["strength"]; !ok || knob.Strength <= 0 {
+		return fmt.Errorf("invalid or missing --strength '%s'", opts["strength"])
+	}
+	rate, err := opts.float("rate", KNOB_DEFAULT_RATE)
+	if err != nil {
+		return err
+	}
+
+	if endStops, ok := opts["endstops"]; ok {
+		limits := strings.Split(endStops, ",")
+		if len(limits) != 2 {
+			return fmt.Errorf("invalid --endstops '%s' (min,max in rad)", endStops)
+		}
+		knob.Min, err = strconv.ParseFloat(limits[0], 64)
+		if err == nil {
+			knob.Max, err = strconv.ParseFloat(limits[1], 64)
+		}
+		if err != nil || knob.Min >= knob.Max {
+			return fmt.Errorf("invalid --endstops '%s' (min,max in rad)", endStops)
+		}
+		knob.EndStops = true
+	}
+
+	via := opts["via"]
+	if via == "" {
+		via = "iq"
+	}
+	if via != "iq" && via != "mit" {
+		return fmt.Errorf("unknown --via '%s' (iq | mit)", via)
+	}
+
+	err = checkIdle(motorId)
+	if err != nil {
+		return err
+	}
+	if rate <= 0 {
+		return fmt.Errorf("invalid rate %g Hz", rate)
+	}
+
+	// A detent where the knob is now
+	feedback, err := stopMotor(motorId)
+	if err != nil {
+		return err
+	}
+	knob.Center = float64(feedback.Angle())
+
+	var command func(torque float64) (*slcan.MotorFeedback, error)
+	if via == "iq" {
+		_, _, err = startLoop(motorId, "current")
+		maxCurrent := iqRef.Max
+		if limit := parameters.MotorLimits(motorId).Current; limit > 0 && float64(limit) < maxCurrent {
+			maxCurrent = float64(limit)
+		}
+		command = func(torque float64) (*slcan.MotorFeedback, error) {
+			return writeSetpoint(motorId, iqRef, clamp(torque/TORQUE_CONSTANT, -maxCurrent, maxCurrent))
+		}
+	} else {
+		_, _, err = motionControlSetpoints(motorId, 0, 0)
+		limit := maxTorque(motorId)
+		command = func(torque float64) (*slcan.MotorFeedback, error) {
+			frame, err := cybergear.MotionControlCmd(motorId, 0, 0, 0, 0, float32(clamp(torque, -limit, limit)))
+			if err != nil {
+				return nil, err
+			}
+			return requestFeedback(motorId, frame)
+		}
+	}
+	if err != nil {
+		stopMotor(motorId)
+		return err
+	}
+
+	angle, velocity := knob.Center, 0.0
+	step := func() error {
+		feedback, err := command(knob.Torque(angle, velocity))
+		if err != nil {
+			return err
+		}
+		angle, velocity = float64(feedback.Angle()), float64(feedback.Speed())
+		return nil
+	}
+
+	err = startBackgroundLoop(motorId, "knob", rate, step, outputCh)
+	if err != nil {
+		stopMotor(motorId)
+		return err
+	}
+
+	endStops := "no end stops"
+	if knob.EndStops {
+		endStops = fmt.Sprintf("end stops at %g and %g rad", knob.Min, knob.Max)
+	}
+	outputCh <- fmt.Sprintf("knob %s: %d detents of %g Nm, %s, friction %g Nm, torque via %s at %.0f Hz. 'knob %02X stop' stops and prints the loop timing",
+		motorLabel(motorId), knob.Detents, knob.Strength, endStops, knob.Friction, via, rate, motorId)
+	return nil
+}
@@ -0,0 +1,110 @@
+package commands
+
+import (
+	"fmt"
+	"gocg/metrics"
+	"gocg/parameters"
+	"gocg/slcan"
+	"strings"
+	"time"
+)
+
+const (
+	STATUS_POLL_INTERVAL = time.Second
+	BUS_LOAD_ALERT       = 0.7 // Fraction of the bitrate
+)
+
+// Bus load and alerts for the status bar, updated from the command goroutine
+type busMonitor struct {
+	last     metrics.TransportSnapshot
+	lastTime time.Time
+	load     float64           // Fraction of the bitrate
+	rate     float64           // Frames per second
+	alerts   map[string]string // Message of each alert currently raised
+	status   string
+}
+
+var monitor = &busMonitor{alerts: map[string]string{}}
+
+// Reads the SLCAN status flags into the bus statistics. Unknown if the adapter doesn't answer.
+func pollStatusFlags() {
+	flags, err := adapter.StatusFlags()
+	if err != nil {
+		metrics.Bus.ClearStatusFlags()
+		return
+	}
+	metrics.Bus.SetStatusFlags(byte(flags))
+}
+
+// Polls the adapter status, updates the bus load and reports alerts on outputCh when a threshold is crossed (and
+// when it's back to normal). Returns the status bar text.
+func UpdateStatus(outputCh chan string) string {
+	// A remote API command is running, try again next time
+	if !commandMutex.TryLock() {
+		return monitor.status
+	}
+	defer commandMutex.Unlock()
+
+	if adapter == nil {
+		metrics.Bus.ClearStatusFlags()
+	} else if activeSniffer == nil {
+		busMutex.Lock()
+		pollStatusFlags()
+		busMutex.Unlock()
+	}
+
+	now := time.Now()
+	snapshot := metrics.Bus.Snapshot()
+	_, profile := parameters.ActiveProfile()
+
+	// Counters go backwards after 'stats reset'
+	if !monitor.lastTime.IsZero() && snapshot.Bits >= monitor.last.Bits && profile.Bitrate > 0 {
+		elapsed := now.Sub(monitor.lastTime).Seconds()
+		frames := snapshot.FramesSent + snapshot.FramesReceived - monitor.last.FramesSent - monitor.last.FramesReceived
+		monitor.load = float64(snapshot.Bits-monitor.last.Bits) / elapsed / float64(profile.Bitrate)
+		monitor.rate = float64(frames) / elapsed
+	}
+	flags, flagsKnown := metrics.Bus.StatusFlags()
+
+	monitor.alert("load", monitor.load > BUS_LOAD_ALERT,
+		fmt.Sprintf("bus load %.0f%% above %.0f%% of %d bit/s", 100*monitor.load, 100*BUS_LOAD_ALERT, profile.Bitrate), outputCh)
+	monitor.alert("flags", flagsKnown && flags != 0, fmt.Sprintf("adapter status: %s", slcan.StatusFlags(flags)), outputCh)
+	monitor.alert("decode", snapshot.DecodeFailures > monitor.last.DecodeFailures,
+		fmt.Sprintf("%d frames couldn't be decoded", snapshot.DecodeFailures-monitor.last.DecodeFailures), outputCh)
+	monitor.alert("overrun", snapshot.Overruns > monitor.last.Overruns,
+		fmt.Sprintf("%d serial overruns", snapshot.Overruns-monitor.last.Overruns), outputCh)
+
+	monitor.last = snapshot
+	monitor.lastTime = now
+
+	if adapter == nil {
+		monitor.status = "adapter closed"
+		return monitor.status
+	}
+
+	status := []string{
+		fmt.Sprintf("bus %.1f%%", 100*monitor.load),
+		fmt.Sprintf("%.0f frames/s", monitor.rate),
+		fmt.Sprintf("%d decode errors", snapshot.DecodeFailures),
+		fmt.Sprintf("%d overruns", snapshot.Overruns),
+	}
+	if flagsKnown {
+		status = append(status, "flags "+slcan.StatusFlags(flags).String())
+	}
+	if len(monitor.alerts) > 0 {
+		status = append([]string{"ALERT"}, status...)
+	}
+	monitor.status = strings.Join(status, " | ")
+	return monitor.status
+}
+
+func (m *busMonitor) alert(name string, raised bool, message string, outputCh chan string) {
+	switch {
+	case raised && m.alerts[name] == "":
+		m.alerts[name] = message
+		outputCh <- fmt.Sprintf("[red]ALERT: %s[-]", message)
+	case !raised && m.alerts[name] != "":
+		outputCh <- fmt.Sprintf("[yellow]Cleared: %s[-]", m.alerts[name])
+		delete(m.alerts, name)
+	}
+}
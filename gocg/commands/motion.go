@@ -0,0 +1,176 @@
+package commands
+
+import (
+	"fmt"
+	"gocg/control"
+	"gocg/cybergear"
+	"gocg/parameters"
+	"gocg/slcan"
+	"math"
+	"time"
+)
+
+// Sends a frame and waits for the feedback frame from the motor
+func requestFeedback(motorId byte, frame *cybergear.Frame) (*slcan.MotorFeedback, error) {
+	reply, err := Request(frame, func(f slcan.Frame) bool {
+		_, ok := f.(*slcan.MotorFeedback)
+		return ok && f.MotorId() == motorId
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	feedback := reply.(*slcan.MotorFeedback)
+	if feedback.Fault() {
+		return feedback, fmt.Errorf("motor %02X fault: %s", motorId, feedback.Faults())
+	}
+
+	return feedback, nil
+}
+
+var (
+	iqRef  = mustLookupParameter("PARAMETER_IQ_REF")
+	spdRef = mustLookupParameter("PARAMETER_SPD_REF")
+	locRef = mustLookupParameter("PARAMETER_LOC_REF")
+)
+
+// Writes a run area setpoint (e.g. PARAMETER_SPD_REF) and returns the feedback the motor answers with
+func writeSetpoint(motorId byte, p cybergear.ParameterInfo, value float64) (*slcan.MotorFeedback, error) {
+	frame, err := cybergear.WriteParameterValueCmd(parameters.HostId, motorId, p, value)
+	if err != nil {
+		return nil, err
+	}
+	return requestFeedback(motorId, frame)
+}
+
+// Stops the motor, sends the run mode frame (cybergear.SetRunMode) and enables the motor again. The run mode can only
+// be changed while the motor is stopped.
+func startMode(motorId byte, runModeFrame *cybergear.Frame) (*slcan.MotorFeedback, error) {
+	_, err := stopMotor(motorId)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = requestFeedback(motorId, runModeFrame)
+	if err != nil {
+		return nil, err
+	}
+
+	frame, err := cybergear.EnableMotorCmd(parameters.HostId, motorId)
+	if err != nil {
+		return nil, err
+	}
+	return requestFeedback(motorId, frame)
+}
+
+// Disables the motor. Faults don't count as errors here, stopping is what you do about them.
+func stopMotor(motorId byte) (*slcan.MotorFeedback, error) {
+	frame, err := cybergear.DisableMotorCmd(parameters.HostId, motorId)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := Request(frame, func(f slcan.Frame) bool {
+		_, ok := f.(*slcan.MotorFeedback)
+		return ok && f.MotorId() == motorId
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reply.(*slcan.MotorFeedback), nil
+}
+
+// A closed loop of the motor: the run mode, the setpoint parameter and the feedback value that follows it
+type controlLoop struct {
+	name     string
+	setpoint cybergear.ParameterInfo
+	unit     string
+	value    func(*slcan.MotorFeedback) float64
+}
+
+var controlLoops = map[string]controlLoop{
+	"speed":    {"speed", spdRef, "rad/s", func(f *slcan.MotorFeedback) float64 { return float64(f.Speed()) }},
+	"position": {"position", locRef, "rad", func(f *slcan.MotorFeedback) float64 { return float64(f.Angle()) }},
+	// The feedback frame has no current, the torque follows it
+	"current": {"current", iqRef, "A (torque in Nm)", func(f *slcan.MotorFeedback) float64 { return float64(f.Torque()) }},
+}
+
+// Switches the motor to the run mode of the loop and enables it. Returns the initial setpoint, the current position
+// in position mode (so that the motor holds it) and 0 otherwise.
+func startLoop(motorId byte, name string) (controlLoop, float64, error) {
+	loop, ok := controlLoops[name]
+	if !ok {
+		return loop, 0, fmt.Errorf("unknown mode '%s' (speed | position | current)", name)
+	}
+
+	initial := 0.0
+	var frame *cybergear.Frame
+	var err error
+
+	switch name {
+	case "speed":
+		frame, err = cybergear.SetRunMode(parameters.HostId, motorId, cybergear.SPEED_MODE)
+	case "current":
+		frame, err = cybergear.SetRunMode(parameters.HostId, motorId, cybergear.CURRENT_MODE)
+	case "position":
+		var feedback *slcan.MotorFeedback
+		feedback, err = stopMotor(motorId)
+		if err != nil {
+			return loop, 0, err
+		}
+		initial = float64(feedback.Angle())
+
+		// Hold the current position when the motor is enabled
+		_, err = writeSetpoint(motorId, locRef, initial)
+		if err != nil {
+			return loop, 0, err
+		}
+		frame, err = cybergear.SetRunMode(parameters.HostId, motorId, cybergear.LOCATION_MODE)
+	}
+	if err != nil {
+		return loop, 0, err
+	}
+
+	_, err = startMode(motorId, frame)
+	return loop, initial, err
+}
+
+// Checks a speed or current setpoint against the limits of the motor in the active profile
+func checkLimits(motorId byte, loop controlLoop, setpoint float64) error {
+	limits := parameters.MotorLimits(motorId)
+
+	switch {
+	case loop.name == "speed" && limits.Speed > 0 && math.Abs(setpoint) > float64(limits.Speed):
+		return fmt.Errorf("speed %.2f rad/s exceeds the configured limit of %.2f rad/s for motor %02X", setpoint, limits.Speed, motorId)
+	case loop.name == "current" && limits.Current > 0 && math.Abs(setpoint) > float64(limits.Current):
+		return fmt.Errorf("current %.2f A exceeds the configured limit of %.2f A for motor %02X", setpoint, limits.Current, motorId)
+	}
+	return nil
+}
+
+// Zeroes the speed or current setpoint and disables the motor
+func stopLoop(motorId byte, loop controlLoop) {
+	if loop.name != "position" {
+		writeSetpoint(motorId, loop.setpoint, 0)
+	}
+	stopMotor(motorId)
+}
+
+// Writes reference(t) to the setpoint of the loop for the given duration and records the feedback as fast as the
+// motor answers
+func recordResponse(motorId byte, loop controlLoop, duration time.Duration, reference func(t float64) float64) ([]control.Sample, error) {
+	var samples []control.Sample
+
+	start := time.Now()
+	for time.Since(start) < duration {
+		r := reference(time.Since(start).Seconds())
+
+		feedback, err := writeSetpoint(motorId, loop.setpoint, r)
+		if err != nil {
+			return samples, err
+		}
+		samples = append(samples, control.Sample{Time: time.Since(start).Seconds(), Reference: r, Value: loop.value(feedback)})
+	}
+
+	return samples, nil
+}
@@ -0,0 +1,197 @@
+package commands
+
+import (
+	"fmt"
+	"gocg/control"
+	"gocg/cybergear"
+	"gocg/parameters"
+	"gocg/slcan"
+	"math"
+	"strconv"
+	"time"
+)
+
+const (
+	MOVE_DEFAULT_RATE = 100.0 // Hz
+	MOVE_SETTLE       = 300 * time.Millisecond
+	MOVE_DEFAULT_KP   = 30.0
+	MOVE_DEFAULT_KD   = 1.0
+)
+
+// Sends one trajectory setpoint and returns the feedback
+type setpointFunc func(position float64, velocity float64) (*slcan.MotorFeedback, error)
+
+// Position setpoints as PARAMETER_LOC_REF writes in location mode
+func locationSetpoints(motorId byte) (setpointFunc, float64, error) {
+	loop, initial, err := startLoop(motorId, "position")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return func(position float64, velocity float64) (*slcan.MotorFeedback, error) {
+		return writeSetpoint(motorId, loop.setpoint, position)
+	}, initial, nil
+}
+
+// Position and velocity setpoints as motion control frames (type 1) in operation control mode
+func motionControlSetpoints(motorId byte, kp float64, kd float64) (setpointFunc, float64, error) {
+	feedback, err := stopMotor(motorId)
+	if err != nil {
+		return nil, 0, err
+	}
+	initial := float64(feedback.Angle())
+
+	frame, err := cybergear.SetRunMode(parameters.HostId, motorId, cybergear.OPEARATION_CONTROL_MODE)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Hold the current position from the first frame on
+	hold, err := cybergear.MotionControlCmd(motorId, float32(initial), 0, float32(kp), float32(kd), 0)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	_, err = startMode(motorId, frame)
+	if err != nil {
+		return nil, 0, err
+	}
+	_, err = requestFeedback(motorId, hold)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return func(position float64, velocity float64) (*slcan.MotorFeedback, error) {
+		frame, err := cybergear.MotionControlCmd(motorId, float32(position), float32(velocity), float32(kp), float32(kd), 0)
+		if err != nil {
+			return nil, err
+		}
+		return requestFeedback(motorId, frame)
+	}, initial, nil
+}
+
+// Streams the trajectory at the given rate and keeps sending the end point for MOVE_SETTLE. Returns reference and
+// measured angle.
+func streamTrajectory(trajectory control.Trajectory, rate float64, setpoint setpointFunc) ([]control.Sample, error) {
+	var samples []control.Sample
+
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / rate))
+	defer ticker.Stop()
+
+	start := time.Now()
+	end := trajectory.Duration() + MOVE_SETTLE.Seconds()
+	for t := 0.0; t <= end; t = time.Since(start).Seconds() {
+		position, velocity, _ := trajectory.Sample(t)
+
+		feedback, err := setpoint(position, velocity)
+		if err != nil {
+			return samples, err
+		}
+		samples = append(samples, control.Sample{Time: t, Reference: position, Value: float64(feedback.Angle())})
+
+		<-ticker.C
+	}
+
+	return samples, nil
+}
+
+// move 7F 1.57 --vmax 2 --amax 5 [--jmax 50] [--rate 100] [--via loc | mit] [--kp 30] [--kd 1]
+func executeMoveCmd(args []string, outputCh chan string) error {
+	positional, opts, err := parseOptions(args, "vmax", "amax", "jmax", "rate", "via", "kp", "kd")
+	if err != nil {
+		return err
+	}
+
+	_, hasVmax := opts["vmax"]
+	_, hasAmax := opts["amax"]
+	if len(positional) != 3 || !hasVmax || !hasAmax {
+		return fmt.Errorf("syntax error ('move <motor ID> <rad> --vmax <rad/s> --amax <rad/s2> [--jmax <rad/s3>] [--rate Hz] [--via loc | mit] [--kp kp] [--kd kd]')' Args: '%+v'", args)
+	}
+
+	motorId, err := parameters.MotorId(positional[1])
+	if err != nil {
+		return err
+	}
+	err = checkIdle(motorId)
+	if err != nil {
+		return err
+	}
+
+	target, err := strconv.ParseFloat(positional[2], 64)
+	if err != nil {
+		return fmt.Errorf("invalid target angle '%s'", positional[2])
+	}
+
+	var vmax, amax, jmax, rate, kp, kd float64
+	for _, o := range []struct {
+		name  string
+		value *float64
+		def   float64
+	}{{"vmax", &vmax, 0}, {"amax", &amax, 0}, {"jmax", &jmax, 0}, {"rate", &rate, MOVE_DEFAULT_RATE}, {"kp", &kp, MOVE_DEFAULT_KP}, {"kd", &kd, MOVE_DEFAULT_KD}} {
+		*o.value, err = opts.float(o.name, o.def)
+		if err != nil {
+			return err
+		}
+	}
+
+	if rate <= 0 {
+		return fmt.Errorf("invalid rate %g Hz", rate)
+	}
+
+	err = checkLimits(motorId, controlLoops["speed"], vmax)
+	if err != nil {
+		return err
+	}
+
+	var setpoint setpointFunc
+	var initial float64
+	switch opts["via"] {
+	case "", "loc":
+		setpoint, initial, err = locationSetpoints(motorId)
+		if err == nil {
+			limit, err := readParameter(motorId, mustLookupParameter("PARAMETER_LIMIT_SPD"))
+			if err == nil && limit < vmax {
+				outputCh <- fmt.Sprintf("[yellow]PARAMETER_LIMIT_SPD (%.2f rad/s) is below vmax, the motor will lag behind[-]", limit)
+			}
+		}
+	case "mit":
+		setpoint, initial, err = motionControlSetpoints(motorId, kp, kd)
+	default:
+		return fmt.Errorf("unknown setpoint path '%s' (loc | mit)", opts["via"])
+	}
+	if err != nil {
+		stopMotor(motorId)
+		return err
+	}
+
+	var trajectory control.Trajectory
+	profile := "trapezoidal"
+	if _, ok := opts["jmax"]; ok {
+		profile = "S-curve"
+		trajectory, err = control.NewSCurve(initial, target, vmax, amax, jmax)
+	} else {
+		trajectory, err = control.NewTrapezoidal(initial, target, vmax, amax)
+	}
+	if err != nil {
+		return err
+	}
+
+	outputCh <- fmt.Sprintf("Moving motor %02X %.3f -> %.3f rad (%s, %.2f s at %.0f Hz)", motorId, initial, target, profile, trajectory.Duration(), rate)
+
+	samples, err := streamTrajectory(trajectory, rate, setpoint)
+	if err != nil {
+		stopMotor(motorId)
+		return err
+	}
+
+	duringMove := samples[:firstSampleAfter(samples, trajectory.Duration())]
+	maxError, rmsError := control.TrackingError(duringMove)
+	final := samples[len(samples)-1]
+
+	outputCh <- fmt.Sprintf("Samples        : %d (%.0f Hz)", len(samples), float64(len(samples))/final.Time)
+	outputCh <- fmt.Sprintf("Tracking error : max %.4f rad, rms %.4f rad", maxError, rmsError)
+	outputCh <- fmt.Sprintf("Final error    : %.4f rad", math.Abs(target-final.Value))
+	outputCh <- fmt.Sprintf("move %02X %.3f OK. The motor holds the position, 'disable %02X' to release it", motorId, target, motorId)
+
+	return nil
+}
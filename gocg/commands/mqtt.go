@@ -0,0 +1,351 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"gocg/parameters"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+const (
+	MQTT_DEFAULT_PREFIX    = "cybergear"
+	MQTT_DEFAULT_CLIENT_ID = "gocg"
+	MQTT_DEFAULT_QOS       = 1
+	MQTT_TIMEOUT           = 5 * time.Second
+	MQTT_COMMAND_QUEUE     = 64
+)
+
+// Publishes feedback, faults and parameter reads of the motors and runs the JSON commands sent to them
+type mqttBridge struct {
+	client  mqtt.Client
+	prefix  string
+	qos     byte
+	stopCh  chan struct{}
+	done    sync.WaitGroup // publishEvents and runCommands
+	queue   chan mqtt.Message
+	mutex   sync.Mutex
+	enabled map[byte]bool // Motors enabled through the bridge, disabled when the broker connection drops
+}
+
+// A command on <prefix>/<motor>/cmd
+type mqttCommand struct {
+	Id     string   `json:"id,omitempty"` // Copied to the reply
+	Op     string   `json:"op"`
+	Mode   string   `json:"mode,omitempty"`
+	Value  *float64 `json:"value,omitempty"`
+	Param  string   `json:"param,omitempty"`
+	Angle  float64  `json:"angle,omitempty"`
+	Speed  float64  `json:"speed,omitempty"`
+	Kp     float64  `json:"kp,omitempty"`
+	Kd     float64  `json:"kd,omitempty"`
+	Torque float64  `json:"torque,omitempty"`
+}
+
+// The answer on <prefix>/<motor>/reply
+type mqttReply struct {
+	Id     string      `json:"id,omitempty"`
+	Op     string      `json:"op"`
+	Result interface{} `json:"result,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// Published on <prefix>/<motor>/faults (retained) when the set of active faults changes
+type mqttFaults struct {
+	Time    time.Time `json:"time"`
+	Active  []string  `json:"active"`
+	Raised  []string  `json:"raised,omitempty"`
+	Cleared []string  `json:"cleared,omitempty"`
+}
+
+var activeBridge *mqttBridge
+
+// mqtt start <broker> [--prefix p] [--qos 0|1|2] [--client id] [--user u --password p] | mqtt stop
+func executeMqttCmd(args []string, outputCh chan string) error {
+	positional, opts, err := parseOptions(args, "prefix", "qos", "client", "user", "password")
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case len(positional) == 2 && positional[1] == "stop":
+		if activeBridge == nil {
+			return fmt.Errorf("MQTT bridge not running")
+		}
+		b := activeBridge
+		activeBridge = nil
+		// runCommands may be waiting for the command mutex this command holds
+		commandMutex.Unlock()
+		b.stop()
+		commandMutex.Lock()
+		outputCh <- "mqtt stop OK"
+		return nil
+	case len(positional) == 3 && positional[1] == "start":
+	default:
+		return fmt.Errorf("syntax error ('mqtt start <broker> [--prefix p] [--qos 0|1|2] [--client id] [--user u --password p]' or 'mqtt stop')' Args: '%+v'", args)
+	}
+
+	if activeBridge != nil {
+		return fmt.Errorf("MQTT bridge already running ('mqtt stop' first)")
+	}
+
+	qos, err := opts.float("qos", MQTT_DEFAULT_QOS)
+	if err != nil {
+		return err
+	}
+	if qos != 0 && qos != 1 && qos != 2 {
+		return fmt.Errorf("invalid QoS %g (0, 1 or 2)", qos)
+	}
+
+	b := &mqttBridge{
+		prefix:  MQTT_DEFAULT_PREFIX,
+		qos:     byte(qos),
+		stopCh:  make(chan struct{}),
+		queue:   make(chan mqtt.Message, MQTT_COMMAND_QUEUE),
+		enabled: map[byte]bool{},
+	}
+	if prefix, ok := opts["prefix"]; ok {
+		b.prefix = strings.TrimSuffix(prefix, "/")
+	}
+
+	broker := positional[2]
+	if !strings.Contains(broker, "://") {
+		broker = "tcp://" + broker
+	}
+
+	clientOpts := mqtt.NewClientOptions().
+		AddBroker(broker).
+		SetClientID(MQTT_DEFAULT_CLIENT_ID).
+		SetAutoReconnect(true).
+		SetConnectTimeout(MQTT_TIMEOUT).
+		// Subscribers learn that the bridge is gone from the broker
+		SetWill(b.prefix+"/bridge/status", "offline", b.qos, true).
+		SetOnConnectHandler(func(c mqtt.Client) { b.connected(outputCh) }).
+		SetConnectionLostHandler(func(c mqtt.Client, err error) { b.connectionLost(err, outputCh) })
+	if client, ok := opts["client"]; ok {
+		clientOpts.SetClientID(client)
+	}
+	if user, ok := opts["user"]; ok {
+		clientOpts.SetUsername(user)
+		clientOpts.SetPassword(opts["password"])
+	}
+
+	b.client = mqtt.NewClient(clientOpts)
+	token := b.client.Connect()
+	if !token.WaitTimeout(MQTT_TIMEOUT) {
+		b.client.Disconnect(0)
+		return fmt.Errorf("timeout connecting to %s", broker)
+	}
+	if token.Error() != nil {
+		return token.Error()
+	}
+
+	activeBridge = b
+	b.start(outputCh)
+
+	outputCh <- fmt.Sprintf("MQTT bridge connected to %s, publishing to %s/<motor>/state, commands on %s/<motor>/cmd", broker, b.prefix, b.prefix)
+	return nil
+}
+
+// Called on every (re)connect, subscriptions don't survive a lost connection
+func (b *mqttBridge) connected(outputCh chan string) {
+	b.client.Publish(b.prefix+"/bridge/status", b.qos, true, "online")
+
+	token := b.client.Subscribe(b.prefix+"/+/cmd", b.qos, func(c mqtt.Client, m mqtt.Message) {
+		select {
+		case b.queue <- m:
+		default:
+			outputCh <- fmt.Sprintf("[yellow]mqtt: command queue full, dropped command on %s[-]", m.Topic())
+		}
+	})
+	if token.WaitTimeout(MQTT_TIMEOUT) && token.Error() != nil {
+		outputCh <- fmt.Sprintf("[red]mqtt: %s[-]", token.Error())
+	}
+}
+
+// Motors that were enabled through the bridge can't be stopped by their controller any more, disable them
+func (b *mqttBridge) connectionLost(err error, outputCh chan string) {
+	outputCh <- fmt.Sprintf("[red]mqtt: connection lost (%s), reconnecting[-]", err)
+
+	for _, motorId := range b.takeEnabled() {
+		_, err := DisableMotor(motorId)
+		if err != nil {
+			outputCh <- fmt.Sprintf("[red]mqtt: disabling motor %02X failed: %s[-]", motorId, err)
+			continue
+		}
+		outputCh <- fmt.Sprintf("[yellow]mqtt: motor %02X disabled[-]", motorId)
+	}
+}
+
+func (b *mqttBridge) takeEnabled() []byte {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	motorIds := make([]byte, 0, len(b.enabled))
+	for motorId := range b.enabled {
+		motorIds = append(motorIds, motorId)
+	}
+	sort.Slice(motorIds, func(i, j int) bool { return motorIds[i] < motorIds[j] })
+	b.enabled = map[byte]bool{}
+	return motorIds
+}
+
+func (b *mqttBridge) setEnabled(motorId byte, enabled bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if enabled {
+		b.enabled[motorId] = true
+	} else {
+		delete(b.enabled, motorId)
+	}
+}
+
+func (b *mqttBridge) start(outputCh chan string) {
+	b.done.Add(2)
+	go func() {
+		defer b.done.Done()
+		b.publishEvents()
+	}()
+	go func() {
+		defer b.done.Done()
+		b.runCommands(outputCh)
+	}()
+}
+
+// Returns once publishEvents and runCommands are done, nothing touches the motors after that
+func (b *mqttBridge) stop() {
+	close(b.stopCh)
+	b.done.Wait()
+	b.client.Publish(b.prefix+"/bridge/status", b.qos, true, "offline").WaitTimeout(MQTT_TIMEOUT)
+	b.client.Disconnect(250)
+}
+
+// Topic name of the motor: its name in the profile, or the hex CAN id
+func mqttMotor(motorId byte) string {
+	if name := parameters.MotorName(motorId); name != "" {
+		return name
+	}
+	return fmt.Sprintf("%02X", motorId)
+}
+
+func (b *mqttBridge) publish(motorId byte, topic string, retained bool, payload interface{}) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+	b.client.Publish(fmt.Sprintf("%s/%s/%s", b.prefix, mqttMotor(motorId), topic), b.qos, retained, data)
+}
+
+// Forwards the decoded frames until the bridge stops
+func (b *mqttBridge) publishEvents() {
+	ch, unsubscribe := SubscribeEvents(256)
+	defer unsubscribe()
+
+	faults := faultTracker{}
+
+	for {
+		var e Event
+		select {
+		case <-b.stopCh:
+			return
+		case e = <-ch:
+		}
+
+		motorId, err := strconv.ParseUint(e.MotorId, 16, 8)
+		if err != nil {
+			continue
+		}
+
+		switch e.Kind {
+		case "parameter":
+			b.publish(byte(motorId), "param", false, e.Parameter)
+		case "feedback":
+			b.publish(byte(motorId), "state", true, struct {
+				Time time.Time `json:"time"`
+				MotorStatus
+			}{e.Time, *e.Feedback})
+		}
+
+		// From the feedback and from the fault reports
+		change, ok := faults.update(e)
+		if ok && (change.First || len(change.Raised) > 0 || len(change.Cleared) > 0) {
+			b.publish(byte(motorId), "faults", true, mqttFaults{Time: e.Time, Active: change.Active, Raised: change.Raised, Cleared: change.Cleared})
+		}
+	}
+}
+
+// Runs the queued commands one after the other, in the order they arrived
+func (b *mqttBridge) runCommands(outputCh chan string) {
+	for {
+		var m mqtt.Message
+		select {
+		case <-b.stopCh:
+			return
+		case m = <-b.queue:
+		}
+
+		// <prefix>/<motor>/cmd
+		motor := strings.TrimSuffix(strings.TrimPrefix(m.Topic(), b.prefix+"/"), "/cmd")
+		motorId, err := parameters.MotorId(motor)
+		if err != nil {
+			outputCh <- fmt.Sprintf("[red]mqtt: %s[-]", err)
+			continue
+		}
+
+		var command mqttCommand
+		reply := mqttReply{}
+		err = json.Unmarshal(m.Payload(), &command)
+		if err == nil {
+			reply.Id, reply.Op = command.Id, command.Op
+			reply.Result, err = b.run(motorId, command)
+		}
+		if err != nil {
+			reply.Error = err.Error()
+		}
+		b.publish(motorId, "reply", false, reply)
+	}
+}
+
+func (b *mqttBridge) run(motorId byte, c mqttCommand) (interface{}, error) {
+	switch c.Op {
+	case "enable":
+		status, err := EnableMotor(motorId)
+		if err == nil {
+			b.setEnabled(motorId, true)
+		}
+		return status, err
+	case "disable":
+		status, err := DisableMotor(motorId)
+		b.setEnabled(motorId, false)
+		return status, err
+	case "status":
+		return GetStatus(motorId)
+	case "mode":
+		status, err := SetMode(motorId, c.Mode)
+		if err == nil {
+			b.setEnabled(motorId, true)
+		}
+		return status, err
+	case "speed", "position", "current":
+		if c.Value == nil {
+			return nil, fmt.Errorf("missing value")
+		}
+		return SetSetpoint(motorId, c.Op, *c.Value)
+	case "motion":
+		return MotionControl(motorId, c.Angle, c.Speed, c.Kp, c.Kd, c.Torque)
+	case "read":
+		return ReadParam(motorId, c.Param)
+	case "write":
+		if c.Value == nil {
+			return nil, fmt.Errorf("missing value")
+		}
+		return WriteParam(motorId, c.Param, *c.Value)
+	}
+	return nil, fmt.Errorf("unknown op '%s' (enable | disable | status | mode | speed | position | current | motion | read | write)", c.Op)
+}
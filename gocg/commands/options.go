@@ -0,0 +1,54 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Command line options of the form --name value, mixed with positional arguments
+type options map[string]string
+
+// Splits args into positional arguments and --name value options. Only the given option names are accepted.
+func parseOptions(args []string, names ...string) ([]string, options, error) {
+	var positional []string
+	opts := options{}
+
+	for i := 0; i < len(args); i++ {
+		if !strings.HasPrefix(args[i], "--") {
+			positional = append(positional, args[i])
+			continue
+		}
+
+		name := strings.TrimPrefix(args[i], "--")
+		known := false
+		for _, n := range names {
+			known = known || n == name
+		}
+		if !known {
+			return nil, nil, fmt.Errorf("unknown option '%s'", args[i])
+		}
+
+		if i+1 >= len(args) {
+			return nil, nil, fmt.Errorf("missing value for option '%s'", args[i])
+		}
+		opts[name] = args[i+1]
+		i++
+	}
+
+	return positional, opts, nil
+}
+
+// Value of a numeric option, or def if the option wasn't given
+func (o options) float(name string, def float64) (float64, error) {
+	s, ok := o[name]
+	if !ok {
+		return def, nil
+	}
+
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value '%s' for option --%s", s, name)
+	}
+	return value, nil
+}
@@ -0,0 +1,343 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"gocg/cybergear"
+	"gocg/parameters"
+	"gocg/slcan"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Parameter dump file, JSON or YAML depending on the file extension
+type parameterDump struct {
+	MotorId    string            `json:"motor_id" yaml:"motor_id"`
+	MotorName  string            `json:"motor_name,omitempty" yaml:"motor_name,omitempty"`
+	Created    time.Time         `json:"created" yaml:"created"`
+	Parameters []dumpedParameter `json:"parameters" yaml:"parameters"`
+}
+
+type dumpedParameter struct {
+	Index    string   `json:"index" yaml:"index"`
+	Name     string   `json:"name" yaml:"name"`
+	Type     string   `json:"type" yaml:"type"`
+	Writable bool     `json:"writable" yaml:"writable"`
+	Value    *float64 `json:"value,omitempty" yaml:"value,omitempty"` // nil if the motor didn't answer
+}
+
+func isYamlFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+func saveDump(path string, dump *parameterDump) error {
+	var buf []byte
+	var err error
+
+	if isYamlFile(path) {
+		buf, err = yaml.Marshal(dump)
+	} else {
+		buf, err = json.MarshalIndent(dump, "", "  ")
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, buf, 0644)
+}
+
+func loadDump(path string) (*parameterDump, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	dump := &parameterDump{}
+	if isYamlFile(path) {
+		err = yaml.Unmarshal(buf, dump)
+	} else {
+		err = json.Unmarshal(buf, dump)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %s", path, err)
+	}
+
+	return dump, nil
+}
+
+// Parameter from a dump file, checked against the parameter table
+func (d dumpedParameter) info() (cybergear.ParameterInfo, error) {
+	index, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(d.Index), "0x"), 16, 16)
+	if err != nil {
+		return cybergear.ParameterInfo{}, fmt.Errorf("invalid index '%s'", d.Index)
+	}
+
+	p, ok := cybergear.ParameterByIndex(uint16(index))
+	if !ok {
+		return p, fmt.Errorf("unknown parameter 0x%04X", index)
+	}
+
+	if d.Name != "" && !strings.EqualFold(d.Name, p.Name) {
+		return p, fmt.Errorf("parameter 0x%04X is %s, not %s", p.Index, p.Name, d.Name)
+	}
+
+	return p, nil
+}
+
+func readParameter(motorId byte, p cybergear.ParameterInfo) (float64, error) {
+	frame, err := cybergear.ReadParameterCmd(parameters.HostId, motorId, p)
+	if err != nil {
+		return 0, err
+	}
+
+	reply, err := Request(frame, func(f slcan.Frame) bool {
+		pf, ok := f.(*slcan.ParameterFrame)
+		return ok && pf.MotorId() == motorId && pf.Index() == p.Index
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return p.Decode(reply.(*slcan.ParameterFrame).Raw()), nil
+}
+
+// Writes a parameter and reads it back. The reply to a write differs between the run area (a feedback frame)
+// and the config area, so the read back is what tells if the write went through.
+func writeParameter(motorId byte, p cybergear.ParameterInfo, value float64) error {
+	frame, err := cybergear.WriteParameterValueCmd(parameters.HostId, motorId, p, value)
+	if err != nil {
+		return err
+	}
+
+	_, err = Request(frame, func(f slcan.Frame) bool {
+		return f.MotorId() == motorId
+	})
+	if err != nil {
+		return err
+	}
+
+	actual, err := readParameter(motorId, p)
+	if err != nil {
+		return err
+	}
+
+	if !sameValue(p, actual, value) {
+		return fmt.Errorf("%s is %s after writing %s", p.Name, p.Format(actual), p.Format(value))
+	}
+
+	return nil
+}
+
+// Compares the values as they are stored in the motor
+func sameValue(p cybergear.ParameterInfo, a float64, b float64) bool {
+	if p.Type == cybergear.PARAMETER_TYPE_FLOAT {
+		return float32(a) == float32(b)
+	}
+	return a == b
+}
+
+// dump 7F motor.json - reads the run area parameters (0x7005 - 0x7020) into a JSON (or YAML) file. The config area
+// takes the undocumented type 9 and is left out.
+func executeDumpCmd(args []string, outputCh chan string) error {
+	if len(args) != 3 {
+		return fmt.Errorf("syntax error ('dump <motor ID> <file>')' Args: '%+v'", args)
+	}
+
+	motorId, err := parameters.MotorId(args[1])
+	if err != nil {
+		return err
+	}
+
+	dump := &parameterDump{
+		MotorId:   fmt.Sprintf("0x%02X", motorId),
+		MotorName: parameters.MotorName(motorId),
+		Created:   time.Now().UTC().Truncate(time.Second),
+	}
+
+	read := 0
+	for _, p := range cybergear.Parameters() {
+		if p.Config() {
+			continue
+		}
+		entry := dumpedParameter{Index: fmt.Sprintf("0x%04X", p.Index), Name: p.Name, Type: p.Type.String(), Writable: p.Writable}
+
+		value, err := readParameter(motorId, p)
+		if err != nil {
+			outputCh <- fmt.Sprintf("[yellow]0x%04X %-26s : %s[-]", p.Index, p.Name, err)
+		} else {
+			entry.Value = &value
+			read++
+			outputCh <- fmt.Sprintf("0x%04X %-26s = %s", p.Index, p.Name, p.Format(value))
+		}
+
+		dump.Parameters = append(dump.Parameters, entry)
+	}
+
+	if read == 0 {
+		return fmt.Errorf("no parameters could be read from motor %02X, %s not written", motorId, args[2])
+	}
+
+	err = saveDump(args[2], dump)
+	if err != nil {
+		return err
+	}
+
+	outputCh <- fmt.Sprintf("dump %02X %s OK (%d of %d parameters)", motorId, args[2], read, len(dump.Parameters))
+	return nil
+}
+
+// restore 7F motor.json [--dry-run] - writes the writable run area parameters that differ from the file. The values are
+// volatile, the motor starts with its config area again after a power cycle.
+func executeRestoreCmd(args []string, outputCh chan string) error {
+	dryRun := false
+	if len(args) == 4 && args[3] == "--dry-run" {
+		dryRun = true
+		args = args[:3]
+	}
+
+	if len(args) != 3 {
+		return fmt.Errorf("syntax error ('restore <motor ID> <file> [--dry-run]')' Args: '%+v'", args)
+	}
+
+	motorId, err := parameters.MotorId(args[1])
+	if err != nil {
+		return err
+	}
+
+	dump, err := loadDump(args[2])
+	if err != nil {
+		return err
+	}
+
+	if dump.MotorId != fmt.Sprintf("0x%02X", motorId) {
+		outputCh <- fmt.Sprintf("%s was dumped from motor %s", args[2], dump.MotorId)
+	}
+
+	changed, unchanged, failed := 0, 0, 0
+	for _, entry := range dump.Parameters {
+		p, err := entry.info()
+		if err != nil {
+			outputCh <- fmt.Sprintf("[red]%s[-]", err)
+			failed++
+			continue
+		}
+
+		// Setpoints and state are not part of the configuration to restore, and the config area isn't written
+		if !p.Writable || p.Command || p.Config() {
+			continue
+		}
+
+		if entry.Value == nil {
+			continue
+		}
+
+		value := *entry.Value
+		if _, err := p.Encode(value); err != nil {
+			outputCh <- fmt.Sprintf("[red]%s[-]", err)
+			failed++
+			continue
+		}
+
+		current, err := readParameter(motorId, p)
+		if err != nil {
+			outputCh <- fmt.Sprintf("[red]0x%04X %-26s : %s[-]", p.Index, p.Name, err)
+			failed++
+			continue
+		}
+
+		if sameValue(p, current, value) {
+			unchanged++
+			continue
+		}
+
+		outputCh <- fmt.Sprintf("0x%04X %-26s : %s -> %s", p.Index, p.Name, p.Format(current), p.Format(value))
+		changed++
+
+		if dryRun {
+			continue
+		}
+
+		err = writeParameter(motorId, p, value)
+		if err != nil {
+			outputCh <- fmt.Sprintf("[red]0x%04X %-26s : %s[-]", p.Index, p.Name, err)
+			changed--
+			failed++
+		} else {
+			warnVolatile(motorId, p, outputCh)
+		}
+	}
+
+	if dryRun {
+		outputCh <- fmt.Sprintf("restore %02X %s (dry run): %d to change, %d unchanged, %d failed", motorId, args[2], changed, unchanged, failed)
+		return nil
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("restore %02X %s: %d changed, %d unchanged, %d failed", motorId, args[2], changed, unchanged, failed)
+	}
+
+	outputCh <- fmt.Sprintf("restore %02X %s OK: %d changed, %d unchanged", motorId, args[2], changed, unchanged)
+	return nil
+}
+
+// Warns when a value that is lost at power off is written
+func warnVolatile(motorId byte, p cybergear.ParameterInfo, outputCh chan string) {
+	if p.Persistence() != cybergear.VOLATILE || p.Command {
+		return
+	}
+
+	if counterpart, ok := cybergear.PersistentCounterpart(p); ok {
+		outputCh <- fmt.Sprintf("[yellow]%s is volatile and lost at power off, motor %02X starts with %s[-]", p.Name, motorId, counterpart.Name)
+	} else {
+		outputCh <- fmt.Sprintf("[yellow]%s is volatile and lost at power off[-]", p.Name)
+	}
+}
+
+// param 7F CONFIG_WR_SPD_KP [value] - reads or writes a single parameter (name or hex index)
+func executeParamCmd(args []string, outputCh chan string) error {
+	if len(args) != 3 && len(args) != 4 {
+		return fmt.Errorf("syntax error ('param <motor ID> <name | index> [value]')' Args: '%+v'", args)
+	}
+
+	motorId, err := parameters.MotorId(args[1])
+	if err != nil {
+		return err
+	}
+
+	p, err := cybergear.LookupParameter(args[2])
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 4 {
+		if !p.Writable {
+			return fmt.Errorf("%s is read only", p.Name)
+		}
+		value, err := strconv.ParseFloat(args[3], 64)
+		if err != nil {
+			return fmt.Errorf("invalid value '%s'", args[3])
+		}
+		err = writeParameter(motorId, p, value)
+		if err != nil {
+			return err
+		}
+		warnVolatile(motorId, p, outputCh)
+	}
+
+	value, err := readParameterString(motorId, p)
+	if err != nil {
+		return err
+	}
+
+	outputCh <- fmt.Sprintf("0x%04X %s = %s (%s, %s)", p.Index, p.Name, value.text, p.Type, p.Persistence())
+	if !value.inRange {
+		outputCh <- fmt.Sprintf("[red]outside the documented range %s[-]", p.RangeString())
+	}
+	return nil
+}
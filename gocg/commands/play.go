@@ -0,0 +1,223 @@
+package commands
+
+import (
+	"fmt"
+	"gocg/control"
+	"gocg/parameters"
+	"math"
+	"os"
+	"time"
+)
+
+const (
+	PLAY_APPROACH_VMAX = 1.0  // rad/s, move to the first waypoint
+	PLAY_APPROACH_AMAX = 2.0  // rad/s2
+	PATH_SPEED_CHECKS  = 1000 // Points along a path where the speed is checked
+	MAX_SPEED          = 30.0 // rad/s, speed mode range
+)
+
+// Error if the speed along the path, played at the given speed factor, exceeds the limit of the profile or the speed
+// mode range anywhere in the first duration seconds of the path
+func checkPathSpeed(motorId byte, path control.Interpolator, duration float64, speed float64) error {
+	for check := 0; check <= PATH_SPEED_CHECKS; check++ {
+		t := duration * float64(check) / PATH_SPEED_CHECKS
+		_, velocity := path.At(t)
+		err := checkLimits(motorId, controlLoops["speed"], velocity*speed)
+		if err == nil && math.Abs(velocity*speed) > MAX_SPEED {
+			err = fmt.Errorf("speed %.2f rad/s of motor %02X exceeds %g rad/s", velocity*speed, motorId, MAX_SPEED)
+		}
+		if err != nil {
+			return fmt.Errorf("at %.2f s: %s", t, err)
+		}
+	}
+	return nil
+}
+
+// Position and velocity of one joint at t seconds
+type jointPath func(t float64) (float64, float64)
+
+// Drives all joints along their paths for duration seconds at the given rate. Samples are appended to samples[joint].
+func streamJoints(motorIds []byte, setpoints []setpointFunc, paths []jointPath, duration float64, rate float64, samples [][]control.Sample) error {
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / rate))
+	defer ticker.Stop()
+
+	start := time.Now()
+	for t := 0.0; t <= duration; t = time.Since(start).Seconds() {
+		for i, motorId := range motorIds {
+			position, velocity := paths[i](t)
+
+			feedback, err := setpoints[i](position, velocity)
+			if err != nil {
+				return fmt.Errorf("motor %02X at %.2f s: %s", motorId, t, err)
+			}
+			samples[i] = append(samples[i], control.Sample{Time: t, Reference: position, Value: float64(feedback.Angle())})
+		}
+
+		<-ticker.C
+	}
+
+	return nil
+}
+
+// Moves all joints from the given angles to the target angles along trapezoidal profiles that end at the same time
+func approachJoints(motorIds []byte, setpoints []setpointFunc, from []float64, to []float64, rate float64, samples [][]control.Sample) error {
+	paths := make([]jointPath, len(motorIds))
+	duration := 0.0
+	for i := range motorIds {
+		trajectory, err := control.NewTrapezoidal(from[i], to[i], PLAY_APPROACH_VMAX, PLAY_APPROACH_AMAX)
+		if err != nil {
+			return err
+		}
+		duration = math.Max(duration, trajectory.Duration())
+		paths[i] = func(t float64) (float64, float64) {
+			position, velocity, _ := trajectory.Sample(t)
+			return position, velocity
+		}
+	}
+
+	if duration == 0 {
+		return nil
+	}
+	return streamJoints(motorIds, setpoints, paths, duration, rate, samples)
+}
+
+// play arm.csv [--interp linear | cubic] [--loop N] [--speed S] [--rate Hz] [--via loc | mit]
+func executePlayCmd(args []string, outputCh chan string) error {
+	positional, opts, err := parseOptions(args, "interp", "loop", "speed", "rate", "via")
+	if err != nil {
+		return err
+	}
+
+	if len(positional) != 2 {
+		return fmt.Errorf("syntax error ('play <file> [--interp linear | cubic] [--loop N] [--speed S] [--rate Hz] [--via loc | mit]')' Args: '%+v'", args)
+	}
+
+	file, err := os.Open(positional[1])
+	if err != nil {
+		return err
+	}
+	waypoints, err := control.ReadWaypoints(file)
+	file.Close()
+	if err != nil {
+		return fmt.Errorf("%s: %s", positional[1], err)
+	}
+
+	loops, err := opts.float("loop", 1)
+	if err != nil {
+		return err
+	}
+	speed, err := opts.float("speed", 1)
+	if err != nil {
+		return err
+	}
+	rate, err := opts.float("rate", MOVE_DEFAULT_RATE)
+	if err != nil {
+		return err
+	}
+	if loops < 1 || loops != math.Trunc(loops) || speed <= 0 || rate <= 0 {
+		return fmt.Errorf("invalid options: loop %g, speed %g, rate %g Hz", loops, speed, rate)
+	}
+
+	motorIds := make([]byte, len(waypoints.Motors))
+	for i, motor := range waypoints.Motors {
+		motorIds[i], err = parameters.MotorId(motor)
+		if err != nil {
+			return fmt.Errorf("%s: %s", positional[1], err)
+		}
+		err = checkIdle(motorIds[i])
+		if err != nil {
+			return err
+		}
+		for _, other := range motorIds[:i] {
+			if other == motorIds[i] {
+				return fmt.Errorf("%s: motor %02X has more than one column", positional[1], other)
+			}
+		}
+	}
+
+	paths := make([]jointPath, len(motorIds))
+	for i, motorId := range motorIds {
+		var interpolator control.Interpolator
+		switch opts["interp"] {
+		case "", "linear":
+			interpolator, err = control.NewLinear(waypoints.Times, waypoints.Column(i))
+		case "cubic":
+			interpolator, err = control.NewCubicSpline(waypoints.Times, waypoints.Column(i))
+		default:
+			return fmt.Errorf("unknown interpolation '%s' (linear | cubic)", opts["interp"])
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %s", positional[1], err)
+		}
+
+		// Check the speed along the path before anything moves
+		err = checkPathSpeed(motorId, interpolator, waypoints.Times[len(waypoints.Times)-1], speed)
+		if err != nil {
+			return fmt.Errorf("%s %s", positional[1], err)
+		}
+
+		paths[i] = func(t float64) (float64, float64) {
+			position, velocity := interpolator.At(t * speed)
+			return position, velocity * speed
+		}
+	}
+
+	stopAll := func() {
+		for _, motorId := range motorIds {
+			stopMotor(motorId)
+		}
+	}
+
+	setpoints := make([]setpointFunc, len(motorIds))
+	initial := make([]float64, len(motorIds))
+	for i, motorId := range motorIds {
+		switch opts["via"] {
+		case "", "loc":
+			setpoints[i], initial[i], err = locationSetpoints(motorId)
+		case "mit":
+			setpoints[i], initial[i], err = motionControlSetpoints(motorId, MOVE_DEFAULT_KP, MOVE_DEFAULT_KD)
+		default:
+			err = fmt.Errorf("unknown setpoint path '%s' (loc | mit)", opts["via"])
+		}
+		if err != nil {
+			stopAll()
+			return err
+		}
+	}
+
+	first := waypoints.Angles[0]
+	last := waypoints.Angles[len(waypoints.Angles)-1]
+	duration := waypoints.Times[len(waypoints.Times)-1] / speed
+	samples := make([][]control.Sample, len(motorIds))
+
+	outputCh <- fmt.Sprintf("Playing %s: %d motors, %d waypoints, %.2f s x %.0f", positional[1], len(motorIds), len(waypoints.Times), duration, loops)
+
+	err = approachJoints(motorIds, setpoints, initial, first, rate, discardSamples(samples))
+	for loop := 0; err == nil && loop < int(loops); loop++ {
+		if loop > 0 {
+			err = approachJoints(motorIds, setpoints, last, first, rate, discardSamples(samples))
+			if err != nil {
+				break
+			}
+		}
+		err = streamJoints(motorIds, setpoints, paths, duration, rate, samples)
+	}
+
+	if err != nil {
+		stopAll()
+		return fmt.Errorf("play aborted, motors disabled: %s", err)
+	}
+
+	for i, motorId := range motorIds {
+		maxError, rmsError := control.TrackingError(samples[i])
+		outputCh <- fmt.Sprintf("%-16s : tracking error max %.4f rad, rms %.4f rad", motorLabel(motorId), maxError, rmsError)
+	}
+	outputCh <- fmt.Sprintf("play %s OK. The motors hold the last waypoint", positional[1])
+
+	return nil
+}
+
+// Fresh sample buffers, for moves that don't count towards the tracking error
+func discardSamples(samples [][]control.Sample) [][]control.Sample {
+	return make([][]control.Sample, len(samples))
+}
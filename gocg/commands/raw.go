@@ -0,0 +1,109 @@
+package commands
+
+import (
+	"encoding/hex"
+	"fmt"
+	"gocg/cybergear"
+	"gocg/parameters"
+	"gocg/slcan"
+	"strconv"
+	"strings"
+)
+
+// raw T0F00007F0 - sends the line as is (after checking that it is a well formed frame)
+func executeRawCmd(args []string, outputCh chan string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("syntax error ('raw T<id><dlc><data>')' Args: '%+v'", args)
+	}
+
+	line := []byte(args[1])
+
+	info, err := slcan.Inspect(line)
+	if err != nil {
+		return err
+	}
+	if len(line) != frameLength(info) {
+		return fmt.Errorf("frame length doesn't match DLC %d: '%s'", len(info.Data), line)
+	}
+
+	outputCh <- fmt.Sprintf("TX : %s", info.Describe(parameters.HostId))
+
+	err = SendLine(line, outputCh)
+	if err != nil {
+		return err
+	}
+
+	outputCh <- fmt.Sprintf("raw %s OK", line)
+	return nil
+}
+
+// Length of the frame line without CR
+func frameLength(info *slcan.FrameInfo) int {
+	if info.Extended() {
+		return 1 + 8 + 1 + 2*len(info.Data)
+	}
+	return 1 + 3 + 1 + 2*len(info.Data)
+}
+
+// cg 15 00 7F 0000 - communication type (decimal), host id (or - for none), motor id and data area (hex), optional payload (hex)
+func executeCgCmd(args []string, outputCh chan string) error {
+	if len(args) != 5 && len(args) != 6 {
+		return fmt.Errorf("syntax error ('cg <type> <host id>|- <motor id> <data16> [payload hex]')' Args: '%+v'", args)
+	}
+
+	communicationType, err := strconv.ParseUint(args[1], 10, 5)
+	if err != nil {
+		return fmt.Errorf("invalid communication type '%s' (0 - 31)", args[1])
+	}
+
+	motorId, err := parameters.MotorId(args[3])
+	if err != nil {
+		return err
+	}
+
+	data, err := strconv.ParseUint(args[4], 16, 16)
+	if err != nil {
+		return fmt.Errorf("invalid data area '%s' (0000 - FFFF)", args[4])
+	}
+
+	// The host id goes into bit 8 - 15 of the frame id, the low byte of the data area
+	if args[2] != "-" {
+		hostId, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(args[2]), "0x"), 16, 8)
+		if err != nil {
+			return fmt.Errorf("invalid host id '%s'", args[2])
+		}
+		if low := data & 0xFF; low != 0 && low != hostId {
+			return fmt.Errorf("host id %02X would overwrite %02X in the data area, use '-' as host id to send it as given", hostId, low)
+		}
+		data = data&0xFF00 | hostId
+	}
+
+	var payload []byte
+	if len(args) == 6 {
+		payload, err = hex.DecodeString(strings.TrimPrefix(args[5], "0x"))
+		if err != nil {
+			return fmt.Errorf("invalid payload '%s'", args[5])
+		}
+	}
+
+	frame, err := cybergear.CustomFrameCmd(cybergear.CommunicationType(communicationType), motorId, uint16(data), payload)
+	if err != nil {
+		return err
+	}
+
+	line := slcan.Encode(frame)
+
+	info, err := slcan.Inspect(line)
+	if err != nil {
+		return err
+	}
+	outputCh <- fmt.Sprintf("TX : %s (%s)", info.Describe(parameters.HostId), line)
+
+	err = SendFrame(frame, outputCh)
+	if err != nil {
+		return err
+	}
+
+	outputCh <- "cg OK"
+	return nil
+}
@@ -0,0 +1,369 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gocg/parameters"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	SERVE_DEFAULT_ADDRESS = "127.0.0.1:8080" // Local only, the API drives the motors without authentication
+	EVENT_BUFFER          = 256              // Events per WebSocket client before it starts losing them
+)
+
+// REST and WebSocket API on top of the commands
+type apiServer struct {
+	server *http.Server
+	stopCh chan struct{} // Ends the WebSocket streams, which Shutdown leaves alone
+}
+
+var activeServer *apiServer
+
+// Registered here, as the API runs commands through dispatchMap itself
+func init() {
+	dispatchMap["serve"] = executeServeCmd
+}
+
+// Pages from other origins can't open the event stream, clients without an Origin header (not browsers) can
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		u, err := url.Parse(origin)
+		return err == nil && strings.EqualFold(u.Host, r.Host)
+	},
+}
+
+// serve [address] | serve stop
+func executeServeCmd(args []string, outputCh chan string) error {
+	switch {
+	case len(args) == 2 && args[1] == "stop":
+		if activeServer == nil {
+			return fmt.Errorf("not serving")
+		}
+		stopServer()
+		outputCh <- "serve stop OK"
+		return nil
+	case len(args) > 2:
+		return fmt.Errorf("syntax error ('serve [address]' or 'serve stop')' Args: '%+v'", args)
+	}
+
+	if activeServer != nil {
+		return fmt.Errorf("already serving ('serve stop' first)")
+	}
+
+	address := SERVE_DEFAULT_ADDRESS
+	if len(args) == 2 {
+		address = args[1]
+	}
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+
+	stopCh := make(chan struct{})
+	activeServer = &apiServer{server: &http.Server{Handler: apiHandler(stopCh)}, stopCh: stopCh}
+	go func(server *http.Server) {
+		err := server.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			outputCh <- fmt.Sprintf("[red]serve: %s[-]", err)
+		}
+	}(activeServer.server)
+
+	outputCh <- fmt.Sprintf("Serving the API on http://%s/api/, events on ws://%s/api/events", listener.Addr(), listener.Addr())
+	return nil
+}
+
+func stopServer() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	close(activeServer.stopCh)
+	activeServer.server.Shutdown(ctx)
+	activeServer = nil
+}
+
+// An error with the HTTP status to answer with
+type apiError struct {
+	status int
+	err    error
+}
+
+func (e *apiError) Error() string {
+	return e.err.Error()
+}
+
+func badRequest(format string, args ...interface{}) error {
+	return &apiError{status: http.StatusBadRequest, err: fmt.Errorf(format, args...)}
+}
+
+func apiHandler(stopCh chan struct{}) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/motors", handleMotors)
+	mux.HandleFunc("/api/motors/", handleMotor)
+	mux.HandleFunc("/api/command", handleCommand)
+	mux.HandleFunc("/api/events", func(w http.ResponseWriter, r *http.Request) {
+		handleEvents(w, r, stopCh)
+	})
+	return requireJson(mux)
+}
+
+// POST and PUT only take JSON, so a page from another origin can't send them as a plain form
+func requireJson(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost || r.Method == http.MethodPut {
+			mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if err != nil || mediaType != "application/json" {
+				writeJson(w, nil, &apiError{status: http.StatusUnsupportedMediaType, err: fmt.Errorf("%s %s needs Content-Type application/json", r.Method, r.URL.Path)})
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeJson(w http.ResponseWriter, result interface{}, err error) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err != nil {
+		status := http.StatusInternalServerError
+		var e *apiError
+		if errors.As(err, &e) {
+			status = e.status
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	json.NewEncoder(w).Encode(result)
+}
+
+func readJson(r *http.Request, body interface{}) error {
+	err := json.NewDecoder(r.Body).Decode(body)
+	if err != nil {
+		return badRequest("invalid request body: %s", err)
+	}
+	return nil
+}
+
+func checkMethod(r *http.Request, method string) error {
+	if r.Method != method {
+		return &apiError{status: http.StatusMethodNotAllowed, err: fmt.Errorf("%s %s not allowed, use %s", r.Method, r.URL.Path, method)}
+	}
+	return nil
+}
+
+// GET /api/motors - the motors of the active profile, with their last feedback if there was any
+func handleMotors(w http.ResponseWriter, r *http.Request) {
+	err := checkMethod(r, http.MethodGet)
+	if err != nil {
+		writeJson(w, nil, err)
+		return
+	}
+
+	motors := []MotorStatus{}
+	for _, motorId := range pollMotorIds() {
+		status, err := cachedStatus(motorId)
+		if err != nil {
+			status = MotorStatus{MotorId: fmt.Sprintf("%02X", motorId), Name: parameters.MotorName(motorId)}
+		}
+		motors = append(motors, status)
+	}
+	writeJson(w, motors, nil)
+}
+
+// /api/motors/<id>[/<operation>[/<parameter>]]
+func handleMotor(w http.ResponseWriter, r *http.Request) {
+	path := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/motors/"), "/"), "/")
+
+	motorId, err := parameters.MotorId(path[0])
+	if err != nil {
+		writeJson(w, nil, badRequest("%s", err))
+		return
+	}
+
+	var result interface{}
+	switch {
+	case len(path) == 1:
+		if err = checkMethod(r, http.MethodGet); err == nil {
+			result, err = GetStatus(motorId)
+		}
+
+	case len(path) == 2 && (path[1] == "enable" || path[1] == "disable"):
+		if err = checkMethod(r, http.MethodPost); err == nil {
+			if path[1] == "enable" {
+				result, err = EnableMotor(motorId)
+			} else {
+				result, err = DisableMotor(motorId)
+			}
+		}
+
+	case len(path) == 2 && path[1] == "mode":
+		var body struct {
+			Mode string `json:"mode"`
+		}
+		if err = checkMethod(r, http.MethodPost); err == nil {
+			if err = readJson(r, &body); err == nil {
+				result, err = SetMode(motorId, body.Mode)
+			}
+		}
+
+	case len(path) == 2 && (path[1] == "speed" || path[1] == "position" || path[1] == "current"):
+		var body struct {
+			Value *float64 `json:"value"`
+		}
+		if err = checkMethod(r, http.MethodPost); err == nil {
+			if err = readJson(r, &body); err == nil && body.Value == nil {
+				err = badRequest("missing value")
+			}
+			if err == nil {
+				result, err = SetSetpoint(motorId, path[1], *body.Value)
+			}
+		}
+
+	case len(path) == 3 && path[1] == "params":
+		switch r.Method {
+		case http.MethodGet:
+			result, err = ReadParam(motorId, path[2])
+		case http.MethodPut:
+			var body struct {
+				Value *float64 `json:"value"`
+			}
+			if err = readJson(r, &body); err == nil {
+				if body.Value == nil {
+					err = badRequest("missing value")
+				} else {
+					result, err = WriteParam(motorId, path[2], *body.Value)
+				}
+			}
+		default:
+			err = checkMethod(r, http.MethodGet)
+		}
+
+	default:
+		err = &apiError{status: http.StatusNotFound, err: fmt.Errorf("unknown path %s", r.URL.Path)}
+	}
+
+	writeJson(w, result, err)
+}
+
+// Console commands /api/command runs: the ones driving the motors. Nothing that reads or writes files, opens or
+// closes the adapter or starts servers and bridges.
+var apiCommands = map[string]bool{
+	"help":        true,
+	"enable":      true,
+	"disable":     true,
+	"set_speed":   true,
+	"set_current": true,
+	"get_status":  true,
+	"info":        true,
+	"param":       true,
+	"autotune":    true,
+	"move":        true,
+	"impedance":   true,
+	"knob":        true,
+}
+
+func checkApiCommand(command string) error {
+	fields := strings.Fields(command)
+	if len(fields) == 0 || !apiCommands[fields[0]] {
+		return &apiError{status: http.StatusForbidden, err: fmt.Errorf("'%s' can't be run through the API", command)}
+	}
+	return nil
+}
+
+// POST /api/command {"command": "param 7F PARAMETER_LIMIT_SPD"} - runs a console command driving the motors
+func handleCommand(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Command string `json:"command"`
+	}
+
+	err := checkMethod(r, http.MethodPost)
+	if err == nil {
+		err = readJson(r, &body)
+	}
+	if err == nil {
+		err = checkApiCommand(body.Command)
+	}
+	if err != nil {
+		writeJson(w, nil, err)
+		return
+	}
+
+	output, err := Run(body.Command)
+	if output == nil {
+		output = []string{}
+	}
+	result := map[string]interface{}{"output": output}
+	if err != nil {
+		result["error"] = err.Error()
+	}
+	writeJson(w, result, nil)
+}
+
+// GET /api/events[?motor=<id>][&kind=<kind>] - WebSocket stream of events as JSON text messages
+func handleEvents(w http.ResponseWriter, r *http.Request, stopCh chan struct{}) {
+	motorFilter := ""
+	if motor := r.URL.Query().Get("motor"); motor != "" {
+		motorId, err := parameters.MotorId(motor)
+		if err != nil {
+			writeJson(w, nil, badRequest("%s", err))
+			return
+		}
+		motorFilter = fmt.Sprintf("%02X", motorId)
+	}
+	kindFilter := r.URL.Query().Get("kind")
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	eventCh, unsubscribe := SubscribeEvents(EVENT_BUFFER)
+	defer unsubscribe()
+
+	// Notices when the client goes away
+	closedCh := make(chan struct{})
+	go func() {
+		defer close(closedCh)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-closedCh:
+			return
+		case <-stopCh:
+			return
+		case e, ok := <-eventCh:
+			if !ok {
+				return
+			}
+			if (motorFilter != "" && e.MotorId != motorFilter) || (kindFilter != "" && e.Kind != kindFilter) {
+				continue
+			}
+			if conn.WriteJSON(e) != nil {
+				return
+			}
+		}
+	}
+}
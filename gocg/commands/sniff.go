@@ -0,0 +1,262 @@
+package commands
+
+import (
+	"fmt"
+	"gocg/cybergear"
+	"gocg/metrics"
+	"gocg/parameters"
+	"gocg/slcan"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Listen-only bus monitor. While the sniffer runs, it owns the adapter.
+type sniffer struct {
+	stopCh     chan struct{}
+	doneCh     chan struct{}
+	ownsPort   bool // The sniffer opened the serial port and closes it when stopped
+	motorIds   map[byte]bool
+	commTypes  map[cybergear.CommunicationType]bool
+	mutex      sync.Mutex
+	started    time.Time
+	counters   map[cybergear.CommunicationType]int
+	standard   int // Standard (11 bit) frames
+	filtered   int // Frames hidden by the filters
+	errors     int // Lines that couldn't be decoded
+	totalCount int
+}
+
+var activeSniffer *sniffer
+
+func executeSniffCmd(args []string, outputCh chan string) error {
+	if len(args) >= 2 {
+		switch args[1] {
+		case "stop":
+			if activeSniffer == nil {
+				return fmt.Errorf("sniffer not running")
+			}
+			stopSniffer(outputCh)
+			outputCh <- "sniff stop OK"
+			return nil
+		case "stats":
+			if activeSniffer == nil {
+				return fmt.Errorf("sniffer not running")
+			}
+			activeSniffer.printStats(outputCh)
+			return nil
+		}
+	}
+
+	if activeSniffer != nil {
+		return fmt.Errorf("sniffer already running ('sniff stop' first)")
+	}
+
+	// Nothing could disable the motors while the adapter is listening only
+	err := checkNoBackgroundTasks()
+	if err != nil {
+		return err
+	}
+
+	s := &sniffer{
+		stopCh:    make(chan struct{}),
+		doneCh:    make(chan struct{}),
+		motorIds:  map[byte]bool{},
+		commTypes: map[cybergear.CommunicationType]bool{},
+		counters:  map[cybergear.CommunicationType]int{},
+	}
+
+	portName := ""
+	for i := 1; i < len(args); i++ {
+		switch args[i] {
+		case "--motor", "--type":
+			if i+1 >= len(args) {
+				return fmt.Errorf("syntax error: %s needs a value", args[i])
+			}
+			if args[i] == "--motor" {
+				motorId, err := parameters.MotorId(args[i+1])
+				if err != nil {
+					return err
+				}
+				s.motorIds[motorId] = true
+			} else {
+				commType, err := strconv.ParseUint(args[i+1], 10, 5)
+				if err != nil {
+					return fmt.Errorf("invalid communication type '%s' (0 - 31)", args[i+1])
+				}
+				s.commTypes[cybergear.CommunicationType(commType)] = true
+			}
+			i++
+		default:
+			if portName != "" {
+				return fmt.Errorf("syntax error ('sniff [serial port name] [--motor <id>]... [--type <n>]...')' Args: '%+v'", args)
+			}
+			portName = args[i]
+		}
+	}
+
+	if nil == adapter {
+		if portName == "" {
+			_, profile := parameters.ActiveProfile()
+			portName = profile.Adapter
+		}
+		if portName == "" {
+			return fmt.Errorf("no adapter in the active profile ('sniff <serial port name>')")
+		}
+		err := openAdapter(portName, outputCh)
+		if err != nil {
+			return err
+		}
+		s.ownsPort = true
+	}
+
+	outputCh <- "Opening CAN Channel in listen-only mode"
+	busMutex.Lock()
+	if !s.ownsPort {
+		// Leave normal mode so that we don't acknowledge anything on the bus
+		adapter.CloseChannel()
+	}
+	err = adapter.OpenListenOnly()
+	busMutex.Unlock()
+	if err != nil {
+		if s.ownsPort {
+			closeAdapter()
+		}
+		return err
+	}
+
+	s.started = time.Now()
+	activeSniffer = s
+	go s.run(outputCh)
+
+	outputCh <- "Sniffing. Type 'sniff stop' to stop, 'sniff stats' for frame counters."
+	return nil
+}
+
+func (s *sniffer) run(outputCh chan string) {
+	defer close(s.doneCh)
+
+	_, profile := parameters.ActiveProfile()
+	polled := time.Now()
+
+	for {
+		select {
+		case <-s.stopCh:
+			return
+		default:
+		}
+
+		// The sniffer owns the adapter, so the status flags for the bus monitor are read here
+		if time.Since(polled) >= STATUS_POLL_INTERVAL {
+			pollStatusFlags()
+			polled = time.Now()
+		}
+
+		line, err := readFrameLine(profile.ReadTimeout)
+		if err == slcan.ErrNoReply {
+			continue
+		}
+		if err != nil {
+			outputCh <- fmt.Sprintf("sniff: %s", err)
+			return
+		}
+
+		s.handle(line, outputCh)
+	}
+}
+
+func (s *sniffer) handle(line []byte, outputCh chan string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.totalCount++
+	timestamp := time.Now().Format("15:04:05.000")
+
+	info, err := slcan.Inspect(line)
+	if err != nil {
+		metrics.Bus.DecodeFailed()
+		s.errors++
+		outputCh <- fmt.Sprintf("%s [red]%s[-]", timestamp, err)
+		return
+	}
+
+	if !info.Extended() {
+		s.standard++
+		if len(s.motorIds) > 0 || len(s.commTypes) > 0 {
+			s.filtered++
+			return
+		}
+		outputCh <- fmt.Sprintf("%s %s", timestamp, info.Describe(parameters.HostId))
+		return
+	}
+
+	if info.Decoded != nil && info.FromMotor(parameters.HostId) {
+		observeFrame(info.Decoded)
+	}
+
+	commType := info.CommunicationType()
+	s.counters[commType]++
+
+	if len(s.motorIds) > 0 && !s.motorIds[info.MotorId(parameters.HostId)] {
+		s.filtered++
+		return
+	}
+	if len(s.commTypes) > 0 && !s.commTypes[commType] {
+		s.filtered++
+		return
+	}
+
+	outputCh <- fmt.Sprintf("%s %02d %s", timestamp, int(commType), info.Describe(parameters.HostId))
+}
+
+func (s *sniffer) printStats(outputCh chan string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	elapsed := time.Since(s.started).Seconds()
+
+	types := make([]int, 0, len(s.counters))
+	for commType := range s.counters {
+		types = append(types, int(commType))
+	}
+	sort.Ints(types)
+
+	outputCh <- fmt.Sprintf("Frames received : %d in %.1f s (%.1f frames/s)", s.totalCount, elapsed, float64(s.totalCount)/elapsed)
+	for _, commType := range types {
+		count := s.counters[cybergear.CommunicationType(commType)]
+		outputCh <- fmt.Sprintf("\t%02d %-14s : %d", commType, cybergear.CommunicationType(commType), count)
+	}
+	outputCh <- fmt.Sprintf("\tstandard frames   : %d", s.standard)
+	outputCh <- fmt.Sprintf("\tdecode errors     : %d", s.errors)
+	outputCh <- fmt.Sprintf("\thidden by filters : %d", s.filtered)
+}
+
+func stopSniffer(outputCh chan string) {
+	s := activeSniffer
+
+	close(s.stopCh)
+	<-s.doneCh
+	activeSniffer = nil
+
+	s.printStats(outputCh)
+
+	outputCh <- "Closing CAN Channel"
+	busMutex.Lock()
+	adapter.CloseChannel()
+	busMutex.Unlock()
+
+	if s.ownsPort {
+		outputCh <- "Closing serial port"
+		closeAdapter()
+		return
+	}
+
+	outputCh <- "Opening CAN Channel in normal mode (send/recevie)"
+	busMutex.Lock()
+	err := adapter.Open()
+	busMutex.Unlock()
+	if err != nil {
+		outputCh <- err.Error()
+	}
+}
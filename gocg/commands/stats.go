@@ -0,0 +1,111 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"gocg/metrics"
+	"gocg/parameters"
+	"gocg/slcan"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const STATS_BAR_WIDTH = 40
+
+// stats | stats reset | stats export <file.json | file.yaml>
+func executeStatsCmd(args []string, outputCh chan string) error {
+	switch {
+	case len(args) == 1:
+		showStats(metrics.Bus.Snapshot(), outputCh)
+		return nil
+	case len(args) == 2 && args[1] == "reset":
+		metrics.Bus.Reset()
+		outputCh <- "stats reset OK"
+		return nil
+	case len(args) == 3 && args[1] == "export":
+		return exportStats(args[2], metrics.Bus.Snapshot(), outputCh)
+	}
+	return fmt.Errorf("syntax error ('stats [reset | export <file>]')' Args: '%+v'", args)
+}
+
+func showStats(s metrics.TransportSnapshot, outputCh chan string) {
+	outputCh <- fmt.Sprintf("Since %.1f s: %d frames sent (%.1f/s), %d received (%.1f/s)", s.Elapsed, s.FramesSent, s.SentPerSecond,
+		s.FramesReceived, s.ReceivedPerSecond)
+
+	counters := fmt.Sprintf("%d requests, %d unanswered, %d frames dropped, %d serial overruns", s.Requests, s.Unanswered, s.Dropped, s.Overruns)
+	if s.Unanswered > 0 || s.Overruns > 0 {
+		counters = "[yellow]" + counters + "[-]"
+	}
+	outputCh <- counters
+
+	flags := "unknown"
+	if f, ok := metrics.Bus.StatusFlags(); ok {
+		flags = slcan.StatusFlags(f).String()
+	}
+	_, profile := parameters.ActiveProfile()
+	load := 0.0
+	if s.Elapsed > 0 && profile.Bitrate > 0 {
+		load = float64(s.Bits) / s.Elapsed / float64(profile.Bitrate)
+	}
+	outputCh <- fmt.Sprintf("Bus load %.1f%% of %d bit/s (%.1f%% last second), %d decode failures, adapter status %s", 100*load, profile.Bitrate,
+		100*monitor.load, s.DecodeFailures, flags)
+
+	showHistogram("Latency (request to reply)", s.Latency, outputCh)
+	showHistogram("Request period", s.Period, outputCh)
+}
+
+func showHistogram(title string, h metrics.HistogramSnapshot, outputCh chan string) {
+	if h.Count == 0 {
+		outputCh <- fmt.Sprintf("%s: no samples", title)
+		return
+	}
+
+	outputCh <- fmt.Sprintf("%s: %d samples, min %s, mean %s, max %s, jitter (std dev) %s, p50 %s, p99 %s", title, h.Count,
+		seconds(h.Min), seconds(h.Mean), seconds(h.Max), seconds(h.StdDev), seconds(h.Quantile(0.5)), seconds(h.Quantile(0.99)))
+
+	max := h.Overflow
+	for _, b := range h.Buckets {
+		if b.Count > max {
+			max = b.Count
+		}
+	}
+
+	bar := func(label string, count uint64) {
+		if count > 0 {
+			outputCh <- fmt.Sprintf("\t%8s %s %d", label, strings.Repeat("#", int(1+(STATS_BAR_WIDTH-1)*count/max)), count)
+		}
+	}
+	for _, b := range h.Buckets {
+		bar("<= "+seconds(b.UpperBound), b.Count)
+	}
+	bar("more", h.Overflow)
+}
+
+func seconds(s float64) string {
+	return time.Duration(s * float64(time.Second)).Round(time.Microsecond).String()
+}
+
+func exportStats(path string, s metrics.TransportSnapshot, outputCh chan string) error {
+	var buf []byte
+	var err error
+
+	if isYamlFile(path) {
+		buf, err = yaml.Marshal(s)
+	} else {
+		buf, err = json.MarshalIndent(s, "", "  ")
+	}
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(path, buf, 0644)
+	if err != nil {
+		return err
+	}
+
+	outputCh <- fmt.Sprintf("stats export OK -> %s", path)
+	return nil
+}
@@ -0,0 +1,265 @@
+package commands
+
+import (
+	"fmt"
+	"gocg/control"
+	"gocg/parameters"
+	"gocg/slcan"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	TEACH_DEFAULT_RATE = 20.0 // Hz
+	TEACH_DEFAULT_KD   = 0.1  // Damping in motion control mode
+	TEACH_KEYFRAME_KEY = "."  // Typed alone on a line, marks a keyframe
+)
+
+// Records the angles of hand guided motors in the background
+type teachSession struct {
+	stopCh    chan struct{}
+	doneCh    chan struct{}
+	motorIds  []byte
+	file      string
+	rate      float64
+	hold      []setpointFunc // Keeps each motor compliant around the given angle, returns its feedback
+	angles    []float64
+	mutex     sync.Mutex
+	waypoints *control.Waypoints
+	mark      bool // Next sample is a keyframe
+	err       error
+}
+
+var activeTeach *teachSession
+
+// teach 7F 01 [--file arm.csv] [--rate Hz] [--mode disabled | mit] [--kp kp] [--kd kd]
+// teach mark | teach stop
+func executeTeachCmd(args []string, outputCh chan string) error {
+	if len(args) == 2 && (args[1] == "stop" || args[1] == "mark") {
+		if activeTeach == nil {
+			return fmt.Errorf("not teaching")
+		}
+		if args[1] == "mark" {
+			return markKeyframe(outputCh)
+		}
+		return stopTeach(outputCh)
+	}
+
+	if activeTeach != nil {
+		return fmt.Errorf("already teaching ('teach stop' first)")
+	}
+
+	positional, opts, err := parseOptions(args, "file", "rate", "mode", "kp", "kd")
+	if err != nil {
+		return err
+	}
+	if len(positional) < 2 {
+		return fmt.Errorf("syntax error ('teach <motor ID>... [--file f] [--rate Hz] [--mode disabled | mit] [--kp kp] [--kd kd]', 'teach mark' or 'teach stop')' Args: '%+v'", args)
+	}
+
+	s := &teachSession{
+		stopCh:    make(chan struct{}),
+		doneCh:    make(chan struct{}),
+		file:      opts["file"],
+		waypoints: &control.Waypoints{},
+	}
+	if s.file == "" {
+		s.file = fmt.Sprintf("teach-%s.csv", time.Now().Format("20060102-150405"))
+	}
+
+	s.rate, err = opts.float("rate", TEACH_DEFAULT_RATE)
+	if err != nil {
+		return err
+	}
+	kp, err := opts.float("kp", 0)
+	if err != nil {
+		return err
+	}
+	kd, err := opts.float("kd", TEACH_DEFAULT_KD)
+	if err != nil {
+		return err
+	}
+	if s.rate <= 0 {
+		return fmt.Errorf("invalid rate %g Hz", s.rate)
+	}
+
+	for _, arg := range positional[1:] {
+		motorId, err := parameters.MotorId(arg)
+		if err != nil {
+			return err
+		}
+		err = checkIdle(motorId)
+		if err != nil {
+			return err
+		}
+		s.motorIds = append(s.motorIds, motorId)
+
+		// Waypoint columns by name where the profile has one, so that the file plays back with another profile too
+		column := parameters.MotorName(motorId)
+		if column == "" {
+			column = fmt.Sprintf("%02X", motorId)
+		}
+		s.waypoints.Motors = append(s.waypoints.Motors, column)
+	}
+
+	mode := opts["mode"]
+	if mode != "" && mode != "disabled" && mode != "mit" {
+		return fmt.Errorf("unknown mode '%s' (disabled | mit)", mode)
+	}
+
+	for _, motorId := range s.motorIds {
+		var hold setpointFunc
+		var angle float64
+
+		if mode == "mit" {
+			// Low gain motion control around the last measured angle
+			hold, angle, err = motionControlSetpoints(motorId, kp, kd)
+		} else {
+			// Stop frames are answered with feedback, so polling with them keeps the motor disabled
+			var feedback *slcan.MotorFeedback
+			feedback, err = stopMotor(motorId)
+			if err == nil {
+				angle = float64(feedback.Angle())
+			}
+			id := motorId
+			hold = func(position float64, velocity float64) (*slcan.MotorFeedback, error) {
+				return stopMotor(id)
+			}
+		}
+		if err != nil {
+			for _, motorId := range s.motorIds {
+				stopMotor(motorId)
+			}
+			return err
+		}
+
+		s.hold = append(s.hold, hold)
+		s.angles = append(s.angles, angle)
+	}
+
+	activeTeach = s
+	for _, motorId := range s.motorIds {
+		backgroundLoops[motorId] = &backgroundLoop{
+			name:        "teach",
+			stopCommand: "teach stop",
+			shared:      true,
+			stopCh:      s.stopCh,
+			doneCh:      s.doneCh,
+			started:     time.Now(),
+		}
+	}
+	go s.run(outputCh)
+
+	outputCh <- fmt.Sprintf("Teaching %d motors at %.0f Hz. Move the arm by hand, '%s' or 'teach mark' marks a keyframe, 'teach stop' saves %s",
+		len(s.motorIds), s.rate, TEACH_KEYFRAME_KEY, s.file)
+	return nil
+}
+
+func (s *teachSession) run(outputCh chan string) {
+	defer close(s.doneCh)
+
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / s.rate))
+	defer ticker.Stop()
+
+	start := time.Now()
+
+	for {
+		t := time.Since(start).Seconds()
+		for i, motorId := range s.motorIds {
+			feedback, err := s.hold[i](s.angles[i], 0)
+			if err != nil {
+				s.mutex.Lock()
+				s.err = fmt.Errorf("motor %02X: %s", motorId, err)
+				s.mutex.Unlock()
+				outputCh <- fmt.Sprintf("[red]teach stopped: %s. 'teach stop' saves what was recorded[-]", s.err)
+				return
+			}
+			s.angles[i] = float64(feedback.Angle())
+		}
+
+		s.mutex.Lock()
+		s.waypoints.Times = append(s.waypoints.Times, t)
+		s.waypoints.Angles = append(s.waypoints.Angles, append([]float64{}, s.angles...))
+		s.waypoints.Keyframes = append(s.waypoints.Keyframes, s.mark)
+		s.mark = false
+		s.mutex.Unlock()
+
+		select {
+		case <-s.stopCh:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func markKeyframe(outputCh chan string) error {
+	if activeTeach == nil {
+		return fmt.Errorf("not teaching")
+	}
+
+	// The recorder stops on its own when a motor doesn't answer
+	select {
+	case <-activeTeach.doneCh:
+		return fmt.Errorf("teach stopped: %s ('teach stop' saves what was recorded)", activeTeach.err)
+	default:
+	}
+
+	activeTeach.mutex.Lock()
+	activeTeach.mark = true
+	keyframes := 1
+	for _, keyframe := range activeTeach.waypoints.Keyframes {
+		if keyframe {
+			keyframes++
+		}
+	}
+	activeTeach.mutex.Unlock()
+
+	outputCh <- fmt.Sprintf("Keyframe %d", keyframes)
+	return nil
+}
+
+// Stops recording, disables the motors and saves the waypoints
+func stopTeach(outputCh chan string) error {
+	s := activeTeach
+	close(s.stopCh)
+	<-s.doneCh
+	activeTeach = nil
+
+	for _, motorId := range s.motorIds {
+		if l, ok := backgroundLoops[motorId]; ok && l.doneCh == s.doneCh {
+			delete(backgroundLoops, motorId)
+		}
+	}
+
+	for _, motorId := range s.motorIds {
+		stopMotor(motorId)
+	}
+
+	if len(s.waypoints.Times) < 2 {
+		return fmt.Errorf("nothing recorded, %s not written", s.file)
+	}
+
+	file, err := os.Create(s.file)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	err = s.waypoints.Write(file)
+	if err != nil {
+		return err
+	}
+
+	outputCh <- fmt.Sprintf("teach stop OK: %d waypoints (%.1f s) -> %s. 'play %s' plays them back",
+		len(s.waypoints.Times), s.waypoints.Times[len(s.waypoints.Times)-1], s.file, s.file)
+	return nil
+}
+
+// . - shortcut for 'teach mark'
+func executeKeyframeCmd(args []string, outputCh chan string) error {
+	if len(args) != 1 || args[0] != TEACH_KEYFRAME_KEY {
+		return fmt.Errorf("syntax error ('%s')' Args: '%+v'", TEACH_KEYFRAME_KEY, args)
+	}
+	return markKeyframe(outputCh)
+}
@@ -0,0 +1,94 @@
+package control
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+)
+
+// Linear chirp from f0 to f1 Hz over duration seconds, amplitude 1
+func Chirp(t float64, f0 float64, f1 float64, duration float64) float64 {
+	k := (f1 - f0) / duration
+	return math.Sin(2 * math.Pi * (f0*t + k*t*t/2))
+}
+
+// n logarithmically spaced frequencies from f0 to f1
+func LogFrequencies(f0 float64, f1 float64, n int) []float64 {
+	if n < 2 {
+		return []float64{f0}
+	}
+
+	frequencies := make([]float64, n)
+	for i := range frequencies {
+		frequencies[i] = f0 * math.Pow(f1/f0, float64(i)/float64(n-1))
+	}
+	return frequencies
+}
+
+type FrequencyPoint struct {
+	Frequency float64 // Hz
+	Magnitude float64 // dB
+	Phase     float64 // Degrees
+}
+
+// Fourier transform of a signal at frequency f, the samples don't have to be equally spaced
+func fourier(samples []Sample, f float64, value func(Sample) float64, offset float64) complex128 {
+	var sum complex128
+	for i := 1; i < len(samples); i++ {
+		dt := samples[i].Time - samples[i-1].Time
+		x := (value(samples[i])+value(samples[i-1]))/2 - offset
+		t := (samples[i].Time + samples[i-1].Time) / 2
+		sum += complex(x*dt, 0) * cmplx.Exp(complex(0, -2*math.Pi*f*t))
+	}
+	return sum
+}
+
+// Estimates the frequency response from reference to value as the ratio of their Fourier transforms (empirical
+// transfer function estimate). Needs a reference with energy at the frequencies, e.g. a chirp.
+func EstimateFrequencyResponse(samples []Sample, frequencies []float64) ([]FrequencyPoint, error) {
+	if len(samples) < 2 {
+		return nil, fmt.Errorf("too few samples (%d)", len(samples))
+	}
+
+	reference := func(s Sample) float64 { return s.Reference }
+	value := func(s Sample) float64 { return s.Value }
+
+	referenceMean, valueMean := 0.0, 0.0
+	for _, s := range samples {
+		referenceMean += s.Reference
+		valueMean += s.Value
+	}
+	referenceMean /= float64(len(samples))
+	valueMean /= float64(len(samples))
+
+	var points []FrequencyPoint
+	for _, f := range frequencies {
+		x := fourier(samples, f, reference, referenceMean)
+		if cmplx.Abs(x) < 1e-12 {
+			continue
+		}
+		h := fourier(samples, f, value, valueMean) / x
+
+		points = append(points, FrequencyPoint{
+			Frequency: f,
+			Magnitude: 20 * math.Log10(cmplx.Abs(h)),
+			Phase:     cmplx.Phase(h) * 180 / math.Pi,
+		})
+	}
+
+	return points, nil
+}
+
+// First frequency where the magnitude is 3 dB below the magnitude at the lowest frequency, NaN if it never is
+func Bandwidth(points []FrequencyPoint) float64 {
+	if len(points) == 0 {
+		return math.NaN()
+	}
+
+	for _, p := range points[1:] {
+		if p.Magnitude < points[0].Magnitude-3 {
+			return p.Frequency
+		}
+	}
+	return math.NaN()
+}
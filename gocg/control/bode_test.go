@@ -0,0 +1,35 @@
+package control
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEstimateFrequencyResponse(t *testing.T) {
+	// First order low pass with a 5 Hz corner, simulated at 1 kHz with a 0.5 - 50 Hz chirp
+	tau := 1 / (2 * math.Pi * 5)
+	dt := 0.001
+	y := 0.0
+
+	var samples []Sample
+	for i := 0; i < 20000; i++ {
+		t := float64(i) * dt
+		r := Chirp(t, 0.5, 50, 20)
+		y += dt / tau * (r - y)
+		samples = append(samples, Sample{Time: t, Reference: r, Value: y})
+	}
+
+	points, err := EstimateFrequencyResponse(samples, LogFrequencies(1, 20, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if math.Abs(points[0].Magnitude) > 1 {
+		t.Errorf("Expected ~0 dB at 1 Hz, got %.2f dB", points[0].Magnitude)
+	}
+
+	bandwidth := Bandwidth(points)
+	if bandwidth < 4 || bandwidth > 8 {
+		t.Errorf("Expected a bandwidth around 5 Hz, got %.2f Hz", bandwidth)
+	}
+}
@@ -0,0 +1,29 @@
+package control
+
+import "math"
+
+// Below this speed the Coulomb friction compensation is scaled down linearly, so that it doesn't chatter around
+// standstill
+const FRICTION_VELOCITY_BAND = 0.05 // rad/s
+
+// Virtual spring and damper around a set point, plus feed forward torque. Stiffness and damping are done by the motor
+// (motion control kp and kd), the feed forward torque is computed here from the measured speed.
+type Impedance struct {
+	SetPoint  float64 // rad
+	Stiffness float64 // Nm/rad
+	Damping   float64 // Nm/(rad/s)
+	Offset    float64 // Constant torque, Nm
+	Coulomb   float64 // Coulomb friction, Nm
+	Viscous   float64 // Viscous friction, Nm/(rad/s)
+}
+
+// Torque offset plus friction compensation at the given speed
+func (i Impedance) FeedForward(velocity float64) float64 {
+	direction := math.Max(-1, math.Min(1, velocity/FRICTION_VELOCITY_BAND))
+	return i.Offset + i.Coulomb*direction + i.Viscous*velocity
+}
+
+// Total torque the motor should produce at the given angle and speed
+func (i Impedance) Torque(angle float64, velocity float64) float64 {
+	return i.Stiffness*(i.SetPoint-angle) - i.Damping*velocity + i.FeedForward(velocity)
+}
@@ -0,0 +1,30 @@
+package control
+
+import (
+	"math"
+	"testing"
+)
+
+func TestImpedance(t *testing.T) {
+	i := Impedance{SetPoint: 1, Stiffness: 10, Damping: 0.5, Offset: 0.2, Coulomb: 0.1, Viscous: 0.05}
+
+	for _, c := range []struct {
+		angle, velocity, feedForward, torque float64
+	}{
+		{1, 0, 0.2, 0.2},
+		{0.5, 2, 0.2 + 0.1 + 0.1, 5 - 1 + 0.4},
+		{1, -1, 0.2 - 0.1 - 0.05, 0.5 + 0.05},
+		{1, FRICTION_VELOCITY_BAND / 2, 0.2 + 0.05 + 0.05*FRICTION_VELOCITY_BAND/2, 0},
+	} {
+		feedForward := i.FeedForward(c.velocity)
+		if math.Abs(feedForward-c.feedForward) > 1e-9 {
+			t.Errorf("Feed forward at %g rad/s: expected %g, actual %g", c.velocity, c.feedForward, feedForward)
+		}
+		if c.torque != 0 {
+			torque := i.Torque(c.angle, c.velocity)
+			if math.Abs(torque-c.torque) > 1e-9 {
+				t.Errorf("Torque at %g rad, %g rad/s: expected %g, actual %g", c.angle, c.velocity, c.torque, torque)
+			}
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package control
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Position and velocity along a path through waypoints
+type Interpolator interface {
+	At(t float64) (position float64, velocity float64)
+}
+
+type linear struct {
+	times  []float64
+	values []float64
+}
+
+func checkWaypoints(times []float64, values []float64) error {
+	if len(times) != len(values) || len(times) < 2 {
+		return fmt.Errorf("need at least two waypoints (times: %d, values: %d)", len(times), len(values))
+	}
+	for i := 1; i < len(times); i++ {
+		if times[i] <= times[i-1] {
+			return fmt.Errorf("waypoint times must increase (%.3f after %.3f)", times[i], times[i-1])
+		}
+	}
+	return nil
+}
+
+// Index of the interval [times[i], times[i+1]] that t falls in, clamped to the first and last interval
+func interval(times []float64, t float64) int {
+	i := sort.SearchFloat64s(times, t) - 1
+	if i < 0 {
+		return 0
+	}
+	if i > len(times)-2 {
+		return len(times) - 2
+	}
+	return i
+}
+
+// Straight lines between the waypoints. Before the first and after the last waypoint, the position is held.
+func NewLinear(times []float64, values []float64) (Interpolator, error) {
+	if err := checkWaypoints(times, values); err != nil {
+		return nil, err
+	}
+	return &linear{times, values}, nil
+}
+
+func (l *linear) At(t float64) (float64, float64) {
+	if t <= l.times[0] {
+		return l.values[0], 0
+	}
+	if t >= l.times[len(l.times)-1] {
+		return l.values[len(l.values)-1], 0
+	}
+
+	i := interval(l.times, t)
+	v := (l.values[i+1] - l.values[i]) / (l.times[i+1] - l.times[i])
+	return l.values[i] + v*(t-l.times[i]), v
+}
+
+// Natural cubic spline, second derivatives in m
+type cubicSpline struct {
+	times  []float64
+	values []float64
+	m      []float64
+}
+
+// Natural cubic spline through the waypoints: continuous velocity and acceleration. Before the first and after the
+// last waypoint, the position is held.
+func NewCubicSpline(times []float64, values []float64) (Interpolator, error) {
+	if err := checkWaypoints(times, values); err != nil {
+		return nil, err
+	}
+
+	n := len(times)
+	m := make([]float64, n)
+
+	// Tridiagonal system for the second derivatives, m[0] = m[n-1] = 0
+	c := make([]float64, n)
+	d := make([]float64, n)
+	for i := 1; i < n-1; i++ {
+		h0 := times[i] - times[i-1]
+		h1 := times[i+1] - times[i]
+		a := h0
+		b := 2 * (h0 + h1)
+		rhs := 6 * ((values[i+1]-values[i])/h1 - (values[i]-values[i-1])/h0)
+
+		denominator := b - a*c[i-1]
+		c[i] = h1 / denominator
+		d[i] = (rhs - a*d[i-1]) / denominator
+	}
+	for i := n - 2; i > 0; i-- {
+		m[i] = d[i] - c[i]*m[i+1]
+	}
+
+	return &cubicSpline{times, values, m}, nil
+}
+
+func (s *cubicSpline) At(t float64) (float64, float64) {
+	if t <= s.times[0] {
+		return s.values[0], 0
+	}
+	if t >= s.times[len(s.times)-1] {
+		return s.values[len(s.values)-1], 0
+	}
+
+	i := interval(s.times, t)
+	h := s.times[i+1] - s.times[i]
+	a := (s.times[i+1] - t) / h
+	b := (t - s.times[i]) / h
+
+	position := a*s.values[i] + b*s.values[i+1] + ((a*a*a-a)*s.m[i]+(b*b*b-b)*s.m[i+1])*h*h/6
+	velocity := (s.values[i+1]-s.values[i])/h + ((1-3*a*a)*s.m[i]+(3*b*b-1)*s.m[i+1])*h/6
+	return position, velocity
+}
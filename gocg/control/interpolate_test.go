@@ -0,0 +1,73 @@
+package control
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestInterpolators(t *testing.T) {
+	times := []float64{0, 1, 2, 4}
+	values := []float64{0, 1, 0, 2}
+
+	lin, _ := NewLinear(times, values)
+	spline, _ := NewCubicSpline(times, values)
+
+	for i, time := range times {
+		for _, interpolator := range []Interpolator{lin, spline} {
+			if p, _ := interpolator.At(time); math.Abs(p-values[i]) > 1e-9 {
+				t.Errorf("%T at %.1f s: %.4f, expected %.4f", interpolator, time, p, values[i])
+			}
+		}
+	}
+
+	if p, v := lin.At(3); p != 1 || v != 1 {
+		t.Errorf("Linear at 3 s: %.3f (v %.3f)", p, v)
+	}
+
+	// Velocity is continuous across waypoints
+	_, before := spline.At(1 - 1e-6)
+	_, after := spline.At(1 + 1e-6)
+	if math.Abs(before-after) > 1e-3 {
+		t.Errorf("Spline velocity jumps at 1 s: %.4f -> %.4f", before, after)
+	}
+
+	if _, err := NewCubicSpline([]float64{0, 0}, []float64{1, 2}); err == nil {
+		t.Error("Expected error for waypoint times that don't increase")
+	}
+}
+
+func TestWaypoints(t *testing.T) {
+	w, err := ReadWaypoints(strings.NewReader("# demo\ntime_s,shoulder,7F\n0,0,0.5\n1.5,1,-0.5\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(w.Motors) != 2 || w.Motors[1] != "7F" || w.Times[1] != 1.5 || w.Column(1)[1] != -0.5 {
+		t.Fatalf("Unexpected waypoints: %+v", w)
+	}
+
+	w.Keyframes[1] = true
+	var buf bytes.Buffer
+	if err := w.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err = ReadWaypoints(&buf)
+	if err != nil || len(w.Motors) != 2 || w.Keyframes[0] || !w.Keyframes[1] {
+		t.Fatalf("Unexpected waypoints after round trip: %+v %v", w, err)
+	}
+
+	if _, err := ReadWaypoints(strings.NewReader("time_s,7F\n0,x\n1,1\n")); err == nil {
+		t.Error("Expected error for invalid angle")
+	}
+
+	if _, err := ReadWaypoints(strings.NewReader("time_s,7F\n-1,0\n1,1\n")); err == nil {
+		t.Error("Expected error for a negative time")
+	}
+
+	if _, err := ReadWaypoints(strings.NewReader("time_s,7F\n0,0\n0,1\n")); err == nil {
+		t.Error("Expected error for a last time of 0")
+	}
+}
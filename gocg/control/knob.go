@@ -0,0 +1,37 @@
+package control
+
+import "math"
+
+// Haptic knob: detents, end stops and friction as a torque computed from the measured angle and speed
+type Knob struct {
+	Detents   int     // Detents per revolution, 0 for none
+	Strength  float64 // Peak detent torque, Nm
+	Center    float64 // Angle of one of the detents, rad
+	EndStops  bool
+	Min       float64 // End stops, rad
+	Max       float64
+	Stiffness float64 // End stop spring, Nm/rad
+	Friction  float64 // Coulomb friction, Nm
+	Damping   float64 // Nm/(rad/s)
+}
+
+// Torque towards the nearest detent, out of the end stops, and against the motion
+func (k Knob) Torque(angle float64, velocity float64) float64 {
+	torque := 0.0
+
+	if k.Detents > 0 {
+		torque -= k.Strength * math.Sin(float64(k.Detents)*(angle-k.Center))
+	}
+
+	if k.EndStops {
+		switch {
+		case angle < k.Min:
+			torque += k.Stiffness * (k.Min - angle)
+		case angle > k.Max:
+			torque += k.Stiffness * (k.Max - angle)
+		}
+	}
+
+	direction := math.Max(-1, math.Min(1, velocity/FRICTION_VELOCITY_BAND))
+	return torque - k.Friction*direction - k.Damping*velocity
+}
@@ -0,0 +1,32 @@
+package control
+
+import (
+	"math"
+	"testing"
+)
+
+func TestKnob(t *testing.T) {
+	k := Knob{Detents: 12, Strength: 0.5, EndStops: true, Min: -1, Max: 1, Stiffness: 20}
+	step := 2 * math.Pi / 12
+
+	for _, c := range []struct {
+		angle, torque float64
+	}{
+		{0, 0},
+		{step, 0},
+		{step / 4, -0.5}, // Halfway to the unstable point between two detents, pulled back
+		{-step / 4, 0.5},
+		{1.1, -2 - 0.5*math.Sin(12*1.1)},
+		{-1.1, 2 - 0.5*math.Sin(-12*1.1)},
+	} {
+		torque := k.Torque(c.angle, 0)
+		if math.Abs(torque-c.torque) > 1e-9 {
+			t.Errorf("Torque at %g rad: expected %g, actual %g", c.angle, c.torque, torque)
+		}
+	}
+
+	k = Knob{Friction: 0.1, Damping: 0.01}
+	if torque := k.Torque(0, 2); math.Abs(torque+0.12) > 1e-9 {
+		t.Errorf("Friction torque: expected -0.12, actual %g", torque)
+	}
+}
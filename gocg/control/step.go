@@ -0,0 +1,129 @@
+package control
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// One sample of a recorded response, time in seconds since the start of the test
+type Sample struct {
+	Time      float64
+	Reference float64
+	Value     float64
+}
+
+// Settling band, fraction of the step size
+const SETTLING_BAND = 0.02
+
+// Returned by AnalyzeStep, with the other metrics, when the value is still outside the settling band at the end
+var ErrNotSettled = errors.New("not settled")
+
+// Step response metrics. Overshoot is a fraction of the step size, times are seconds after the step.
+type StepResponse struct {
+	Initial          float64
+	Final            float64
+	Target           float64
+	RiseTime         float64 // 10% - 90%
+	PeakTime         float64
+	Overshoot        float64
+	SettlingTime     float64 // Time until the value stays within SETTLING_BAND of the final value, NaN if it doesn't
+	SteadyStateError float64 // Target - final value
+}
+
+// Analyzes a step from the samples before stepTime to target. The final value is the mean of the last 10% of the samples.
+func AnalyzeStep(samples []Sample, stepTime float64, target float64) (StepResponse, error) {
+	var before, after []Sample
+	for _, s := range samples {
+		if s.Time < stepTime {
+			before = append(before, s)
+		} else {
+			after = append(after, s)
+		}
+	}
+
+	if len(after) < 10 {
+		return StepResponse{}, fmt.Errorf("too few samples after the step (%d)", len(after))
+	}
+
+	r := StepResponse{Target: target}
+	if len(before) > 0 {
+		r.Initial = mean(before)
+	} else {
+		r.Initial = after[0].Value
+	}
+	r.Final = mean(after[len(after)-len(after)/10:])
+	r.SteadyStateError = target - r.Final
+
+	delta := r.Final - r.Initial
+	if math.Abs(delta) < 1e-6 {
+		return r, fmt.Errorf("no response to the step")
+	}
+
+	// Normalized response, 0 before and 1 after the step
+	normalized := func(s Sample) float64 {
+		return (s.Value - r.Initial) / delta
+	}
+
+	t10, t90 := math.NaN(), math.NaN()
+	peak := math.Inf(-1)
+	for _, s := range after {
+		y := normalized(s)
+		if math.IsNaN(t10) && y >= 0.1 {
+			t10 = s.Time
+		}
+		if math.IsNaN(t90) && y >= 0.9 {
+			t90 = s.Time
+		}
+		if y > peak {
+			peak = y
+			r.PeakTime = s.Time - stepTime
+		}
+	}
+	r.RiseTime = t90 - t10
+	r.Overshoot = math.Max(0, peak-1)
+
+	for i := len(after) - 1; i >= 0; i-- {
+		if math.Abs(normalized(after[i])-1) > SETTLING_BAND {
+			if i == len(after)-1 {
+				r.SettlingTime = math.NaN()
+				return r, fmt.Errorf("%w within %.3f s after the step", ErrNotSettled, after[i].Time-stepTime)
+			}
+			r.SettlingTime = after[i+1].Time - stepTime
+			return r, nil
+		}
+	}
+	r.SettlingTime = 0
+
+	return r, nil
+}
+
+func mean(samples []Sample) float64 {
+	sum := 0.0
+	for _, s := range samples {
+		sum += s.Value
+	}
+	return sum / float64(len(samples))
+}
+
+// Damping ratio and natural frequency (rad/s) of the second order system with the same overshoot and peak time.
+// Without overshoot the system is taken as critically damped and the natural frequency comes from the rise time.
+// An overshoot of 100% or more has no damped second order equivalent.
+func SecondOrderModel(r StepResponse) (zeta float64, wn float64, err error) {
+	if r.Overshoot >= 1 {
+		return 0, 0, fmt.Errorf("%.0f %% overshoot doesn't fit a damped second order model", 100*r.Overshoot)
+	}
+
+	if r.Overshoot > 0.005 && r.PeakTime > 0 {
+		l := math.Log(r.Overshoot)
+		zeta = -l / math.Sqrt(math.Pi*math.Pi+l*l)
+		wn = math.Pi / (r.PeakTime * math.Sqrt(1-zeta*zeta))
+		return zeta, wn, nil
+	}
+
+	// 10% - 90% rise time of a critically damped second order system is 3.36 / wn
+	if r.RiseTime > 0 {
+		return 1, 3.36 / r.RiseTime, nil
+	}
+	return 1, math.NaN(), nil
+}
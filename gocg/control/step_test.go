@@ -0,0 +1,67 @@
+package control
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// Step response of a second order system with the given damping and natural frequency, step at t = 0.1 s
+func secondOrderStep(zeta float64, wn float64) []Sample {
+	var samples []Sample
+	wd := wn * math.Sqrt(1-zeta*zeta)
+	for i := 0; i < 2000; i++ {
+		t := float64(i) * 0.001
+		y := 0.0
+		if t >= 0.1 {
+			tau := t - 0.1
+			y = 1 - math.Exp(-zeta*wn*tau)*(math.Cos(wd*tau)+zeta/math.Sqrt(1-zeta*zeta)*math.Sin(wd*tau))
+		}
+		samples = append(samples, Sample{Time: t, Reference: 1, Value: 2 * y})
+	}
+	return samples
+}
+
+func TestAnalyzeStep(t *testing.T) {
+	r, err := AnalyzeStep(secondOrderStep(0.5, 20), 0.1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 16.3% overshoot, peak after pi / wd
+	if math.Abs(r.Overshoot-0.163) > 0.005 || math.Abs(r.PeakTime-0.181) > 0.002 {
+		t.Errorf("Unexpected overshoot %.3f / peak time %.3f", r.Overshoot, r.PeakTime)
+	}
+
+	if math.Abs(r.SteadyStateError) > 0.001 || r.SettlingTime < 0.3 || r.SettlingTime > 0.5 {
+		t.Errorf("Unexpected steady state error %.4f / settling time %.3f", r.SteadyStateError, r.SettlingTime)
+	}
+
+	zeta, wn, err := SecondOrderModel(r)
+	if err != nil || math.Abs(zeta-0.5) > 0.01 || math.Abs(wn-20) > 0.5 {
+		t.Errorf("Unexpected model: zeta %.3f, wn %.2f (%v)", zeta, wn, err)
+	}
+
+	if _, err := AnalyzeStep(secondOrderStep(0.5, 20)[:50], 0.1, 2); err == nil {
+		t.Error("Expected error without samples after the step")
+	}
+
+	// Still ringing at the end of the recording
+	r, err = AnalyzeStep(secondOrderStep(0.02, 20), 0.1, 2)
+	if !errors.Is(err, ErrNotSettled) || !math.IsNaN(r.SettlingTime) || r.Overshoot < 0.5 {
+		t.Errorf("Expected a response that doesn't settle, got %+v (%v)", r, err)
+	}
+}
+
+func TestSecondOrderModel(t *testing.T) {
+	// No overshoot: critically damped, wn from the rise time
+	zeta, wn, err := SecondOrderModel(StepResponse{RiseTime: 0.168})
+	if err != nil || zeta != 1 || math.Abs(wn-20) > 0.01 {
+		t.Errorf("Unexpected model: zeta %.3f, wn %.2f (%v)", zeta, wn, err)
+	}
+
+	// More than 100% overshoot would take a negative damping ratio
+	if _, _, err := SecondOrderModel(StepResponse{Overshoot: 1.2, PeakTime: 0.1}); err == nil {
+		t.Error("Expected error for 120% overshoot")
+	}
+}
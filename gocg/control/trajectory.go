@@ -0,0 +1,158 @@
+package control
+
+import (
+	"fmt"
+	"math"
+)
+
+// Point to point motion profile
+type Trajectory interface {
+	Duration() float64                                                  // s
+	Sample(t float64) (position float64, velocity float64, acc float64) // At t seconds after the start
+}
+
+// Part of a profile with constant jerk, starting with the given acceleration
+type segment struct {
+	duration float64
+	jerk     float64
+	acc      float64
+}
+
+type segmentProfile struct {
+	start    float64
+	segments []segment
+}
+
+func (p *segmentProfile) Duration() float64 {
+	d := 0.0
+	for _, s := range p.segments {
+		d += s.duration
+	}
+	return d
+}
+
+func (p *segmentProfile) Sample(t float64) (float64, float64, float64) {
+	pos, vel := p.start, 0.0
+	acc := 0.0
+
+	for _, s := range p.segments {
+		dt := math.Min(math.Max(t, 0), s.duration)
+		acc = s.acc + s.jerk*dt
+		pos += vel*dt + s.acc*dt*dt/2 + s.jerk*dt*dt*dt/6
+		vel += s.acc*dt + s.jerk*dt*dt/2
+
+		if t <= s.duration {
+			return pos, vel, acc
+		}
+		t -= s.duration
+	}
+
+	// Done, at rest
+	return pos, 0, 0
+}
+
+func checkLimits(name string, values ...float64) error {
+	for _, v := range values {
+		if !(v > 0) || math.IsInf(v, 0) {
+			return fmt.Errorf("%s: limits must be positive (%v)", name, values)
+		}
+	}
+	return nil
+}
+
+// Trapezoidal velocity profile from start to end with max velocity vmax and acceleration amax. Short moves never reach
+// vmax and become triangular.
+func NewTrapezoidal(start float64, end float64, vmax float64, amax float64) (Trajectory, error) {
+	if err := checkLimits("trapezoidal profile", vmax, amax); err != nil {
+		return nil, err
+	}
+
+	distance := math.Abs(end - start)
+	sign := math.Copysign(1, end-start)
+
+	v := math.Min(vmax, math.Sqrt(distance*amax))
+	ta := v / amax
+	tv := 0.0
+	if v > 0 {
+		tv = distance/v - ta
+	}
+
+	return &segmentProfile{start: start, segments: []segment{
+		{ta, 0, sign * amax},
+		{tv, 0, 0},
+		{ta, 0, -sign * amax},
+	}}, nil
+}
+
+// Time to accelerate from standstill to v with jerk j and max acceleration a, and the time with changing acceleration
+func sCurveAccelerationTime(v float64, a float64, j float64) (ta float64, tj float64) {
+	if v*j >= a*a {
+		tj = a / j
+		return v/a + tj, tj
+	}
+	tj = math.Sqrt(v / j)
+	return 2 * tj, tj
+}
+
+// Jerk limited (S-curve) profile from start to end with max velocity vmax, acceleration amax and jerk jmax.
+// Short moves use a lower peak velocity.
+func NewSCurve(start float64, end float64, vmax float64, amax float64, jmax float64) (Trajectory, error) {
+	if err := checkLimits("S-curve profile", vmax, amax, jmax); err != nil {
+		return nil, err
+	}
+
+	distance := math.Abs(end - start)
+	sign := math.Copysign(1, end-start)
+
+	// Accelerating to v and back down to 0 takes v * ta, find the highest v that fits the distance
+	v := vmax
+	ta, _ := sCurveAccelerationTime(v, amax, jmax)
+	if v*ta > distance {
+		low, high := 0.0, vmax
+		for i := 0; i < 60; i++ {
+			v = (low + high) / 2
+			ta, _ = sCurveAccelerationTime(v, amax, jmax)
+			if v*ta > distance {
+				high = v
+			} else {
+				low = v
+			}
+		}
+		v = low
+	}
+
+	ta, tj := sCurveAccelerationTime(v, amax, jmax)
+	tc := ta - 2*tj
+	ap := jmax * tj
+	tv := 0.0
+	if v > 0 {
+		tv = math.Max(0, distance/v-ta)
+	}
+
+	j := sign * jmax
+	ap *= sign
+
+	return &segmentProfile{start: start, segments: []segment{
+		{tj, j, 0},
+		{tc, 0, ap},
+		{tj, -j, ap},
+		{tv, 0, 0},
+		{tj, -j, 0},
+		{tc, 0, -ap},
+		{tj, j, -ap},
+	}}, nil
+}
+
+// Max and RMS difference between reference and value
+func TrackingError(samples []Sample) (max float64, rms float64) {
+	if len(samples) == 0 {
+		return 0, 0
+	}
+
+	for _, s := range samples {
+		e := math.Abs(s.Reference - s.Value)
+		max = math.Max(max, e)
+		rms += e * e
+	}
+	return max, math.Sqrt(rms / float64(len(samples)))
+}
@@ -0,0 +1,58 @@
+package control
+
+import (
+	"math"
+	"testing"
+)
+
+func checkTrajectory(t *testing.T, name string, trajectory Trajectory, start float64, end float64, vmax float64, amax float64) {
+	t.Helper()
+
+	duration := trajectory.Duration()
+	previous := start
+	for i := 0; i <= 1000; i++ {
+		pos, vel, acc := trajectory.Sample(duration * float64(i) / 1000)
+		if math.Abs(vel) > vmax+1e-9 || math.Abs(acc) > amax+1e-9 {
+			t.Fatalf("%s: limits exceeded at %.3f s: v %.4f, a %.4f", name, duration*float64(i)/1000, vel, acc)
+		}
+		if (end-start)*(pos-previous) < -1e-9 {
+			t.Fatalf("%s: moving backwards at %.3f s", name, duration*float64(i)/1000)
+		}
+		previous = pos
+	}
+
+	if pos, vel, _ := trajectory.Sample(duration); math.Abs(pos-end) > 1e-6 || math.Abs(vel) > 1e-6 {
+		t.Fatalf("%s: ends at %.6f (v %.6f), expected %.6f", name, pos, vel, end)
+	}
+}
+
+func TestTrapezoidal(t *testing.T) {
+	long, _ := NewTrapezoidal(0, 10, 2, 4)
+	checkTrajectory(t, "long", long, 0, 10, 2, 4)
+
+	// 0.5 s acceleration, 4.5 s cruise, 0.5 s deceleration
+	if math.Abs(long.Duration()-5.5) > 1e-9 {
+		t.Errorf("Unexpected duration %.3f", long.Duration())
+	}
+
+	short, _ := NewTrapezoidal(1, 0.9, 2, 4)
+	checkTrajectory(t, "short", short, 1, 0.9, 2, 4)
+
+	if _, err := NewTrapezoidal(0, 1, 0, 4); err == nil {
+		t.Error("Expected error for zero velocity limit")
+	}
+}
+
+func TestSCurve(t *testing.T) {
+	long, _ := NewSCurve(0, 10, 2, 4, 20)
+	checkTrajectory(t, "long", long, 0, 10, 2, 4)
+
+	short, _ := NewSCurve(0, -0.05, 2, 4, 20)
+	checkTrajectory(t, "short", short, 0, -0.05, 2, 4)
+
+	// Acceleration is continuous
+	_, _, acc := long.Sample(0)
+	if acc != 0 {
+		t.Errorf("Expected zero acceleration at the start, got %.3f", acc)
+	}
+}
@@ -0,0 +1,106 @@
+package control
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Joint space waypoints. CSV with a header row "time_s,<motor>,<motor>...[,keyframe]" and one row per waypoint with
+// the time (s) and one angle (rad) per motor. Motors are names from the profile or CAN ids. The optional keyframe
+// column is 1 for waypoints marked while teaching.
+type Waypoints struct {
+	Motors    []string
+	Times     []float64
+	Angles    [][]float64 // Angles[waypoint][motor]
+	Keyframes []bool
+}
+
+const KEYFRAME_COLUMN = "keyframe"
+
+func ReadWaypoints(r io.Reader) (*Waypoints, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records) < 1 || len(records[0]) < 2 {
+		return nil, fmt.Errorf("missing header (time_s,<motor>,...)")
+	}
+
+	header := records[0]
+	hasKeyframes := strings.EqualFold(header[len(header)-1], KEYFRAME_COLUMN)
+	motorColumns := len(header) - 1
+	if hasKeyframes {
+		motorColumns--
+	}
+	if motorColumns < 1 {
+		return nil, fmt.Errorf("no motor columns in header")
+	}
+
+	w := &Waypoints{Motors: header[1 : 1+motorColumns]}
+	for line, record := range records[1:] {
+		t, err := strconv.ParseFloat(record[0], 64)
+		if err != nil || t < 0 || math.IsNaN(t) || math.IsInf(t, 0) {
+			return nil, fmt.Errorf("line %d: invalid time '%s' (s, 0 or more)", line+2, record[0])
+		}
+
+		angles := make([]float64, motorColumns)
+		for i := range angles {
+			angles[i], err = strconv.ParseFloat(record[1+i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid angle '%s'", line+2, record[1+i])
+			}
+		}
+
+		w.Times = append(w.Times, t)
+		w.Angles = append(w.Angles, angles)
+		w.Keyframes = append(w.Keyframes, hasKeyframes && record[len(record)-1] == "1")
+	}
+
+	if len(w.Times) < 2 {
+		return nil, fmt.Errorf("need at least two waypoints")
+	}
+
+	if w.Times[len(w.Times)-1] <= 0 {
+		return nil, fmt.Errorf("the last waypoint must be after 0 s")
+	}
+
+	return w, nil
+}
+
+func (w *Waypoints) Write(writer io.Writer) error {
+	out := csv.NewWriter(writer)
+	out.Write(append(append([]string{"time_s"}, w.Motors...), KEYFRAME_COLUMN))
+
+	for i, t := range w.Times {
+		record := []string{strconv.FormatFloat(t, 'f', 3, 64)}
+		for _, angle := range w.Angles[i] {
+			record = append(record, strconv.FormatFloat(angle, 'f', 4, 64))
+		}
+		keyframe := "0"
+		if i < len(w.Keyframes) && w.Keyframes[i] {
+			keyframe = "1"
+		}
+		out.Write(append(record, keyframe))
+	}
+
+	out.Flush()
+	return out.Error()
+}
+
+// Angles of one motor over all waypoints
+func (w *Waypoints) Column(motor int) []float64 {
+	column := make([]float64, len(w.Angles))
+	for i, angles := range w.Angles {
+		column[i] = angles[motor]
+	}
+	return column
+}
@@ -0,0 +1,174 @@
+package cybergear
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+const MAX_EXTENDED_ID = 0x1FFFFFFF
+
+// CyberGear CAN frame: 29 bit extended CAN id and up to 8 bytes of payload.
+//
+//	bit 28 - 24 : communication type
+//	bit 23 - 8  : data area 2 (host CAN id in bit 8 - 15 for most communication types)
+//	bit 7 - 0   : target address
+type Frame struct {
+	id     uint32
+	length int
+	data   [8]byte
+}
+
+func NewFrame(communicationType CommunicationType) *Frame {
+	f := &Frame{}
+	f.SetCommunicationType(communicationType)
+	return f
+}
+
+func (f *Frame) Id() uint32 {
+	return f.id
+}
+
+func (f *Frame) SetId(id uint32) error {
+	if id > MAX_EXTENDED_ID {
+		return fmt.Errorf("invalid CAN id (0x%X). Max extended id is 0x%X", id, MAX_EXTENDED_ID)
+	}
+	f.id = id
+	return nil
+}
+
+// bit 24 - 28
+func (f *Frame) CommunicationType() CommunicationType {
+	return CommunicationType(f.id >> 24 & 0x1F)
+}
+
+func (f *Frame) SetCommunicationType(t CommunicationType) {
+	f.id = f.id&^(0x1F<<24) | uint32(t&0x1F)<<24
+}
+
+// bit 8 - 23
+func (f *Frame) DataArea() uint16 {
+	return uint16(f.id >> 8)
+}
+
+func (f *Frame) SetDataArea(data uint16) {
+	f.id = f.id&^(0xFFFF<<8) | uint32(data)<<8
+}
+
+// bit 8 - 15: host CAN id for frames sent to a motor, motor CAN id for frames sent by a motor
+func (f *Frame) HostId() byte {
+	return byte(f.id >> 8)
+}
+
+func (f *Frame) SetHostId(id byte) {
+	f.id = f.id&^(0xFF<<8) | uint32(id)<<8
+}
+
+// bit 0 - 7
+func (f *Frame) TargetId() byte {
+	return byte(f.id)
+}
+
+func (f *Frame) SetTargetId(id byte) {
+	f.id = f.id&^0xFF | uint32(id)
+}
+
+// Data length (DLC)
+func (f *Frame) Len() int {
+	return f.length
+}
+
+func (f *Frame) SetLen(length int) error {
+	if length < 0 || length > 8 {
+		return fmt.Errorf("invalid data length %d. Max is 8 bytes", length)
+	}
+	f.length = length
+	return nil
+}
+
+// The payload (Len() bytes)
+func (f *Frame) Data() []byte {
+	return f.data[:f.length]
+}
+
+func (f *Frame) SetData(data []byte) error {
+	err := f.SetLen(len(data))
+	if err != nil {
+		return err
+	}
+	f.data = [8]byte{}
+	copy(f.data[:], data)
+	return nil
+}
+
+// Little endian payload helpers. The setters extend the data length to cover the value. A value that doesn't
+// fit in the 8 byte payload at offset reads as 0 and isn't written.
+
+func (f *Frame) Uint16(offset int) uint16 {
+	if !fits(offset, 2) {
+		return 0
+	}
+	return binary.LittleEndian.Uint16(f.data[offset:])
+}
+
+func (f *Frame) SetUint16(offset int, value uint16) {
+	if !fits(offset, 2) {
+		return
+	}
+	binary.LittleEndian.PutUint16(f.data[offset:], value)
+	f.extend(offset + 2)
+}
+
+func (f *Frame) Uint32(offset int) uint32 {
+	if !fits(offset, 4) {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(f.data[offset:])
+}
+
+func (f *Frame) SetUint32(offset int, value uint32) {
+	if !fits(offset, 4) {
+		return
+	}
+	binary.LittleEndian.PutUint32(f.data[offset:], value)
+	f.extend(offset + 4)
+}
+
+func (f *Frame) Float32(offset int) float32 {
+	return math.Float32frombits(f.Uint32(offset))
+}
+
+func (f *Frame) SetFloat32(offset int, value float32) {
+	f.SetUint32(offset, math.Float32bits(value))
+}
+
+// Motion control (type 1) and feedback (type 2) frames carry big endian 16 bit values
+
+func (f *Frame) BigEndianUint16(offset int) uint16 {
+	if !fits(offset, 2) {
+		return 0
+	}
+	return binary.BigEndian.Uint16(f.data[offset:])
+}
+
+func (f *Frame) SetBigEndianUint16(offset int, value uint16) {
+	if !fits(offset, 2) {
+		return
+	}
+	binary.BigEndian.PutUint16(f.data[offset:], value)
+	f.extend(offset + 2)
+}
+
+func fits(offset int, size int) bool {
+	return offset >= 0 && offset+size <= 8
+}
+
+func (f *Frame) extend(length int) {
+	if f.length < length {
+		f.length = length
+	}
+}
+
+func (f *Frame) String() string {
+	return fmt.Sprintf("%08X [%d] % X", f.id, f.length, f.Data())
+}
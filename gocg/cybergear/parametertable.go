@@ -0,0 +1,396 @@
+package cybergear
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type ParameterType int
+
+const (
+	PARAMETER_TYPE_FLOAT ParameterType = iota
+	PARAMETER_TYPE_UINT8
+	PARAMETER_TYPE_UINT16
+	PARAMETER_TYPE_UINT32
+	PARAMETER_TYPE_INT16
+	PARAMETER_TYPE_INT32
+	PARAMETER_TYPE_STRING
+)
+
+var parameterTypeNames = map[ParameterType]string{
+	PARAMETER_TYPE_FLOAT:  "float",
+	PARAMETER_TYPE_UINT8:  "uint8",
+	PARAMETER_TYPE_UINT16: "uint16",
+	PARAMETER_TYPE_UINT32: "uint32",
+	PARAMETER_TYPE_INT16:  "int16",
+	PARAMETER_TYPE_INT32:  "int32",
+	PARAMETER_TYPE_STRING: "string",
+}
+
+func (t ParameterType) String() string {
+	return parameterTypeNames[t]
+}
+
+// Whether a value survives a power cycle. Run area writes (type 18) are lost after power failure, the config area
+// holds what the motor starts with. The manual doesn't document how to save to the config area, so gocg doesn't.
+type Persistence int
+
+const (
+	VOLATILE Persistence = iota
+	PERSISTENT
+)
+
+func (p Persistence) String() string {
+	if p == PERSISTENT {
+		return "persistent"
+	}
+	return "volatile"
+}
+
+// Description of a configParameter or motorParameterIndex, see the constants for the source of the types and ranges.
+type ParameterInfo struct {
+	Index    uint16
+	Name     string // Name of the constant, e.g. CONFIG_WR_SPD_KP
+	Type     ParameterType
+	Writable bool
+	Command  bool // Setpoint or motor state rather than configuration (e.g. PARAMETER_SPD_REF)
+	Ranged   bool // Min and Max are valid
+	Min      float64
+	Max      float64
+}
+
+// Parameters in the config area (0x0000 - 0x302F) are only read with the undocumented communication type 9, gocg
+// knows them to decode sniffed frames but doesn't read or write them. Parameters in the run area (0x7005 - 0x7020)
+// are read and written with types 17 and 18.
+func (p ParameterInfo) Config() bool {
+	return p.Index < 0x7000
+}
+
+// Read only values that change while the motor runs (currents, temperatures, position etc)
+func (p ParameterInfo) Measurement() bool {
+	return (p.Index >= 0x3000 && p.Config()) || (!p.Config() && !p.Writable)
+}
+
+func (p ParameterInfo) Persistence() Persistence {
+	if p.Config() {
+		return PERSISTENT
+	}
+	return VOLATILE
+}
+
+func (p ParameterInfo) InRange(value float64) bool {
+	return !p.Ranged || (value >= p.Min && value <= p.Max)
+}
+
+func (p ParameterInfo) RangeString() string {
+	if !p.Ranged {
+		return ""
+	}
+	return fmt.Sprintf("[%g, %g]", p.Min, p.Max)
+}
+
+// Interprets the 4 data bytes of a parameter frame (byte 4 - 7, little endian) according to the parameter type
+func (p ParameterInfo) Decode(raw uint32) float64 {
+	switch p.Type {
+	case PARAMETER_TYPE_FLOAT:
+		return float64(math.Float32frombits(raw))
+	case PARAMETER_TYPE_UINT8:
+		return float64(uint8(raw))
+	case PARAMETER_TYPE_UINT16:
+		return float64(uint16(raw))
+	case PARAMETER_TYPE_INT16:
+		return float64(int16(raw))
+	case PARAMETER_TYPE_INT32:
+		return float64(int32(raw))
+	}
+	return float64(raw)
+}
+
+// Inverse of Decode
+func (p ParameterInfo) Encode(value float64) (uint32, error) {
+	if p.Type == PARAMETER_TYPE_STRING {
+		return 0, fmt.Errorf("%s is a string parameter", p.Name)
+	}
+
+	if !p.InRange(value) {
+		return 0, fmt.Errorf("%s: %g is outside the valid range %s", p.Name, value, p.RangeString())
+	}
+
+	if p.Type == PARAMETER_TYPE_FLOAT {
+		if math.IsNaN(value) || math.IsInf(float64(float32(value)), 0) {
+			return 0, fmt.Errorf("%s: %g doesn't fit in %s", p.Name, value, p.Type)
+		}
+		return math.Float32bits(float32(value)), nil
+	}
+
+	if value != math.Trunc(value) {
+		return 0, fmt.Errorf("%s: %g is not an integer", p.Name, value)
+	}
+
+	switch p.Type {
+	case PARAMETER_TYPE_UINT8:
+		if value < 0 || value > math.MaxUint8 {
+			return 0, fmt.Errorf("%s: %g doesn't fit in %s", p.Name, value, p.Type)
+		}
+	case PARAMETER_TYPE_UINT16:
+		if value < 0 || value > math.MaxUint16 {
+			return 0, fmt.Errorf("%s: %g doesn't fit in %s", p.Name, value, p.Type)
+		}
+	case PARAMETER_TYPE_UINT32:
+		if value < 0 || value > math.MaxUint32 {
+			return 0, fmt.Errorf("%s: %g doesn't fit in %s", p.Name, value, p.Type)
+		}
+	case PARAMETER_TYPE_INT16:
+		if value < math.MinInt16 || value > math.MaxInt16 {
+			return 0, fmt.Errorf("%s: %g doesn't fit in %s", p.Name, value, p.Type)
+		}
+		return uint32(uint16(int16(value))), nil
+	case PARAMETER_TYPE_INT32:
+		if value < math.MinInt32 || value > math.MaxInt32 {
+			return 0, fmt.Errorf("%s: %g doesn't fit in %s", p.Name, value, p.Type)
+		}
+		return uint32(int32(value)), nil
+	}
+
+	return uint32(value), nil
+}
+
+// Formats a decoded value, integers without decimals
+func (p ParameterInfo) Format(value float64) string {
+	if p.Type == PARAMETER_TYPE_FLOAT {
+		return strconv.FormatFloat(value, 'g', -1, 32)
+	}
+	return strconv.FormatFloat(value, 'f', 0, 64)
+}
+
+func ranged(min float64, max float64) func(*ParameterInfo) {
+	return func(p *ParameterInfo) {
+		p.Ranged = true
+		p.Min = min
+		p.Max = max
+	}
+}
+
+func command(p *ParameterInfo) {
+	p.Command = true
+}
+
+func param(index uint16, name string, t ParameterType, writable bool, options ...func(*ParameterInfo)) ParameterInfo {
+	p := ParameterInfo{Index: index, Name: name, Type: t, Writable: writable}
+	for _, option := range options {
+		option(&p)
+	}
+	return p
+}
+
+const (
+	R  = false
+	WR = true
+)
+
+var parameterTable = []ParameterInfo{
+	// Config area, read only: writing it isn't documented
+	param(uint16(CONFIG_WR_NAME), "CONFIG_WR_NAME", PARAMETER_TYPE_STRING, R),
+	param(uint16(CONFIG_R_BAR_CODE), "CONFIG_R_BAR_CODE", PARAMETER_TYPE_STRING, R),
+	param(uint16(CONFIG_R_BOOT_CODE_VERSION), "CONFIG_R_BOOT_CODE_VERSION", PARAMETER_TYPE_STRING, R),
+	param(uint16(CONFIG_R_BOOT_BUILD_DATE), "CONFIG_R_BOOT_BUILD_DATE", PARAMETER_TYPE_STRING, R),
+	param(uint16(CONFIG_R_BOOT_BUILD_TIME), "CONFIG_R_BOOT_BUILD_TIME", PARAMETER_TYPE_STRING, R),
+	param(uint16(CONFIG_R_APP_CODE_VERSION), "CONFIG_R_APP_CODE_VERSION", PARAMETER_TYPE_STRING, R),
+	param(uint16(CONFIG_R_APP_GIT_VERSION), "CONFIG_R_APP_GIT_VERSION", PARAMETER_TYPE_STRING, R),
+	param(uint16(CONFIG_R_APP_BUILD_DATE), "CONFIG_R_APP_BUILD_DATE", PARAMETER_TYPE_STRING, R),
+	param(uint16(CONFIG_R_APP_BUILD_TIME), "CONFIG_R_APP_BUILD_TIME", PARAMETER_TYPE_STRING, R),
+	param(uint16(CONFIG_R_APP_CODE_NAME), "CONFIG_R_APP_CODE_NAME", PARAMETER_TYPE_STRING, R),
+	param(uint16(CONFIG_R_ECHO_PARA1), "CONFIG_R_ECHO_PARA1", PARAMETER_TYPE_UINT16, R),
+	param(uint16(CONFIG_R_ECHO_PARA2), "CONFIG_R_ECHO_PARA2", PARAMETER_TYPE_UINT16, R),
+	param(uint16(CONFIG_R_ECHO_PARA3), "CONFIG_R_ECHO_PARA3", PARAMETER_TYPE_UINT16, R),
+	param(uint16(CONFIG_R_ECHO_PARA4), "CONFIG_R_ECHO_PARA4", PARAMETER_TYPE_UINT16, R),
+	param(uint16(CONFIG_WR_ECHO_FRE_HZ), "CONFIG_WR_ECHO_FRE_HZ", PARAMETER_TYPE_UINT32, R),
+	param(uint16(CONFIG_R_MECH_OFFSET), "CONFIG_R_MECH_OFFSET", PARAMETER_TYPE_FLOAT, R, ranged(-7, 7)),
+	param(uint16(CONFIG_WR_MECH_POS_INIT), "CONFIG_WR_MECH_POS_INIT", PARAMETER_TYPE_FLOAT, R, ranged(-50, 50)),
+	param(uint16(CONFIG_WR_LIMIT_TORQUE), "CONFIG_WR_LIMIT_TORQUE", PARAMETER_TYPE_FLOAT, R, ranged(0, 12)),
+	param(uint16(CONFIG_WR_I_FW_MAX), "CONFIG_WR_I_FW_MAX", PARAMETER_TYPE_FLOAT, R, ranged(0, 33)),
+	param(uint16(CONFIG_WR_MOTOR_INDEX), "CONFIG_WR_MOTOR_INDEX", PARAMETER_TYPE_UINT8, R, ranged(0, 20)),
+	param(uint16(CONFIG_WR_CAN_ID), "CONFIG_WR_CAN_ID", PARAMETER_TYPE_UINT8, R, ranged(0, 127), command),
+	param(uint16(CONFIG_WR_CAN_MASTER), "CONFIG_WR_CAN_MASTER", PARAMETER_TYPE_UINT8, R, ranged(0, 127)),
+	param(uint16(CONFIG_WR_CAN_TIMEOUT), "CONFIG_WR_CAN_TIMEOUT", PARAMETER_TYPE_UINT32, R, ranged(0, 10000)),
+	param(uint16(CONFIG_WR_MOTOR_OVER_TEMP), "CONFIG_WR_MOTOR_OVER_TEMP", PARAMETER_TYPE_UINT16, R, ranged(0, 1500)),
+	param(uint16(CONFIG_WR_OVER_TEMP_TIME), "CONFIG_WR_OVER_TEMP_TIME", PARAMETER_TYPE_UINT32, R, ranged(0, 100000)),
+	param(uint16(CONFIG_WR_GEAR_RATIO), "CONFIG_WR_GEAR_RATIO", PARAMETER_TYPE_FLOAT, R, ranged(1, 64)),
+	param(uint16(CONFIG_WR_TQ_CALI_TYPE), "CONFIG_WR_TQ_CALI_TYPE", PARAMETER_TYPE_UINT8, R, ranged(0, 1)),
+	param(uint16(CONFIG_WR_CUR_FILT_GAIN), "CONFIG_WR_CUR_FILT_GAIN", PARAMETER_TYPE_FLOAT, R, ranged(0, 1)),
+	param(uint16(CONFIG_WR_CUR_KP), "CONFIG_WR_CUR_KP", PARAMETER_TYPE_FLOAT, R, ranged(0, 200)),
+	param(uint16(CONFIG_WR_CUR_KI), "CONFIG_WR_CUR_KI", PARAMETER_TYPE_FLOAT, R, ranged(0, 200)),
+	param(uint16(CONFIG_WR_SPD_KP), "CONFIG_WR_SPD_KP", PARAMETER_TYPE_FLOAT, R, ranged(0, 200)),
+	param(uint16(CONFIG_WR_SPD_KI), "CONFIG_WR_SPD_KI", PARAMETER_TYPE_FLOAT, R, ranged(0, 200)),
+	param(uint16(CONFIG_WR_LOC_KP), "CONFIG_WR_LOC_KP", PARAMETER_TYPE_FLOAT, R, ranged(0, 200)),
+	param(uint16(CONFIG_WR_SPD_FILT_GAIN), "CONFIG_WR_SPD_FILT_GAIN", PARAMETER_TYPE_FLOAT, R, ranged(0, 1)),
+	param(uint16(CONFIG_WR_LIMIT_SPD), "CONFIG_WR_LIMIT_SPD", PARAMETER_TYPE_FLOAT, R, ranged(0, 200)),
+	param(uint16(CONFIG_WR_LIMIT_CUR), "CONFIG_WR_LIMIT_CUR", PARAMETER_TYPE_FLOAT, R, ranged(0, 27)),
+	param(uint16(CONFIG_R_TIME_USE0), "CONFIG_R_TIME_USE0", PARAMETER_TYPE_UINT16, R),
+	param(uint16(CONFIG_R_TIME_USE1), "CONFIG_R_TIME_USE1", PARAMETER_TYPE_UINT16, R),
+	param(uint16(CONFIG_R_TIME_USE2), "CONFIG_R_TIME_USE2", PARAMETER_TYPE_UINT16, R),
+	param(uint16(CONFIG_R_TIME_USE3), "CONFIG_R_TIME_USE3", PARAMETER_TYPE_UINT16, R),
+	param(uint16(CONFIG_R_ENCODER_RAW), "CONFIG_R_ENCODER_RAW", PARAMETER_TYPE_UINT16, R),
+	param(uint16(CONFIG_R_MCU_TEMP), "CONFIG_R_MCU_TEMP", PARAMETER_TYPE_UINT16, R),
+	param(uint16(CONFIG_R_MOTOR_TEMP), "CONFIG_R_MOTOR_TEMP", PARAMETER_TYPE_UINT16, R),
+	param(uint16(CONFIG_R_VBUS_MV), "CONFIG_R_VBUS_MV", PARAMETER_TYPE_UINT16, R),
+	param(uint16(CONFIG_R_ADC1_OFFSET), "CONFIG_R_ADC1_OFFSET", PARAMETER_TYPE_INT32, R),
+	param(uint16(CONFIG_R_ADC2_OFFSET), "CONFIG_R_ADC2_OFFSET", PARAMETER_TYPE_INT32, R),
+	param(uint16(CONFIG_R_ADC1_RAW), "CONFIG_R_ADC1_RAW", PARAMETER_TYPE_UINT32, R),
+	param(uint16(CONFIG_R_ADC2_RAW), "CONFIG_R_ADC2_RAW", PARAMETER_TYPE_UINT32, R),
+	param(uint16(CONFIG_R_VBUS_V), "CONFIG_R_VBUS_V", PARAMETER_TYPE_FLOAT, R),
+	param(uint16(CONFIG_R_CMD_ID), "CONFIG_R_CMD_ID", PARAMETER_TYPE_FLOAT, R),
+	param(uint16(CONFIG_R_CMD_IQ), "CONFIG_R_CMD_IQ", PARAMETER_TYPE_FLOAT, R),
+	param(uint16(CONFIG_R_CMD_LOC_REF), "CONFIG_R_CMD_LOC_REF", PARAMETER_TYPE_FLOAT, R),
+	param(uint16(CONFIG_R_CMD_SPD_REF), "CONFIG_R_CMD_SPD_REF", PARAMETER_TYPE_FLOAT, R),
+	param(uint16(CONFIG_R_CMD_TORQUE), "CONFIG_R_CMD_TORQUE", PARAMETER_TYPE_FLOAT, R),
+	param(uint16(CONFIG_R_CMD_POS), "CONFIG_R_CMD_POS", PARAMETER_TYPE_FLOAT, R),
+	param(uint16(CONFIG_R_CMD_VEL), "CONFIG_R_CMD_VEL", PARAMETER_TYPE_FLOAT, R),
+	param(uint16(CONFIG_R_ROTATION), "CONFIG_R_ROTATION", PARAMETER_TYPE_INT16, R),
+	param(uint16(CONFIG_R_MOD_POS), "CONFIG_R_MOD_POS", PARAMETER_TYPE_FLOAT, R),
+	param(uint16(CONFIG_R_MECH_POS), "CONFIG_R_MECH_POS", PARAMETER_TYPE_FLOAT, R),
+	param(uint16(CONFIG_R_MECH_VEL), "CONFIG_R_MECH_VEL", PARAMETER_TYPE_FLOAT, R),
+	param(uint16(CONFIG_R_ELEC_POS), "CONFIG_R_ELEC_POS", PARAMETER_TYPE_FLOAT, R),
+	param(uint16(CONFIG_R_IA), "CONFIG_R_IA", PARAMETER_TYPE_FLOAT, R),
+	param(uint16(CONFIG_R_IB), "CONFIG_R_IB", PARAMETER_TYPE_FLOAT, R),
+	param(uint16(CONFIG_R_IC), "CONFIG_R_IC", PARAMETER_TYPE_FLOAT, R),
+	param(uint16(CONFIG_R_TICK), "CONFIG_R_TICK", PARAMETER_TYPE_UINT32, R),
+	param(uint16(CONFIG_R_PHASE_ORDER), "CONFIG_R_PHASE_ORDER", PARAMETER_TYPE_UINT8, R),
+	param(uint16(CONFIG_R_IQF), "CONFIG_R_IQF", PARAMETER_TYPE_FLOAT, R),
+	param(uint16(CONFIG_R_BOARD_TEMP), "CONFIG_R_BOARD_TEMP", PARAMETER_TYPE_INT16, R),
+	param(uint16(CONFIG_R_IQ), "CONFIG_R_IQ", PARAMETER_TYPE_FLOAT, R),
+	param(uint16(CONFIG_R_ID), "CONFIG_R_ID", PARAMETER_TYPE_FLOAT, R),
+	param(uint16(CONFIG_R_FAULT_STATUS), "CONFIG_R_FAULT_STATUS", PARAMETER_TYPE_UINT32, R),
+	param(uint16(CONFIG_R_WARN_STATUS), "CONFIG_R_WARN_STATUS", PARAMETER_TYPE_UINT32, R),
+	param(uint16(CONFIG_R_DRV_FAULT), "CONFIG_R_DRV_FAULT", PARAMETER_TYPE_UINT16, R),
+	param(uint16(CONFIG_R_DRV_TEMP), "CONFIG_R_DRV_TEMP", PARAMETER_TYPE_INT16, R),
+	param(uint16(CONFIG_R_UQ), "CONFIG_R_UQ", PARAMETER_TYPE_FLOAT, R),
+	param(uint16(CONFIG_R_UD), "CONFIG_R_UD", PARAMETER_TYPE_FLOAT, R),
+	param(uint16(CONFIG_R_DTC_U), "CONFIG_R_DTC_U", PARAMETER_TYPE_FLOAT, R),
+	param(uint16(CONFIG_R_DTC_V), "CONFIG_R_DTC_V", PARAMETER_TYPE_FLOAT, R),
+	param(uint16(CONFIG_R_DTC_W), "CONFIG_R_DTC_W", PARAMETER_TYPE_FLOAT, R),
+	param(uint16(CONFIG_R_CLOSED_LOOP_V_BUS), "CONFIG_R_CLOSED_LOOP_V_BUS", PARAMETER_TYPE_FLOAT, R),
+	param(uint16(CONFIG_R_CLOSED_LOOP_V_REF), "CONFIG_R_CLOSED_LOOP_V_REF", PARAMETER_TYPE_FLOAT, R),
+	param(uint16(CONFIG_R_TORQUE_FDB), "CONFIG_R_TORQUE_FDB", PARAMETER_TYPE_FLOAT, R),
+	param(uint16(CONFIG_R_RATED_I), "CONFIG_R_RATED_I", PARAMETER_TYPE_FLOAT, R),
+	param(uint16(CONFIG_R_LIMIT_I), "CONFIG_R_LIMIT_I", PARAMETER_TYPE_FLOAT, R),
+
+	param(uint16(PARAMETER_RUN_MODE), "PARAMETER_RUN_MODE", PARAMETER_TYPE_UINT8, WR, ranged(0, 3), command),
+	param(uint16(PARAMETER_IQ_REF), "PARAMETER_IQ_REF", PARAMETER_TYPE_FLOAT, WR, ranged(-23, 23), command),
+	param(uint16(PARAMETER_SPD_REF), "PARAMETER_SPD_REF", PARAMETER_TYPE_FLOAT, WR, ranged(-30, 30), command),
+	param(uint16(PARAMETER_IMIT_TORQUE), "PARAMETER_IMIT_TORQUE", PARAMETER_TYPE_FLOAT, WR, ranged(0, 12)),
+	param(uint16(PARAMETER_CUR_KP), "PARAMETER_CUR_KP", PARAMETER_TYPE_FLOAT, WR),
+	param(uint16(PARAMETER_CUR_KI), "PARAMETER_CUR_KI", PARAMETER_TYPE_FLOAT, WR),
+	param(uint16(PARAMETER_CUR_FILT_GAIN), "PARAMETER_CUR_FILT_GAIN", PARAMETER_TYPE_FLOAT, WR, ranged(0, 1)),
+	param(uint16(PARAMETER_LOC_REF), "PARAMETER_LOC_REF", PARAMETER_TYPE_FLOAT, WR, command),
+	param(uint16(PARAMETER_LIMIT_SPD), "PARAMETER_LIMIT_SPD", PARAMETER_TYPE_FLOAT, WR, ranged(0, 30)),
+	param(uint16(PARAMETER_LIMIT_CUR), "PARAMETER_LIMIT_CUR", PARAMETER_TYPE_FLOAT, WR, ranged(0, 23)),
+	param(uint16(PARAMETER_MECH_POS), "PARAMETER_MECH_POS", PARAMETER_TYPE_FLOAT, R),
+	param(uint16(PARAMETER_IQF), "PARAMETER_IQF", PARAMETER_TYPE_FLOAT, R, ranged(-23, 23)),
+	param(uint16(PARAMETER_MECH_VEL), "PARAMETER_MECH_VEL", PARAMETER_TYPE_FLOAT, R, ranged(-30, 30)),
+	param(uint16(PARAMETER_MECH_VBUS), "PARAMETER_MECH_VBUS", PARAMETER_TYPE_FLOAT, R),
+	param(uint16(PARAMETER_MECH_ROTATION), "PARAMETER_MECH_ROTATION", PARAMETER_TYPE_INT16, WR, command),
+	param(uint16(PARAMETER_LOC_KP), "PARAMETER_LOC_KP", PARAMETER_TYPE_FLOAT, WR),
+	param(uint16(PARAMETER_SPD_KP), "PARAMETER_SPD_KP", PARAMETER_TYPE_FLOAT, WR),
+	param(uint16(PARAMETER_SPD_KI), "PARAMETER_SPD_KI", PARAMETER_TYPE_FLOAT, WR),
+}
+
+// Run area parameters with a config area parameter that holds the value the motor starts with
+var persistentCounterparts = map[motorParameterIndex]configParameter{
+	PARAMETER_IMIT_TORQUE:   CONFIG_WR_LIMIT_TORQUE,
+	PARAMETER_CUR_KP:        CONFIG_WR_CUR_KP,
+	PARAMETER_CUR_KI:        CONFIG_WR_CUR_KI,
+	PARAMETER_CUR_FILT_GAIN: CONFIG_WR_CUR_FILT_GAIN,
+	PARAMETER_LIMIT_SPD:     CONFIG_WR_LIMIT_SPD,
+	PARAMETER_LIMIT_CUR:     CONFIG_WR_LIMIT_CUR,
+	PARAMETER_LOC_KP:        CONFIG_WR_LOC_KP,
+	PARAMETER_SPD_KP:        CONFIG_WR_SPD_KP,
+	PARAMETER_SPD_KI:        CONFIG_WR_SPD_KI,
+}
+
+// The config area parameter a volatile run area parameter starts from
+func PersistentCounterpart(p ParameterInfo) (ParameterInfo, bool) {
+	index, ok := persistentCounterparts[motorParameterIndex(p.Index)]
+	if !ok {
+		return ParameterInfo{}, false
+	}
+	return ParameterByIndex(uint16(index))
+}
+
+// All known parameters, config area first
+func Parameters() []ParameterInfo {
+	return append([]ParameterInfo{}, parameterTable...)
+}
+
+// Looks up a parameter by constant name (case insensitive, e.g. config_wr_spd_kp) or hex index (e.g. 0x2014)
+func LookupParameter(nameOrIndex string) (ParameterInfo, error) {
+	for _, p := range parameterTable {
+		if strings.EqualFold(p.Name, nameOrIndex) {
+			return p, nil
+		}
+	}
+
+	index, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(nameOrIndex), "0x"), 16, 16)
+	if err == nil {
+		if p, ok := ParameterByIndex(uint16(index)); ok {
+			return p, nil
+		}
+	}
+
+	return ParameterInfo{}, fmt.Errorf("unknown parameter '%s'", nameOrIndex)
+}
+
+func ParameterByIndex(index uint16) (ParameterInfo, bool) {
+	for _, p := range parameterTable {
+		if p.Index == index {
+			return p, true
+		}
+	}
+	return ParameterInfo{}, false
+}
+
+// Reads a run area parameter (type 17). Reading the config area takes the undocumented type 9 and isn't supported.
+func ReadParameterCmd(hostId byte, motorId byte, p ParameterInfo) (*Frame, error) {
+	if p.Config() {
+		return nil, fmt.Errorf("%s is in the config area, reading it isn't supported", p.Name)
+	}
+
+	frame, err := newHostFrame(COMMUNICATION_READ_SINGLE_PARAM, hostId, motorId)
+	if err != nil {
+		return nil, err
+	}
+
+	frame.SetUint16(0, p.Index)
+	frame.SetLen(8)
+
+	return frame, nil
+}
+
+// Writes a run area parameter (type 18). The value is checked against the parameter type and range. Config area
+// writes aren't documented and aren't supported.
+func WriteParameterValueCmd(hostId byte, motorId byte, p ParameterInfo, value float64) (*Frame, error) {
+	if !p.Writable {
+		return nil, fmt.Errorf("%s is read only", p.Name)
+	}
+
+	if p.Config() {
+		return nil, fmt.Errorf("%s is in the config area, writing it isn't supported", p.Name)
+	}
+
+	raw, err := p.Encode(value)
+	if err != nil {
+		return nil, err
+	}
+
+	frame, err := newHostFrame(COMMUNICATION_WRITE_SINGLE_PARAM, hostId, motorId)
+	if err != nil {
+		return nil, err
+	}
+
+	frame.SetUint16(0, p.Index)
+	frame.SetUint32(4, raw)
+
+	return frame, nil
+}
@@ -18,12 +18,38 @@ const (
 	COMMUNICATION_DISABLE_DEVICE               CommunicationType = 4 // Motor stopped (communication type 4)
 	COMMUNICATION_SET_MECHANICAL_ZERO_POSITION CommunicationType = 6 // Setting the mechanical zero position of the motor (communication type 6) will set the current motor position to the mechanical zero position (lost after power failure)
 	COMMUNICATION_SET_CAN_ID                   CommunicationType = 7 // Set motor CAN_ID (communication type 7) to change the current motor CAN_ID, which will take effect immediately.
+	COMMUNICATION_WRITE_CONFIG_PARAM           CommunicationType = 8 // Config area (0x0000 - 0x302F) parameter writing - undocumented, decoded but never sent
+	COMMUNICATION_READ_CONFIG_PARAM            CommunicationType = 9 // Config area (0x0000 - 0x302F) parameter reading - undocumented, same layout as type 17
 	COMMUNICATION_GET_STATUS                   CommunicationType = 15
 	COMMUNICATION_READ_SINGLE_PARAM            CommunicationType = 17 // Single parameter reading (communication type 17)
 	COMMUNICATION_WRITE_SINGLE_PARAM           CommunicationType = 18 // Single parameter writing (communication type 18) (lost after power failure)
 	COMMUNICATION_ERROR_REPORT                 CommunicationType = 21 // Fault feedback frame (communication type 21)
 )
 
+var communicationTypeNames = map[CommunicationType]string{
+	COMMUNICATION_FETCH_DEVICE_ID:              "device id",
+	COMMUNICATION_MOTION_CONTROL_COMMAND:       "motion control",
+	COMMUNICATION_STATUS_REPORT:                "feedback",
+	COMMUNICATION_ENABLE_DEVICE:                "enable",
+	COMMUNICATION_DISABLE_DEVICE:               "disable",
+	COMMUNICATION_SET_MECHANICAL_ZERO_POSITION: "set zero",
+	COMMUNICATION_SET_CAN_ID:                   "set CAN id",
+	COMMUNICATION_WRITE_CONFIG_PARAM:           "write config",
+	COMMUNICATION_READ_CONFIG_PARAM:            "read config",
+	COMMUNICATION_GET_STATUS:                   "get status",
+	COMMUNICATION_READ_SINGLE_PARAM:            "read param",
+	COMMUNICATION_WRITE_SINGLE_PARAM:           "write param",
+	COMMUNICATION_ERROR_REPORT:                 "fault",
+}
+
+func (t CommunicationType) String() string {
+	name, ok := communicationTypeNames[t]
+	if !ok {
+		return fmt.Sprintf("type %d", int(t))
+	}
+	return name
+}
+
 type configParameter int
 
 const ( // (Translated from chinese)
@@ -163,201 +189,66 @@ type singleParam struct {
 	data      [4]byte
 }
 
-// CyberGear SLCAN frame
-type SLCanFrame struct {
-	header   [10]byte
-	data     [16]byte
-	checksum byte
-}
-
-func NewSLCanFrame() SLCanFrame {
-	return SLCanFrame{
-		header: [10]byte{0x30, 0x30, 0x30, 0x30, 0x30, 0x30, 0x30, 0x30, 0x30, 0x30},
-		data:   [16]byte{0x30, 0x30, 0x30, 0x30, 0x30, 0x30, 0x30, 0x30, 0x30, 0x30, 0x30, 0x30, 0x30, 0x30, 0x30, 0x30},
-	}
-}
-
-func (f *SLCanFrame) Serialize() []byte {
-	buf := []byte{}
-	buf = append(buf, f.header[:]...)
-
-	if f.header[9] != 0x30 {
-		buf = append(buf, f.data[:(f.header[9]-0x30)*2]...)
-	}
-
-	// For some reason, the SLCan implementation for MKS CANable and / or cybergear doesn't seem to be interested in the checksum
-	// var checksum byte
-	// for _, b := range buf {
-	// 	checksum ^= byte(b)
-	// }
-	// checksumStr := fmt.Sprintf("%02X", checksum)
-	// buf = append(buf, checksumStr[1])
-	// buf = append(buf, checksumStr[0])
-	return buf
-}
-
-// 4.1.4 Motor enable operation (communication type 3)
-func EnableMotorCmd(hostId byte, motorId byte) (*SLCanFrame, error) {
+// Frame from the host to a motor, with the host CAN id in bit 8 - 15 and the motor CAN id in bit 0 - 7
+func newHostFrame(communicationType CommunicationType, hostId byte, motorId byte) (*Frame, error) {
 	if hostId > MAX_CAN_ID {
 		return nil, fmt.Errorf("invalid host Id (%d). Max Id is %d", hostId, MAX_CAN_ID)
 	}
 
 	if motorId > MAX_CAN_ID {
-		return nil, fmt.Errorf("invalid motor Id (%d). Max Id is %d", hostId, MAX_CAN_ID)
+		return nil, fmt.Errorf("invalid motor Id (%d). Max Id is %d", motorId, MAX_CAN_ID)
 	}
 
-	hostIdString := fmt.Sprintf("%02X", hostId)
-	motorIdString := fmt.Sprintf("%02X", motorId)
-	communicationType := fmt.Sprintf("%02X", COMMUNICATION_ENABLE_DEVICE)
+	frame := NewFrame(communicationType)
+	frame.SetHostId(hostId)
+	frame.SetTargetId(motorId)
 
-	frame := NewSLCanFrame()
-	frame.header[0] = 'T' // Extended frame
-	frame.header[1] = communicationType[0]
-	frame.header[2] = communicationType[1]
-	frame.header[5] = hostIdString[0]
-	frame.header[6] = hostIdString[1]
-	frame.header[7] = motorIdString[0]
-	frame.header[8] = motorIdString[1]
-
-	return &frame, nil
+	return frame, nil
 }
 
-// 4.1.4 Get Status (communication type 15) - undocumented...
-func GetStatusCmd(hostId byte, motorId byte) (*SLCanFrame, error) {
-	if hostId > MAX_CAN_ID {
-		return nil, fmt.Errorf("invalid host Id (%d). Max Id is %d", hostId, MAX_CAN_ID)
-	}
-
-	if motorId > MAX_CAN_ID {
-		return nil, fmt.Errorf("invalid motor Id (%d). Max Id is %d", hostId, MAX_CAN_ID)
-	}
-
-	hostIdString := fmt.Sprintf("%02X", hostId)
-	motorIdString := fmt.Sprintf("%02X", motorId)
-	communicationType := fmt.Sprintf("%02X", COMMUNICATION_GET_STATUS)
+// 4.1.1 Get device ID (communication type 0), answered with the 64 bit MCU unique identifier
+func FetchDeviceIdCmd(hostId byte, motorId byte) (*Frame, error) {
+	return newHostFrame(COMMUNICATION_FETCH_DEVICE_ID, hostId, motorId)
+}
 
-	frame := NewSLCanFrame()
-	frame.header[0] = 'T' // Extended frame
-	frame.header[1] = communicationType[0]
-	frame.header[2] = communicationType[1]
-	frame.header[5] = hostIdString[0]
-	frame.header[6] = hostIdString[1]
-	frame.header[7] = motorIdString[0]
-	frame.header[8] = motorIdString[1]
+// 4.1.4 Motor enable operation (communication type 3)
+func EnableMotorCmd(hostId byte, motorId byte) (*Frame, error) {
+	return newHostFrame(COMMUNICATION_ENABLE_DEVICE, hostId, motorId)
+}
 
-	return &frame, nil
+// 4.1.4 Get Status (communication type 15) - undocumented...
+func GetStatusCmd(hostId byte, motorId byte) (*Frame, error) {
+	return newHostFrame(COMMUNICATION_GET_STATUS, hostId, motorId)
 }
 
 // 4.1.5 Motor stopped (communication type 4)
-func DisableMotorCmd(hostId byte, motorId byte) (*SLCanFrame, error) {
-	if hostId > MAX_CAN_ID {
-		return nil, fmt.Errorf("invalid host Id (%d). Max Id is %d", hostId, MAX_CAN_ID)
-	}
-
-	if motorId > MAX_CAN_ID {
-		return nil, fmt.Errorf("invalid motor Id (%d). Max Id is %d", hostId, MAX_CAN_ID)
-	}
-
-	hostIdString := fmt.Sprintf("%02X", hostId)
-	motorIdString := fmt.Sprintf("%02X", motorId)
-	communicationType := fmt.Sprintf("%02X", COMMUNICATION_DISABLE_DEVICE)
-
-	frame := NewSLCanFrame()
-	frame.header[0] = 'T' // Extended frame
-	frame.header[1] = communicationType[0]
-	frame.header[2] = communicationType[1]
-	frame.header[5] = hostIdString[0]
-	frame.header[6] = hostIdString[1]
-	frame.header[7] = motorIdString[0]
-	frame.header[8] = motorIdString[1]
-
-	return &frame, nil
+func DisableMotorCmd(hostId byte, motorId byte) (*Frame, error) {
+	return newHostFrame(COMMUNICATION_DISABLE_DEVICE, hostId, motorId)
 }
 
-func SetRunMode(hostId byte, motorId byte, mode runModeType) (*SLCanFrame, error) {
-	if hostId > MAX_CAN_ID {
-		return nil, fmt.Errorf("invalid host Id (%d). Max Id is %d", hostId, MAX_CAN_ID)
+func SetRunMode(hostId byte, motorId byte, mode runModeType) (*Frame, error) {
+	frame, err := newHostFrame(COMMUNICATION_WRITE_SINGLE_PARAM, hostId, motorId)
+	if err != nil {
+		return nil, err
 	}
 
-	if motorId > MAX_CAN_ID {
-		return nil, fmt.Errorf("invalid motor Id (%d). Max Id is %d", hostId, MAX_CAN_ID)
-	}
+	frame.SetUint16(0, uint16(PARAMETER_RUN_MODE))
+	frame.SetUint32(4, uint32(mode))
 
-	hostIdString := fmt.Sprintf("%02X", hostId)
-	motorIdString := fmt.Sprintf("%02X", motorId)
-	communicationType := fmt.Sprintf("%02X", COMMUNICATION_WRITE_SINGLE_PARAM)
-
-	frame := NewSLCanFrame()
-	frame.header[0] = 'T' // Extended frame
-	frame.header[1] = communicationType[0]
-	frame.header[2] = communicationType[1]
-	frame.header[5] = hostIdString[0]
-	frame.header[6] = hostIdString[1]
-	frame.header[7] = motorIdString[0]
-	frame.header[8] = motorIdString[1]
-	frame.header[9] = '8' // DLC
-
-	index := fmt.Sprintf("%04X", PARAMETER_RUN_MODE)
-
-	frame.data[0] = index[2]
-	frame.data[1] = index[3]
-	frame.data[2] = index[0]
-	frame.data[3] = index[1]
-
-	runMode := fmt.Sprintf("%02X", mode)
-
-	frame.data[8] = runMode[0]
-	frame.data[9] = runMode[1]
-	frame.data[10] = '0'
-	frame.data[11] = '0'
-	frame.data[12] = '0'
-	frame.data[13] = '0'
-	frame.data[14] = '0'
-	frame.data[15] = '0'
-
-	return &frame, nil
+	return frame, nil
 }
 
-func ReadSingleParameterFrame(hostId byte, motorId byte, parameter motorParameterIndex) (*SLCanFrame, error) {
-	if hostId > MAX_CAN_ID {
-		return nil, fmt.Errorf("invalid host Id (%d). Max Id is %d", hostId, MAX_CAN_ID)
+// 4.1.8 Single parameter reading (communication type 17)
+func ReadSingleParameterFrame(hostId byte, motorId byte, parameter motorParameterIndex) (*Frame, error) {
+	frame, err := newHostFrame(COMMUNICATION_READ_SINGLE_PARAM, hostId, motorId)
+	if err != nil {
+		return nil, err
 	}
 
-	if motorId > MAX_CAN_ID {
-		return nil, fmt.Errorf("invalid motor Id (%d). Max Id is %d", hostId, MAX_CAN_ID)
-	}
+	frame.SetUint16(0, uint16(parameter))
+	frame.SetLen(8)
 
-	hostIdString := fmt.Sprintf("%02X", hostId)
-	motorIdString := fmt.Sprintf("%02X", motorId)
-	communicationType := fmt.Sprintf("%02X", COMMUNICATION_READ_SINGLE_PARAM)
-
-	frame := NewSLCanFrame()
-	frame.header[0] = 'T' // Extended frame
-	frame.header[1] = communicationType[0]
-	frame.header[2] = communicationType[1]
-	frame.header[5] = hostIdString[0]
-	frame.header[6] = hostIdString[1]
-	frame.header[7] = motorIdString[0]
-	frame.header[8] = motorIdString[1]
-	frame.header[9] = '8' // DLC
-
-	index := fmt.Sprintf("%04X", parameter)
-
-	frame.data[0] = index[2]
-	frame.data[1] = index[3]
-	frame.data[2] = index[0]
-	frame.data[3] = index[1]
-
-	frame.data[8] = '0'
-	frame.data[9] = '0'
-	frame.data[10] = '0'
-	frame.data[11] = '0'
-	frame.data[12] = '0'
-	frame.data[13] = '0'
-	frame.data[14] = '0'
-	frame.data[15] = '0'
-
-	return &frame, nil
+	return frame, nil
 }
 
 // 4.1.9 Single parameter writing (communication type 18) (lost in case of power failure)
@@ -372,49 +263,73 @@ func ReadSingleParameterFrame(hostId byte, motorId byte, parameter motorParamete
 //	PARAMETER_LOC_REF 		loc_ref 		Position mode angle					float	4 		rad 						R/W
 //	PARAMETER_LIMIT_SPD		limit_spd 		Location mode speed limit			float 	4 		0~30rad/s 					R/W
 //	PARAMETER_LIMIT_CUR		limit_cur 		Speed Position mode Current limit	float 	4 		0~23A						R/W
-func WriteParameterCmd(hostId byte, motorId byte, index motorParameterIndex, data float32) (*SLCanFrame, error) {
-	if hostId > MAX_CAN_ID {
-		return nil, fmt.Errorf("invalid host Id (%d). Max Id is %d", hostId, MAX_CAN_ID)
+func WriteParameterCmd(hostId byte, motorId byte, index motorParameterIndex, data float32) (*Frame, error) {
+	frame, err := newHostFrame(COMMUNICATION_WRITE_SINGLE_PARAM, hostId, motorId)
+	if err != nil {
+		return nil, err
 	}
 
+	frame.SetUint16(0, uint16(index))
+	frame.SetFloat32(4, data)
+
+	return frame, nil
+}
+
+// Maps value in [min, max] to the nearest of [0, 65535]
+func floatToUint16(value float32, min float32, max float32) uint16 {
+	return uint16(math.Round(float64(value-min) * 65535 / float64(max-min)))
+}
+
+// 4.1.2 Operation control mode motor control instructions (communication type 1). The torque goes in the data area,
+// angle, speed, kp and kd in the payload, all big endian. The motor answers with a feedback frame (type 2).
+func MotionControlCmd(motorId byte, angle float32, speed float32, kp float32, kd float32, torque float32) (*Frame, error) {
 	if motorId > MAX_CAN_ID {
-		return nil, fmt.Errorf("invalid motor Id (%d). Max Id is %d", hostId, MAX_CAN_ID)
+		return nil, fmt.Errorf("invalid motor Id (%d). Max Id is %d", motorId, MAX_CAN_ID)
+	}
+
+	c := motionControl{id: motorId, angle: angle, speed: speed, kp: kp, kd: kd, torque: torque}
+
+	switch {
+	case c.angle < -4*math.Pi || c.angle > 4*math.Pi:
+		return nil, fmt.Errorf("invalid angle %.3f rad. Valid range is [-4pi, 4pi]", c.angle)
+	case c.speed < -30 || c.speed > 30:
+		return nil, fmt.Errorf("invalid speed %.3f rad/s. Valid range is [-30, 30]", c.speed)
+	case c.kp < 0 || c.kp > 500:
+		return nil, fmt.Errorf("invalid kp %.3f. Valid range is [0, 500]", c.kp)
+	case c.kd < 0 || c.kd > 5:
+		return nil, fmt.Errorf("invalid kd %.3f. Valid range is [0, 5]", c.kd)
+	case c.torque < -12 || c.torque > 12:
+		return nil, fmt.Errorf("invalid torque %.3f Nm. Valid range is [-12, 12]", c.torque)
+	}
+
+	frame := NewFrame(COMMUNICATION_MOTION_CONTROL_COMMAND)
+	frame.SetDataArea(floatToUint16(c.torque, -12, 12))
+	frame.SetTargetId(c.id)
+	frame.SetBigEndianUint16(0, floatToUint16(c.angle, -4*math.Pi, 4*math.Pi))
+	frame.SetBigEndianUint16(2, floatToUint16(c.speed, -30, 30))
+	frame.SetBigEndianUint16(4, floatToUint16(c.kp, 0, 500))
+	frame.SetBigEndianUint16(6, floatToUint16(c.kd, 0, 5))
+
+	return frame, nil
+}
+
+// Frame of any communication type, for experimenting with undocumented features. The 16 bit data area (bit 8 - 23)
+// is used as given, so a frame to a motor carries the host id only if data has it in bit 8 - 15.
+func CustomFrameCmd(communicationType CommunicationType, motorId byte, data uint16, payload []byte) (*Frame, error) {
+	if communicationType > 0x1F {
+		return nil, fmt.Errorf("invalid communication type (%d). Max type is %d", communicationType, 0x1F)
+	}
+
+	frame := NewFrame(communicationType)
+	frame.SetDataArea(data)
+	frame.SetTargetId(motorId)
+
+	err := frame.SetData(payload)
+	if err != nil {
+		return nil, err
 	}
 
-	hostIdString := fmt.Sprintf("%02X", hostId)
-	motorIdString := fmt.Sprintf("%02X", motorId)
-	communicationType := fmt.Sprintf("%02X", COMMUNICATION_WRITE_SINGLE_PARAM)
-
-	frame := NewSLCanFrame()
-	frame.header[0] = 'T' // Extended frame
-	frame.header[1] = communicationType[0]
-	frame.header[2] = communicationType[1]
-	frame.header[5] = hostIdString[0]
-	frame.header[6] = hostIdString[1]
-	frame.header[7] = motorIdString[0]
-	frame.header[8] = motorIdString[1]
-	frame.header[9] = '8' // DLC
-
-	indexString := fmt.Sprintf("%04X", index)
-
-	frame.data[0] = indexString[2]
-	frame.data[1] = indexString[3]
-	frame.data[2] = indexString[0]
-	frame.data[3] = indexString[1]
-
-	data_int := math.Float32bits(data)
-	incrediblyWeirdEncoding := fmt.Sprintf("%02X%02X%02X%02X", data_int>>24&0xFF, data_int>>16&0xFF, data_int>>8&0xFF, data_int&0xFF)
-
-	frame.data[8] = incrediblyWeirdEncoding[6]
-	frame.data[9] = incrediblyWeirdEncoding[7]
-	frame.data[10] = incrediblyWeirdEncoding[4]
-	frame.data[11] = incrediblyWeirdEncoding[5]
-	frame.data[12] = incrediblyWeirdEncoding[2]
-	frame.data[13] = incrediblyWeirdEncoding[3]
-	frame.data[14] = incrediblyWeirdEncoding[0]
-	frame.data[15] = incrediblyWeirdEncoding[1]
-
-	return &frame, nil
+	return frame, nil
 }
 
 // /* 对 CAN ID 区域特定的比特位进行赋值整数
@@ -3,14 +3,13 @@ package cybergear
 import (
 	"encoding/hex"
 	"fmt"
+	"math"
 	"slices"
 	"testing"
 )
 
 func TestFrameEnable(t *testing.T) {
-	expected := NewcgSLCanFrame()
-
-	copy(expected.header[:], []byte{0x54, 0x30, 0x33, 0x30, 0x30, 0x36, 0x34, 0x37, 0x46, 0x30})
+	var expectedId uint32 = 0x0300647F
 
 	frame, err := EnableMotorCmd(0x64, 0x7F)
 
@@ -18,15 +17,13 @@ func TestFrameEnable(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if !slices.Equal(expected.header[:], frame.header[:]) {
-		t.Errorf("Unextected frame header: expected: %+v - actual: %+v", expected.header, frame.header)
+	if frame.Id() != expectedId || frame.Len() != 0 {
+		t.Errorf("Unextected frame: expected: %08X [0] - actual: %s", expectedId, frame)
 	}
 }
 
 func TestFrameDisable(t *testing.T) {
-	expected := NewcgSLCanFrame()
-
-	copy(expected.header[:], []byte{0x54, 0x30, 0x34, 0x30, 0x30, 0x36, 0x34, 0x37, 0x46, 0x30})
+	var expectedId uint32 = 0x0400647F
 
 	frame, err := DisableMotorCmd(0x64, 0x7F)
 
@@ -34,120 +31,156 @@ func TestFrameDisable(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if !slices.Equal(expected.header[:], frame.header[:]) {
-		t.Errorf("Unextected frame header: expected: %+v - actual: %+v", expected.header, frame.header)
+	if frame.Id() != expectedId || frame.Len() != 0 {
+		t.Errorf("Unextected frame: expected: %08X [0] - actual: %s", expectedId, frame)
 	}
 }
 
-func TestFrameSerializeNoPayload(t *testing.T) {
-	f := NewcgSLCanFrame()
-	b := f.Serialize()
+func TestFrameFetchDeviceId(t *testing.T) {
+	var expectedId uint32 = 0x0000647F
+
+	frame, err := FetchDeviceIdCmd(0x64, 0x7F)
+
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	if len(b) != 10 {
-		t.Fatal(fmt.Errorf("Unexpected length of serialized frame (%d). DLC=0 => only header should be serialized", len(b)))
+	if frame.Id() != expectedId || frame.Len() != 0 {
+		t.Errorf("Unextected frame: expected: %08X [0] - actual: %s", expectedId, frame)
 	}
 }
 
-func TestFrameSerializeWithPayload(t *testing.T) {
-	f := NewcgSLCanFrame()
-	var dlc byte = '3'
-	f.header[9] = dlc
-	b := f.Serialize()
+func TestFrameInvalidIds(t *testing.T) {
+	if _, err := EnableMotorCmd(0x80, 0x7F); err == nil {
+		t.Errorf("Expected error for host id 0x80")
+	}
 
-	if len(b) != int(10+2*(dlc-0x30)) {
-		t.Fatal(fmt.Errorf("Unexpected length of serialized frame (%d). DLC=%d => only header + %d bytes should be serialized", len(b), dlc, 2*dlc))
+	if _, err := EnableMotorCmd(0x00, 0x80); err == nil {
+		t.Errorf("Expected error for motor id 0x80")
 	}
 }
 
-func TestSetSpeedMode(t *testing.T) {
-	expected := NewcgSLCanFrame()
-	copy(expected.header[:], []byte{0x54, 0x31, 0x32, 0x30, 0x30, 0x30, 0x30, 0x37, 0x46, 0x38})
-	copy(expected.data[:], []byte{0x30, 0x35, 0x37, 0x30, 0x30, 0x30, 0x30, 0x30, 0x30, 0x32, 0x30, 0x30, 0x30, 0x30, 0x30, 0x30})
+func TestFrameIdFields(t *testing.T) {
+	f := NewFrame(COMMUNICATION_WRITE_SINGLE_PARAM)
+	f.SetDataArea(0xABCD)
+	f.SetTargetId(0x7F)
 
-	var hostId byte = 0x00
-	var motorId byte = 0x7F
+	if f.Id() != 0x12ABCD7F {
+		t.Fatalf("Unexpected id: %08X", f.Id())
+	}
 
-	actual, err := SetRunMode(hostId, motorId, SPEED_MODE)
+	f.SetHostId(0x01)
+	f.SetCommunicationType(COMMUNICATION_STATUS_REPORT)
 
-	if err != nil {
-		t.Fatal(err)
+	if f.Id() != 0x02AB017F {
+		t.Fatalf("Unexpected id: %08X", f.Id())
 	}
 
-	if !slices.Equal(expected.header[:], actual.header[:]) {
-		t.Fatalf("Wrong header bytes. Expected: %+v, Actual: %+v", expected.header, actual.header)
+	if f.CommunicationType() != COMMUNICATION_STATUS_REPORT || f.DataArea() != 0xAB01 || f.HostId() != 0x01 || f.TargetId() != 0x7F {
+		t.Fatalf("Unexpected fields: %d %04X %02X %02X", f.CommunicationType(), f.DataArea(), f.HostId(), f.TargetId())
 	}
 
-	if !slices.Equal(expected.data[:], actual.data[:]) {
-		t.Fatalf("Wrong data bytes. Expected: %s, Actual: %s", hex.Dump(expected.data[:]), hex.Dump(actual.data[:]))
+	if err := f.SetId(0x20000000); err == nil {
+		t.Fatal("Expected error for id wider than 29 bits")
 	}
 }
 
-func TestSetOperationControlMode(t *testing.T) {
-	expected := NewcgSLCanFrame()
-	copy(expected.header[:], []byte{0x54, 0x31, 0x32, 0x30, 0x30, 0x30, 0x30, 0x37, 0x46, 0x38})
-	copy(expected.data[:], []byte{0x30, 0x35, 0x37, 0x30, 0x30, 0x30, 0x30, 0x30, 0x30, 0x30, 0x30, 0x30, 0x30, 0x30, 0x30, 0x30})
+func TestFramePayloadHelpers(t *testing.T) {
+	f := NewFrame(COMMUNICATION_WRITE_SINGLE_PARAM)
 
-	var hostId byte = 0x00
-	var motorId byte = 0x7F
+	f.SetUint16(0, 0x700A)
+	if f.Len() != 2 {
+		t.Fatal(fmt.Errorf("Unexpected length (%d) after writing 2 bytes", f.Len()))
+	}
 
-	actual, err := SetRunMode(hostId, motorId, OPEARATION_CONTROL_MODE)
+	f.SetFloat32(4, 1.12)
+	if f.Len() != 8 {
+		t.Fatal(fmt.Errorf("Unexpected length (%d) after writing 8 bytes", f.Len()))
+	}
 
-	if err != nil {
-		t.Fatal(err)
+	expected := []byte{0x0A, 0x70, 0x00, 0x00, 0x29, 0x5C, 0x8F, 0x3F}
+	if !slices.Equal(expected, f.Data()) {
+		t.Fatalf("Wrong data bytes. Expected: %s, Actual: %s", hex.Dump(expected), hex.Dump(f.Data()))
 	}
 
-	if !slices.Equal(expected.header[:], actual.header[:]) {
-		t.Fatalf("Wrong header bytes. Expected: %+v, Actual: %+v", expected.header, actual.header)
+	if f.Uint16(0) != 0x700A || f.Float32(4) != 1.12 {
+		t.Fatalf("Unexpected values: %04X %f", f.Uint16(0), f.Float32(4))
 	}
 
-	if !slices.Equal(expected.data[:], actual.data[:]) {
-		t.Fatalf("Wrong data bytes. Expected: %s, Actual: %s", hex.Dump(expected.data[:]), hex.Dump(actual.data[:]))
+	f.SetBigEndianUint16(0, 0x1234)
+	if f.Data()[0] != 0x12 || f.BigEndianUint16(0) != 0x1234 {
+		t.Fatalf("Unexpected big endian encoding: % X", f.Data())
+	}
+
+	if err := f.SetData(make([]byte, 9)); err == nil {
+		t.Fatal("Expected error for payload longer than 8 bytes")
+	}
+
+	// Offsets outside the payload read 0 and leave the frame alone
+	f.SetUint32(6, 0xFFFFFFFF)
+	f.SetUint16(-1, 0xFFFF)
+	f.SetBigEndianUint16(7, 0xFFFF)
+	if f.Uint32(5) != 0 || f.Uint16(8) != 0 || f.BigEndianUint16(-2) != 0 || f.Float32(100) != 0 {
+		t.Fatal("Expected 0 for values outside the payload")
+	}
+	if f.Len() != 8 || f.Uint16(6) != 0x3F8F {
+		t.Fatalf("Frame modified by out of range writes: %s", f)
 	}
 }
 
-func TestSetLocationMode(t *testing.T) {
-	expected := NewcgSLCanFrame()
-	copy(expected.header[:], []byte{0x54, 0x31, 0x32, 0x30, 0x30, 0x30, 0x30, 0x37, 0x46, 0x38})
-	copy(expected.data[:], []byte{0x30, 0x35, 0x37, 0x30, 0x30, 0x30, 0x30, 0x30, 0x30, 0x31, 0x30, 0x30, 0x30, 0x30, 0x30, 0x30})
+func testRunMode(t *testing.T, mode runModeType, expectedData []byte) {
+	var expectedId uint32 = 0x1200007F
 
 	var hostId byte = 0x00
 	var motorId byte = 0x7F
 
-	actual, err := SetRunMode(hostId, motorId, LOCATION_MODE)
+	actual, err := SetRunMode(hostId, motorId, mode)
 
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	if !slices.Equal(expected.header[:], actual.header[:]) {
-		t.Fatalf("Wrong header bytes. Expected: %+v, Actual: %+v", expected.header, actual.header)
+	if expectedId != actual.Id() {
+		t.Fatalf("Wrong id. Expected: %08X, Actual: %08X", expectedId, actual.Id())
 	}
 
-	if !slices.Equal(expected.data[:], actual.data[:]) {
-		t.Fatalf("Wrong data bytes. Expected: %s, Actual: %s", hex.Dump(expected.data[:]), hex.Dump(actual.data[:]))
+	if !slices.Equal(expectedData, actual.Data()) {
+		t.Fatalf("Wrong data bytes. Expected: %s, Actual: %s", hex.Dump(expectedData), hex.Dump(actual.Data()))
 	}
 }
 
+func TestSetSpeedMode(t *testing.T) {
+	testRunMode(t, SPEED_MODE, []byte{0x05, 0x70, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00})
+}
+
+func TestSetOperationControlMode(t *testing.T) {
+	testRunMode(t, OPEARATION_CONTROL_MODE, []byte{0x05, 0x70, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00})
+}
+
+func TestSetLocationMode(t *testing.T) {
+	testRunMode(t, LOCATION_MODE, []byte{0x05, 0x70, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00})
+}
+
 func TestSetCurrentMode(t *testing.T) {
-	expected := NewcgSLCanFrame()
-	copy(expected.header[:], []byte{0x54, 0x31, 0x32, 0x30, 0x30, 0x30, 0x30, 0x37, 0x46, 0x38})
-	copy(expected.data[:], []byte{0x30, 0x35, 0x37, 0x30, 0x30, 0x30, 0x30, 0x30, 0x30, 0x33, 0x30, 0x30, 0x30, 0x30, 0x30, 0x30})
+	testRunMode(t, CURRENT_MODE, []byte{0x05, 0x70, 0x00, 0x00, 0x03, 0x00, 0x00, 0x00})
+}
 
-	var hostId byte = 0x00
-	var motorId byte = 0x7F
+func TestReadSingleParameterFrame(t *testing.T) {
+	var expectedId uint32 = 0x1100007F
+	expectedData := []byte{0x19, 0x70, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
 
-	actual, err := SetRunMode(hostId, motorId, CURRENT_MODE)
+	actual, err := ReadSingleParameterFrame(0x00, 0x7F, PARAMETER_MECH_POS)
 
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	if !slices.Equal(expected.header[:], actual.header[:]) {
-		t.Fatalf("Wrong header bytes. Expected: %+v, Actual: %+v", expected.header, actual.header)
+	if expectedId != actual.Id() {
+		t.Fatalf("Wrong id. Expected: %08X, Actual: %08X", expectedId, actual.Id())
 	}
 
-	if !slices.Equal(expected.data[:], actual.data[:]) {
-		t.Fatalf("Wrong data bytes. Expected: %s, Actual: %s", hex.Dump(expected.data[:]), hex.Dump(actual.data[:]))
+	if !slices.Equal(expectedData, actual.Data()) {
+		t.Fatalf("Wrong data bytes. Expected: %s, Actual: %s", hex.Dump(expectedData), hex.Dump(actual.Data()))
 	}
 }
 
@@ -159,9 +192,8 @@ func TestWriteParameterCmd(t *testing.T) {
 	var speed float32 = 1.12 // rad/s
 
 	// Speed mode - expected data
-	expected := NewcgSLCanFrame()
-	copy(expected.header[:], []byte{0x54, 0x31, 0x32, 0x30, 0x30, 0x30, 0x30, 0x37, 0x46, 0x38})
-	copy(expected.data[:], []byte{0x30, 0x41, 0x37, 0x30, 0x30, 0x30, 0x30, 0x30, 0x32, 0x39, 0x35, 0x43, 0x38, 0x46, 0x33, 0x46})
+	var expectedId uint32 = 0x1200007F
+	expectedData := []byte{0x0A, 0x70, 0x00, 0x00, 0x29, 0x5C, 0x8F, 0x3F}
 
 	actual, err := WriteParameterCmd(hostId, motorId, PARAMETER_SPD_REF, speed)
 
@@ -169,24 +201,160 @@ func TestWriteParameterCmd(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if !slices.Equal(expected.header[:], actual.header[:]) {
-		t.Fatalf("Wrong header bytes. Expected: %+v, Actual: %+v", expected.header, actual.header)
+	if expectedId != actual.Id() {
+		t.Fatalf("Wrong id. Expected: %08X, Actual: %08X", expectedId, actual.Id())
 	}
 
-	if !slices.Equal(expected.data[:], actual.data[:]) {
-		t.Fatalf("Wrong data bytes. Expected: %s, Actual: %s", hex.Dump(expected.data[:]), hex.Dump(actual.data[:]))
+	if !slices.Equal(expectedData, actual.Data()) {
+		t.Fatalf("Wrong data bytes. Expected: %s, Actual: %s", hex.Dump(expectedData), hex.Dump(actual.Data()))
 	}
 }
 
-func TestFrameSerializeWithFullPayload(t *testing.T) {
+func TestCustomFrameCmd(t *testing.T) {
+	// Get status (type 15) from host 0x64 to motor 0x7F, no payload
+	actual, err := CustomFrameCmd(COMMUNICATION_GET_STATUS, 0x7F, 0x0064, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	f := NewcgSLCanFrame()
-	copy(f.header[:], []byte{0x54, 0x31, 0x32, 0x30, 0x30, 0x30, 0x30, 0x37, 0x46, 0x38})
-	copy(f.data[:], []byte{0x30, 0x41, 0x37, 0x30, 0x30, 0x30, 0x30, 0x30, 0x32, 0x39, 0x35, 0x43, 0x38, 0x46, 0x33, 0x46})
+	if actual.Id() != 0x0F00647F || actual.Len() != 0 {
+		t.Fatalf("Wrong frame. Expected: 0F00647F [0], Actual: %s", actual)
+	}
 
-	expected := []byte{0x54, 0x31, 0x32, 0x30, 0x30, 0x30, 0x30, 0x37, 0x46, 0x38, 0x30, 0x41, 0x37, 0x30, 0x30, 0x30, 0x30, 0x30, 0x32, 0x39, 0x35, 0x43, 0x38, 0x46, 0x33, 0x46}
+	// Set CAN id (type 7): new id in bit 16 - 23, host id in bit 8 - 15, two bytes payload
+	actual, err = CustomFrameCmd(COMMUNICATION_SET_CAN_ID, 0x7F, 0x0501, []byte{0xAB, 0x01})
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	if !slices.Equal(expected, f.Serialize()) {
-		t.Fatalf("Frame serialize failed")
+	if actual.Id() != 0x0705017F || !slices.Equal([]byte{0xAB, 0x01}, actual.Data()) {
+		t.Fatalf("Wrong frame. Expected: 0705017F [2] AB 01, Actual: %s", actual)
 	}
+
+	// Motion control (type 1) with the torque in the data area is sent as is, whatever the low byte
+	actual, err = CustomFrameCmd(COMMUNICATION_MOTION_CONTROL_COMMAND, 0x7F, 0x80FF, make([]byte, 8))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if actual.Id() != 0x0180FF7F {
+		t.Fatalf("Wrong frame. Expected: 0180FF7F, Actual: %s", actual)
+	}
+
+	_, err = CustomFrameCmd(COMMUNICATION_GET_STATUS, 0x7F, 0, make([]byte, 9))
+	if err == nil {
+		t.Fatal("Expected error for payload longer than 8 bytes")
+	}
+}
+
+func TestParameterTable(t *testing.T) {
+	p, err := LookupParameter("config_wr_spd_kp")
+	if err != nil || p.Index != 0x2014 || p.Writable || !p.Config() {
+		t.Fatalf("Unexpected lookup: %+v %v", p, err)
+	}
+
+	if _, err := p.Encode(201); err == nil {
+		t.Error("Expected range error for spd_kp 201")
+	}
+
+	p, err = LookupParameter("0x3025")
+	if err != nil || p.Name != "CONFIG_R_DRV_TEMP" {
+		t.Fatalf("Unexpected lookup: %+v %v", p, err)
+	}
+
+	raw, err := p.Encode(-12)
+	if err != nil || raw != 0xFFF4 || p.Decode(raw) != -12 {
+		t.Fatalf("Unexpected int16 encoding: %08X %v", raw, err)
+	}
+
+	if _, err := WriteParameterValueCmd(0x00, 0x7F, p, 1); err == nil {
+		t.Error("Expected error writing a read only parameter")
+	}
+}
+
+func TestReadParameterCmd(t *testing.T) {
+	config, _ := LookupParameter("CONFIG_WR_CAN_TIMEOUT")
+	run, _ := LookupParameter("PARAMETER_LIMIT_SPD")
+
+	if _, err := ReadParameterCmd(0x00, 0x7F, config); err == nil {
+		t.Error("Expected error reading a config area parameter")
+	}
+
+	frame, err := ReadParameterCmd(0x00, 0x7F, run)
+	if err != nil || frame.Id() != 0x1100007F || frame.Uint16(0) != 0x7017 {
+		t.Fatalf("Unexpected run area read frame: %s %v", frame, err)
+	}
+
+	frame, err = WriteParameterValueCmd(0x00, 0x7F, run, 1.12)
+	if err != nil || frame.Id() != 0x1200007F || frame.Float32(4) != 1.12 {
+		t.Fatalf("Unexpected write frame: %s %v", frame, err)
+	}
+
+	if _, err := WriteParameterValueCmd(0x00, 0x7F, config, 100); err == nil {
+		t.Error("Expected error writing a config area parameter")
+	}
+}
+
+func TestPersistentCounterpart(t *testing.T) {
+	run, _ := LookupParameter("PARAMETER_SPD_KP")
+	if run.Persistence() != VOLATILE {
+		t.Errorf("Expected %s to be volatile", run.Name)
+	}
+
+	config, ok := PersistentCounterpart(run)
+	if !ok || config.Name != "CONFIG_WR_SPD_KP" || config.Persistence() != PERSISTENT {
+		t.Fatalf("Unexpected counterpart: %+v", config)
+	}
+
+	ref, _ := LookupParameter("PARAMETER_LOC_REF")
+	if _, ok := PersistentCounterpart(ref); ok {
+		t.Errorf("%s has no persistent counterpart", ref.Name)
+	}
+}
+
+func TestMotionControlCmd(t *testing.T) {
+	// Zero angle, speed and torque sit in the middle of their ranges
+	frame, err := MotionControlCmd(0x7F, 0, 0, 500, 5, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedData := []byte{0x80, 0x00, 0x80, 0x00, 0xFF, 0xFF, 0xFF, 0xFF}
+	if frame.Id() != 0x0180007F || !slices.Equal(expectedData, frame.Data()) {
+		t.Fatalf("Unexpected frame: %s", frame)
+	}
+
+	if _, err := MotionControlCmd(0x7F, 0, 31, 1, 1, 0); err == nil {
+		t.Error("Expected error for speed 31 rad/s")
+	}
+}
+
+// Every value Encode accepts decodes to the value the motor stores: the value itself for integers, the nearest
+// float32 for floats
+func FuzzParameterValue(f *testing.F) {
+	f.Add(uint16(0x2014), 1.5)
+	f.Add(uint16(0x3025), -12.0)
+	f.Add(uint16(0x7005), 2.0)
+	f.Add(uint16(0x200A), 4294967295.0)
+	f.Add(uint16(0x7016), math.NaN())
+	f.Add(uint16(0x7016), 1e300)
+	f.Fuzz(func(t *testing.T, index uint16, value float64) {
+		p, ok := ParameterByIndex(index)
+		if !ok {
+			p = parameterTable[int(index)%len(parameterTable)]
+		}
+
+		raw, err := p.Encode(value)
+		if err != nil {
+			return
+		}
+
+		expected := value
+		if p.Type == PARAMETER_TYPE_FLOAT {
+			expected = float64(float32(value))
+		}
+		if decoded := p.Decode(raw); decoded != expected || math.IsInf(decoded, 0) {
+			t.Fatalf("%s (%s): %g encoded as %08X, decoded as %g", p.Name, p.Type, value, raw, decoded)
+		}
+	})
 }
@@ -0,0 +1,123 @@
+// JSON equivalents of the ROS 2 sensor_msgs/JointState and trajectory_msgs/JointTrajectory messages, with the same
+// field names, so that a thin ROS node can forward them to and from gocg
+package joints
+
+import (
+	"fmt"
+	"gocg/control"
+	"math"
+	"time"
+)
+
+// builtin_interfaces/Time
+type Time struct {
+	Sec     int32  `json:"sec"`
+	Nanosec uint32 `json:"nanosec"`
+}
+
+func NewTime(t time.Time) Time {
+	return Time{Sec: int32(t.Unix()), Nanosec: uint32(t.Nanosecond())}
+}
+
+// builtin_interfaces/Duration
+type Duration struct {
+	Sec     int32  `json:"sec"`
+	Nanosec uint32 `json:"nanosec"`
+}
+
+func (d Duration) Seconds() float64 {
+	return float64(d.Sec) + float64(d.Nanosec)/1e9
+}
+
+// std_msgs/Header
+type Header struct {
+	Stamp   Time   `json:"stamp"`
+	FrameId string `json:"frame_id"`
+}
+
+// sensor_msgs/JointState: position (rad), velocity (rad/s) and effort (Nm) per joint
+type JointState struct {
+	Header   Header    `json:"header"`
+	Name     []string  `json:"name"`
+	Position []float64 `json:"position"`
+	Velocity []float64 `json:"velocity"`
+	Effort   []float64 `json:"effort"`
+}
+
+// trajectory_msgs/JointTrajectoryPoint
+type JointTrajectoryPoint struct {
+	Positions     []float64 `json:"positions"`
+	Velocities    []float64 `json:"velocities,omitempty"`
+	Accelerations []float64 `json:"accelerations,omitempty"`
+	Effort        []float64 `json:"effort,omitempty"`
+	TimeFromStart Duration  `json:"time_from_start"`
+}
+
+// trajectory_msgs/JointTrajectory
+type JointTrajectory struct {
+	Header     Header                 `json:"header"`
+	JointNames []string               `json:"joint_names"`
+	Points     []JointTrajectoryPoint `json:"points"`
+}
+
+// Max distance of a point at time 0 from the start position, further away the joint would have to jump
+const START_TOLERANCE = 0.01 // rad
+
+// Paths of the joints through the positions of the points, starting at the given positions. A cubic spline passes
+// through the points at their time_from_start, velocities and accelerations of the points aren't used. A first point
+// at time 0 has to be at the start positions, a single one holds them. Returns the paths by joint name and the
+// duration (s).
+func (t *JointTrajectory) Plan(start map[string]float64) (map[string]control.Interpolator, float64, error) {
+	if len(t.Points) == 0 {
+		return nil, 0, fmt.Errorf("trajectory without points")
+	}
+
+	times := []float64{0}
+	values := make([][]float64, len(t.JointNames))
+	for i, name := range t.JointNames {
+		position, ok := start[name]
+		if !ok {
+			return nil, 0, fmt.Errorf("unknown joint '%s'", name)
+		}
+		values[i] = []float64{position}
+	}
+
+	for n, point := range t.Points {
+		if len(point.Positions) != len(t.JointNames) {
+			return nil, 0, fmt.Errorf("point %d has %d positions for %d joints", n, len(point.Positions), len(t.JointNames))
+		}
+
+		if n == 0 && point.TimeFromStart.Seconds() == 0 {
+			for i, name := range t.JointNames {
+				if math.Abs(point.Positions[i]-values[i][0]) > START_TOLERANCE {
+					return nil, 0, fmt.Errorf("point 0 at time 0 is %.3f rad away from joint '%s', give it a time_from_start",
+						point.Positions[i]-values[i][0], name)
+				}
+			}
+			continue
+		}
+
+		times = append(times, point.TimeFromStart.Seconds())
+		for i := range t.JointNames {
+			values[i] = append(values[i], point.Positions[i])
+		}
+	}
+
+	// Only the start: hold it
+	if len(times) == 1 {
+		times = append(times, 1)
+		for i := range values {
+			values[i] = append(values[i], values[i][0])
+		}
+	}
+
+	paths := map[string]control.Interpolator{}
+	for i, name := range t.JointNames {
+		path, err := control.NewCubicSpline(times, values[i])
+		if err != nil {
+			return nil, 0, err
+		}
+		paths[name] = path
+	}
+	return paths, times[len(times)-1], nil
+}
@@ -0,0 +1,72 @@
+package joints
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestPlan(t *testing.T) {
+	var trajectory JointTrajectory
+	err := json.Unmarshal([]byte(`{
+		"joint_names": ["elbow", "shoulder"],
+		"points": [
+			{"positions": [1, 0.5], "time_from_start": {"sec": 1}},
+			{"positions": [2, 0], "time_from_start": {"sec": 2, "nanosec": 500000000}}
+		]
+	}`), &trajectory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	paths, duration, err := trajectory.Plan(map[string]float64{"elbow": 0, "shoulder": 0, "wrist": 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if duration != 2.5 || len(paths) != 2 {
+		t.Fatalf("Unexpected plan: %d paths, %g s", len(paths), duration)
+	}
+
+	for _, c := range []struct {
+		joint    string
+		t        float64
+		expected float64
+	}{{"elbow", 0, 0}, {"elbow", 1, 1}, {"elbow", 2.5, 2}, {"elbow", 3, 2}, {"shoulder", 1, 0.5}, {"shoulder", 2.5, 0}} {
+		position, _ := paths[c.joint].At(c.t)
+		if math.Abs(position-c.expected) > 1e-9 {
+			t.Errorf("%s at %g s: expected %g, got %g", c.joint, c.t, c.expected, position)
+		}
+	}
+}
+
+// A single point at time 0 at the start position holds it
+func TestPlanHold(t *testing.T) {
+	trajectory := JointTrajectory{JointNames: []string{"elbow"}, Points: []JointTrajectoryPoint{{Positions: []float64{0.505}}}}
+	paths, _, err := trajectory.Plan(map[string]float64{"elbow": 0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, at := range []float64{0, 0.5, 2} {
+		position, velocity := paths["elbow"].At(at)
+		if position != 0.5 || velocity != 0 {
+			t.Errorf("At %g s: position %g, velocity %g", at, position, velocity)
+		}
+	}
+}
+
+func TestPlanErrors(t *testing.T) {
+	start := map[string]float64{"elbow": 0}
+
+	for name, trajectory := range map[string]JointTrajectory{
+		"no points":      {JointNames: []string{"elbow"}},
+		"unknown joint":  {JointNames: []string{"wrist"}, Points: []JointTrajectoryPoint{{Positions: []float64{1}, TimeFromStart: Duration{Sec: 1}}}},
+		"positions":      {JointNames: []string{"elbow"}, Points: []JointTrajectoryPoint{{Positions: []float64{1, 2}, TimeFromStart: Duration{Sec: 1}}}},
+		"jump":           {JointNames: []string{"elbow"}, Points: []JointTrajectoryPoint{{Positions: []float64{1}}}},
+		"time goes back": {JointNames: []string{"elbow"}, Points: []JointTrajectoryPoint{{Positions: []float64{1}, TimeFromStart: Duration{Sec: 2}}, {Positions: []float64{2}, TimeFromStart: Duration{Sec: 1}}}},
+	} {
+		_, _, err := trajectory.Plan(start)
+		if err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
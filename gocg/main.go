@@ -1,16 +1,41 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"gocg/commands"
 	"gocg/parameters"
+	"io/fs"
 	"log"
 	"strings"
+	"time"
 
 	"github.com/borud/chatui"
 )
 
 func main() {
+	configFile := flag.String("config", parameters.DEFAULT_CONFIG_FILE, "configuration file with adapter and motor profiles")
+	profile := flag.String("profile", "", "profile to use (defaults to the configuration file's default profile)")
+	serve := flag.String("serve", "", "run without the console: open the adapter of the profile and serve the API on this address")
+	grpcAddress := flag.String("grpc", "", "run without the console: open the adapter of the profile and serve gRPC on this address")
+	broker := flag.String("mqtt", "", "run without the console: open the adapter of the profile and bridge the motors to this MQTT broker")
+	jointsAddress := flag.String("joints", "", "run without the console: open the adapter of the profile and run the joint bridge on this address")
+	flag.Parse()
+
+	err := parameters.Init(*configFile, *profile)
+	if err != nil {
+		// A missing default configuration file is fine, everything else isn't
+		if !errors.Is(err, fs.ErrNotExist) || *configFile != parameters.DEFAULT_CONFIG_FILE {
+			log.Fatal(err)
+		}
+	}
+
+	if *serve != "" || *grpcAddress != "" || *broker != "" || *jointsAddress != "" {
+		runHeadless(*serve, *grpcAddress, *broker, *jointsAddress)
+		return
+	}
+
 	outputCh := make(chan string, 10)
 	commandCh := make(chan string)
 
@@ -22,21 +47,34 @@ func main() {
 		HistorySize:  10,
 	})
 
+	profileName, activeProfile := parameters.ActiveProfile()
+
 	outputCh <- "CyberGear playground. The current settings are:"
+	outputCh <- fmt.Sprintf("Profile        : %s", profileName)
 	outputCh <- fmt.Sprintf("Host  CAN id is : 0x%02X", parameters.HostId)
+	outputCh <- fmt.Sprintf("CAN bitrate    : %d bit/s", activeProfile.Bitrate)
 	outputCh <- "Frame format: SLCAN"
 	outputCh <- "When in doubt: Type 'help' for - wait for it - help."
 
 	go func() {
-		for command := range commandCh {
-			if strings.ToLower(command) == "/quit" {
-				chatui.Stop()
-			}
-			err := commands.Dispatch(command, outputCh)
-			if err != nil {
-				outputCh <- err.Error()
+		// Status bar: bus monitor panel, refreshed between commands
+		ticker := time.NewTicker(commands.STATUS_POLL_INTERVAL)
+		hint := "type /quit to exit"
+
+		for {
+			select {
+			case command := <-commandCh:
+				if strings.ToLower(command) == "/quit" {
+					chatui.Stop()
+				}
+				err := commands.Dispatch(command, outputCh)
+				if err != nil {
+					outputCh <- err.Error()
+				}
+				hint = "last command was: " + command
+			case <-ticker.C:
 			}
-			chatui.SetStatus("last command was: " + command)
+			chatui.SetStatus(commands.UpdateStatus(outputCh) + " | " + hint)
 		}
 	}()
 
@@ -45,8 +83,41 @@ func main() {
 		chatui.SetStatus("type /quit to exit")
 	}()
 
-	err := chatui.Run()
+	err = chatui.Run()
 	if err != nil {
 		log.Fatal(err)
 	}
 }
+
+// Serves the REST API, gRPC, the MQTT bridge and/or the joint bridge without the console, logging the command output
+func runHeadless(serveAddress string, grpcAddress string, broker string, jointsAddress string) {
+	outputCh := make(chan string, 10)
+	go func() {
+		for line := range outputCh {
+			log.Print(line)
+		}
+	}()
+
+	commandList := []string{"open"}
+	if serveAddress != "" {
+		commandList = append(commandList, "serve "+serveAddress)
+	}
+	if grpcAddress != "" {
+		commandList = append(commandList, "grpc "+grpcAddress)
+	}
+	if broker != "" {
+		commandList = append(commandList, "mqtt start "+broker)
+	}
+	if jointsAddress != "" {
+		commandList = append(commandList, "joints start "+jointsAddress)
+	}
+
+	for _, command := range commandList {
+		err := commands.Dispatch(command, outputCh)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	select {}
+}
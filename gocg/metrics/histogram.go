@@ -0,0 +1,118 @@
+package metrics
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Upper bounds of the histogram buckets. Values above the last bound go to an overflow bucket.
+var DURATION_BUCKETS = []time.Duration{
+	250 * time.Microsecond,
+	500 * time.Microsecond,
+	1 * time.Millisecond,
+	2 * time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	20 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	200 * time.Millisecond,
+	500 * time.Millisecond,
+	1 * time.Second,
+}
+
+// Histogram of durations with fixed buckets. Safe for concurrent use.
+type Histogram struct {
+	mutex  sync.Mutex
+	counts []uint64 // One per bucket plus overflow
+	count  uint64
+	sum    float64 // Seconds
+	sumSq  float64
+	min    time.Duration
+	max    time.Duration
+}
+
+type Bucket struct {
+	UpperBound float64 `json:"upperBound" yaml:"upperBound"` // Seconds
+	Count      uint64  `json:"count" yaml:"count"`           // Not cumulative
+}
+
+// Copy of a histogram at one point in time. Durations in seconds.
+type HistogramSnapshot struct {
+	Count    uint64   `json:"count" yaml:"count"`
+	Sum      float64  `json:"sum" yaml:"sum"`
+	Min      float64  `json:"min" yaml:"min"`
+	Max      float64  `json:"max" yaml:"max"`
+	Mean     float64  `json:"mean" yaml:"mean"`
+	StdDev   float64  `json:"stdDev" yaml:"stdDev"` // Jitter
+	Buckets  []Bucket `json:"buckets" yaml:"buckets"`
+	Overflow uint64   `json:"overflow" yaml:"overflow"` // Count above the last bucket
+}
+
+func NewHistogram() *Histogram {
+	return &Histogram{counts: make([]uint64, len(DURATION_BUCKETS)+1)}
+}
+
+func (h *Histogram) Observe(d time.Duration) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	i := 0
+	for i < len(DURATION_BUCKETS) && d > DURATION_BUCKETS[i] {
+		i++
+	}
+	h.counts[i]++
+
+	if h.count == 0 || d < h.min {
+		h.min = d
+	}
+	if d > h.max {
+		h.max = d
+	}
+	h.count++
+	h.sum += d.Seconds()
+	h.sumSq += d.Seconds() * d.Seconds()
+}
+
+func (h *Histogram) Reset() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.counts = make([]uint64, len(DURATION_BUCKETS)+1)
+	h.count, h.sum, h.sumSq, h.min, h.max = 0, 0, 0, 0, 0
+}
+
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	s := HistogramSnapshot{Count: h.count, Sum: h.sum, Min: h.min.Seconds(), Max: h.max.Seconds()}
+	if h.count > 0 {
+		s.Mean = h.sum / float64(h.count)
+		s.StdDev = math.Sqrt(math.Max(0, h.sumSq/float64(h.count)-s.Mean*s.Mean))
+	}
+
+	for i, bound := range DURATION_BUCKETS {
+		s.Buckets = append(s.Buckets, Bucket{UpperBound: bound.Seconds(), Count: h.counts[i]})
+	}
+	s.Overflow = h.counts[len(DURATION_BUCKETS)]
+	return s
+}
+
+// Upper bound of the bucket holding the given quantile (0 - 1), at most the max
+func (s HistogramSnapshot) Quantile(q float64) float64 {
+	if s.Count == 0 {
+		return 0
+	}
+
+	rank := uint64(math.Ceil(q * float64(s.Count)))
+	var seen uint64
+	for _, b := range s.Buckets {
+		seen += b.Count
+		if seen >= rank && b.Count > 0 {
+			return math.Min(b.UpperBound, s.Max)
+		}
+	}
+	return s.Max
+}
@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestHistogram(t *testing.T) {
+	h := NewHistogram()
+	for _, d := range []time.Duration{time.Millisecond, 3 * time.Millisecond, 3 * time.Millisecond, 4 * time.Millisecond, 2 * time.Second} {
+		h.Observe(d)
+	}
+
+	s := h.Snapshot()
+	if s.Count != 5 || s.Min != 0.001 || s.Max != 2 || math.Abs(s.Mean-0.4022) > 1e-9 {
+		t.Errorf("Unexpected snapshot: %+v", s)
+	}
+
+	// 1 ms is in the <= 1 ms bucket, 3 and 4 ms in <= 5 ms, 2 s overflows
+	if s.Buckets[2].Count != 1 || s.Buckets[4].Count != 3 || s.Overflow != 1 {
+		t.Errorf("Unexpected buckets: %+v, overflow %d", s.Buckets, s.Overflow)
+	}
+
+	if q := s.Quantile(0.5); q != 0.005 {
+		t.Errorf("Unexpected median bucket: %g", q)
+	}
+	if q := s.Quantile(1); q != 2 {
+		t.Errorf("Unexpected max quantile: %g", q)
+	}
+
+	h.Reset()
+	if s := h.Snapshot(); s.Count != 0 || s.Max != 0 {
+		t.Errorf("Not reset: %+v", s)
+	}
+}
+
+func TestTransport(t *testing.T) {
+	tr := NewTransport()
+	start := tr.Request()
+	tr.FrameSent(131)
+	tr.FrameReceived(131)
+	tr.Answered(start)
+	tr.Request()
+	tr.FrameSent(67)
+	tr.Unanswered()
+	tr.Dropped(2)
+
+	s := tr.Snapshot()
+	if s.Requests != 2 || s.FramesSent != 2 || s.FramesReceived != 1 || s.Bits != 329 || s.Unanswered != 1 || s.Dropped != 2 ||
+		s.Latency.Count != 1 || s.Period.Count != 1 {
+		t.Errorf("Unexpected snapshot: %+v", s)
+	}
+
+	if _, ok := tr.StatusFlags(); ok {
+		t.Errorf("Status flags known before they were set")
+	}
+	tr.SetStatusFlags(0x28)
+	if flags, ok := tr.StatusFlags(); !ok || flags != 0x28 {
+		t.Errorf("Unexpected status flags %02X", flags)
+	}
+}
@@ -0,0 +1,92 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+)
+
+// Last known state of a motor, from the feedback frames and parameter replies seen on the bus
+type MotorState struct {
+	Angle       float64 // rad
+	Speed       float64 // rad/s
+	Torque      float64 // Nm
+	Temperature float64 // C
+	Mode        int
+	Updated     time.Time         // Last feedback
+	Feedback    uint64            // Feedback frames received
+	Faults      map[string]uint64 // Times each fault was raised
+	Parameters  map[string]float64
+	Active      []string // Faults in the last feedback
+}
+
+// Motor states by CAN id. Safe for concurrent use.
+type MotorCache struct {
+	mutex  sync.Mutex
+	motors map[byte]*MotorState
+}
+
+var Motors = NewMotorCache()
+
+func NewMotorCache() *MotorCache {
+	return &MotorCache{motors: map[byte]*MotorState{}}
+}
+
+func (c *MotorCache) motor(motorId byte) *MotorState {
+	m, ok := c.motors[motorId]
+	if !ok {
+		m = &MotorState{Faults: map[string]uint64{}, Parameters: map[string]float64{}}
+		c.motors[motorId] = m
+	}
+	return m
+}
+
+// Records a feedback frame. A fault is counted when it's raised, not for every frame reporting it.
+func (c *MotorCache) Feedback(motorId byte, angle float64, speed float64, torque float64, temperature float64, mode int, faults []string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	m := c.motor(motorId)
+	m.Angle, m.Speed, m.Torque, m.Temperature, m.Mode = angle, speed, torque, temperature, mode
+	m.Updated = time.Now()
+	m.Feedback++
+
+	for _, fault := range faults {
+		raised := true
+		for _, active := range m.Active {
+			raised = raised && active != fault
+		}
+		if raised {
+			m.Faults[fault]++
+		}
+	}
+	m.Active = append([]string{}, faults...)
+}
+
+func (c *MotorCache) Parameter(motorId byte, name string, value float64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.motor(motorId).Parameters[name] = value
+}
+
+// Copy of the motor states
+func (c *MotorCache) Snapshot() map[byte]MotorState {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	motors := map[byte]MotorState{}
+	for motorId, m := range c.motors {
+		s := *m
+		s.Faults = map[string]uint64{}
+		for fault, count := range m.Faults {
+			s.Faults[fault] = count
+		}
+		s.Parameters = map[string]float64{}
+		for name, value := range m.Parameters {
+			s.Parameters[name] = value
+		}
+		s.Active = append([]string{}, m.Active...)
+		motors[motorId] = s
+	}
+	return motors
+}
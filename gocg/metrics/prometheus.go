@@ -0,0 +1,148 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Writes the transport statistics and motor states in the Prometheus text format. Motors are labelled by CAN id
+// and by the name motorName returns ("" if unnamed).
+func WritePrometheus(w io.Writer, t *Transport, c *MotorCache, motorName func(byte) string) error {
+	p := &promWriter{w: w}
+	s := t.Snapshot()
+
+	p.metric("gocg_frames_sent_total", "counter", "CAN frames sent.", "", float64(s.FramesSent))
+	p.metric("gocg_frames_received_total", "counter", "CAN frames received.", "", float64(s.FramesReceived))
+	p.metric("gocg_bus_bits_total", "counter", "CAN bits of the frames sent and received, without stuff bits.", "", float64(s.Bits))
+	p.metric("gocg_requests_total", "counter", "Requests sent to motors.", "", float64(s.Requests))
+	p.metric("gocg_requests_unanswered_total", "counter", "Requests without a reply.", "", float64(s.Unanswered))
+	p.metric("gocg_frames_dropped_total", "counter", "Frames flushed before a request.", "", float64(s.Dropped))
+	p.metric("gocg_serial_overruns_total", "counter", "Frame lines that lost characters on the serial line.", "", float64(s.Overruns))
+	p.metric("gocg_decode_failures_total", "counter", "Received frames that couldn't be decoded.", "", float64(s.DecodeFailures))
+	if flags, ok := t.StatusFlags(); ok {
+		p.metric("gocg_adapter_status_flags", "gauge", "SLCAN status flags (F command).", "", float64(flags))
+	}
+	p.histogram("gocg_request_latency_seconds", "Time from request sent to reply received.", s.Latency)
+	p.histogram("gocg_request_period_seconds", "Time between the starts of consecutive requests.", s.Period)
+
+	motors := c.Snapshot()
+	ids := make([]int, 0, len(motors))
+	for motorId := range motors {
+		ids = append(ids, int(motorId))
+	}
+	sort.Ints(ids)
+
+	gauges := []struct {
+		name  string
+		help  string
+		value func(m MotorState) float64
+	}{
+		{"gocg_motor_angle_radians", "Angle from the last feedback frame.", func(m MotorState) float64 { return m.Angle }},
+		{"gocg_motor_speed_radians_per_second", "Speed from the last feedback frame.", func(m MotorState) float64 { return m.Speed }},
+		{"gocg_motor_torque_newton_meters", "Torque from the last feedback frame.", func(m MotorState) float64 { return m.Torque }},
+		{"gocg_motor_temperature_celsius", "Temperature from the last feedback frame.", func(m MotorState) float64 { return m.Temperature }},
+		{"gocg_motor_mode", "Mode from the last feedback frame (0 reset, 1 calibration, 2 run).", func(m MotorState) float64 { return float64(m.Mode) }},
+		{"gocg_motor_last_feedback_timestamp_seconds", "Unix time of the last feedback frame.", func(m MotorState) float64 {
+			return float64(m.Updated.UnixNano()) / 1e9
+		}},
+	}
+	for _, g := range gauges {
+		p.header(g.name, "gauge", g.help)
+		for _, id := range ids {
+			if m := motors[byte(id)]; m.Feedback > 0 {
+				p.sample(g.name, motorLabels(byte(id), motorName), g.value(m))
+			}
+		}
+	}
+
+	p.header("gocg_motor_feedback_total", "counter", "Feedback frames received.")
+	for _, id := range ids {
+		p.sample("gocg_motor_feedback_total", motorLabels(byte(id), motorName), float64(motors[byte(id)].Feedback))
+	}
+
+	p.header("gocg_motor_faults_total", "counter", "Times a fault was raised in the feedback frames.")
+	for _, id := range ids {
+		for _, fault := range sortedKeys(motors[byte(id)].Faults) {
+			p.sample("gocg_motor_faults_total", motorLabels(byte(id), motorName)+`,fault="`+escape(fault)+`"`, float64(motors[byte(id)].Faults[fault]))
+		}
+	}
+
+	p.header("gocg_motor_parameter", "gauge", "Last value read of a motor parameter, unscaled.")
+	for _, id := range ids {
+		for _, name := range sortedKeys(motors[byte(id)].Parameters) {
+			p.sample("gocg_motor_parameter", motorLabels(byte(id), motorName)+`,parameter="`+escape(name)+`"`, motors[byte(id)].Parameters[name])
+		}
+	}
+
+	return p.err
+}
+
+// Serves WritePrometheus
+func Handler(t *Transport, c *MotorCache, motorName func(byte) string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		WritePrometheus(w, t, c, motorName)
+	})
+}
+
+type promWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (p *promWriter) printf(format string, args ...interface{}) {
+	if p.err == nil {
+		_, p.err = fmt.Fprintf(p.w, format, args...)
+	}
+}
+
+func (p *promWriter) header(name string, kind string, help string) {
+	p.printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func (p *promWriter) sample(name string, labels string, value float64) {
+	if labels != "" {
+		labels = "{" + labels + "}"
+	}
+	p.printf("%s%s %s\n", name, labels, strconv.FormatFloat(value, 'g', -1, 64))
+}
+
+func (p *promWriter) metric(name string, kind string, help string, labels string, value float64) {
+	p.header(name, kind, help)
+	p.sample(name, labels, value)
+}
+
+// Cumulative buckets as Prometheus expects them
+func (p *promWriter) histogram(name string, help string, h HistogramSnapshot) {
+	p.header(name, "histogram", help)
+
+	var cumulative uint64
+	for _, b := range h.Buckets {
+		cumulative += b.Count
+		p.sample(name+"_bucket", `le="`+strconv.FormatFloat(b.UpperBound, 'g', -1, 64)+`"`, float64(cumulative))
+	}
+	p.sample(name+"_bucket", `le="+Inf"`, float64(h.Count))
+	p.sample(name+"_sum", "", h.Sum)
+	p.sample(name+"_count", "", float64(h.Count))
+}
+
+func motorLabels(motorId byte, motorName func(byte) string) string {
+	return fmt.Sprintf(`motor_id="%02X",motor_name="%s"`, motorId, escape(motorName(motorId)))
+}
+
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrometheusScrape(t *testing.T) {
+	tr := NewTransport()
+	start := tr.Request()
+	tr.FrameSent(131)
+	tr.FrameReceived(131)
+	tr.Answered(start)
+
+	c := NewMotorCache()
+	c.Feedback(0x7F, 1.5, 0, 0.25, 27.2, 2, nil)
+	c.Feedback(0x7F, 1.5, 0, 0.25, 27.2, 2, []string{"overtemperature"})
+	c.Feedback(0x7F, 1.5, 0, 0.25, 27.2, 2, []string{"overtemperature"})
+	c.Parameter(0x7F, "CONFIG_R_VBUS_V", 24.1)
+
+	server := httptest.NewServer(Handler(tr, c, func(motorId byte) string { return "shoulder" }))
+	defer server.Close()
+
+	response, err := server.Client().Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+
+	if !strings.HasPrefix(response.Header.Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Errorf("Unexpected content type '%s'", response.Header.Get("Content-Type"))
+	}
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, line := range []string{
+		"# TYPE gocg_frames_sent_total counter",
+		"gocg_frames_sent_total 1",
+		"gocg_bus_bits_total 262",
+		`gocg_request_latency_seconds_bucket{le="+Inf"} 1`,
+		"gocg_request_latency_seconds_count 1",
+		`gocg_motor_angle_radians{motor_id="7F",motor_name="shoulder"} 1.5`,
+		`gocg_motor_temperature_celsius{motor_id="7F",motor_name="shoulder"} 27.2`,
+		`gocg_motor_feedback_total{motor_id="7F",motor_name="shoulder"} 3`,
+		`gocg_motor_faults_total{motor_id="7F",motor_name="shoulder",fault="overtemperature"} 1`,
+		`gocg_motor_parameter{motor_id="7F",motor_name="shoulder",parameter="CONFIG_R_VBUS_V"} 24.1`,
+	} {
+		if !strings.Contains(string(body), line+"\n") {
+			t.Errorf("Missing '%s' in:\n%s", line, body)
+		}
+	}
+
+	if s := c.Snapshot()[0x7F]; time.Since(s.Updated) > time.Second {
+		t.Errorf("Feedback time not updated: %s", s.Updated)
+	}
+}
@@ -0,0 +1,138 @@
+package metrics
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Counters and timing of the requests to the motors
+type Transport struct {
+	framesSent     atomic.Uint64
+	framesReceived atomic.Uint64
+	requests       atomic.Uint64
+	unanswered     atomic.Uint64 // Requests without a reply within the read timeout
+	dropped        atomic.Uint64 // Frames flushed or not matched while waiting for a reply
+	overruns       atomic.Uint64 // Frame lines that lost characters on the serial line
+	decodeFailures atomic.Uint64 // Received frames HandleIncomingFrame doesn't understand
+	bits           atomic.Uint64 // CAN bits of the frames sent and received
+	statusFlags    atomic.Int32  // Last SLCAN status flags, -1 if unknown
+	started        atomic.Int64  // Unix ns of the last reset
+	lastRequest    atomic.Int64  // Unix ns
+
+	Latency *Histogram // Request sent to reply received
+	Period  *Histogram // Between the starts of consecutive requests
+}
+
+// Copy of the transport counters. Rates are averages since the last reset.
+type TransportSnapshot struct {
+	Elapsed           float64           `json:"elapsed" yaml:"elapsed"` // Seconds since the last reset
+	FramesSent        uint64            `json:"framesSent" yaml:"framesSent"`
+	FramesReceived    uint64            `json:"framesReceived" yaml:"framesReceived"`
+	SentPerSecond     float64           `json:"sentPerSecond" yaml:"sentPerSecond"`
+	ReceivedPerSecond float64           `json:"receivedPerSecond" yaml:"receivedPerSecond"`
+	Requests          uint64            `json:"requests" yaml:"requests"`
+	Unanswered        uint64            `json:"unanswered" yaml:"unanswered"`
+	Dropped           uint64            `json:"dropped" yaml:"dropped"`
+	Overruns          uint64            `json:"overruns" yaml:"overruns"`
+	DecodeFailures    uint64            `json:"decodeFailures" yaml:"decodeFailures"`
+	Bits              uint64            `json:"bits" yaml:"bits"`
+	Latency           HistogramSnapshot `json:"latency" yaml:"latency"`
+	Period            HistogramSnapshot `json:"period" yaml:"period"`
+}
+
+// Statistics of the bus gocg talks to
+var Bus = NewTransport()
+
+func NewTransport() *Transport {
+	t := &Transport{Latency: NewHistogram(), Period: NewHistogram()}
+	t.started.Store(time.Now().UnixNano())
+	t.statusFlags.Store(-1)
+	return t
+}
+
+// A request was sent. Returns the start time to pass to Answered.
+func (t *Transport) Request() time.Time {
+	now := time.Now()
+	last := t.lastRequest.Swap(now.UnixNano())
+	if last != 0 {
+		t.Period.Observe(now.Sub(time.Unix(0, last)))
+	}
+	t.requests.Add(1)
+	return now
+}
+
+func (t *Transport) Answered(start time.Time) {
+	t.Latency.Observe(time.Since(start))
+}
+
+func (t *Transport) Unanswered() {
+	t.unanswered.Add(1)
+}
+
+// A frame of the given size in bits was sent
+func (t *Transport) FrameSent(bits int) {
+	t.framesSent.Add(1)
+	t.bits.Add(uint64(bits))
+}
+
+func (t *Transport) FrameReceived(bits int) {
+	t.framesReceived.Add(1)
+	t.bits.Add(uint64(bits))
+}
+
+func (t *Transport) DecodeFailed() {
+	t.decodeFailures.Add(1)
+}
+
+func (t *Transport) SetStatusFlags(flags byte) {
+	t.statusFlags.Store(int32(flags))
+}
+
+// Last status flags reported by the adapter. False if they were never read or couldn't be read.
+func (t *Transport) StatusFlags() (byte, bool) {
+	flags := t.statusFlags.Load()
+	return byte(flags), flags >= 0
+}
+
+func (t *Transport) ClearStatusFlags() {
+	t.statusFlags.Store(-1)
+}
+
+func (t *Transport) Dropped(frames int) {
+	t.dropped.Add(uint64(frames))
+}
+
+func (t *Transport) Overrun() {
+	t.overruns.Add(1)
+}
+
+func (t *Transport) Reset() {
+	for _, c := range []*atomic.Uint64{&t.framesSent, &t.framesReceived, &t.requests, &t.unanswered, &t.dropped, &t.overruns, &t.decodeFailures, &t.bits} {
+		c.Store(0)
+	}
+	t.lastRequest.Store(0)
+	t.started.Store(time.Now().UnixNano())
+	t.Latency.Reset()
+	t.Period.Reset()
+}
+
+func (t *Transport) Snapshot() TransportSnapshot {
+	s := TransportSnapshot{
+		Elapsed:        time.Since(time.Unix(0, t.started.Load())).Seconds(),
+		FramesSent:     t.framesSent.Load(),
+		FramesReceived: t.framesReceived.Load(),
+		Requests:       t.requests.Load(),
+		Unanswered:     t.unanswered.Load(),
+		Dropped:        t.dropped.Load(),
+		Overruns:       t.overruns.Load(),
+		DecodeFailures: t.decodeFailures.Load(),
+		Bits:           t.bits.Load(),
+		Latency:        t.Latency.Snapshot(),
+		Period:         t.Period.Snapshot(),
+	}
+	if s.Elapsed > 0 {
+		s.SentPerSecond = float64(s.FramesSent) / s.Elapsed
+		s.ReceivedPerSecond = float64(s.FramesReceived) / s.Elapsed
+	}
+	return s
+}
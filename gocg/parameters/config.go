@@ -0,0 +1,164 @@
+package parameters
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// gocg configuration file. Example:
+//
+//	default: bench
+//	profiles:
+//	  bench:
+//	    adapter: /dev/ttyACM0
+//	    transport: slcan
+//	    bitrate: 1000000
+//	    host_id: 0x00
+//	    motors:
+//	      shoulder:
+//	        id: 0x7F
+//	        joint: shoulder_pan_joint
+//	        limits:
+//	          speed: 10
+//	          current: 5
+type Config struct {
+	Default  string             `yaml:"default"`
+	Profiles map[string]Profile `yaml:"profiles"`
+}
+
+// Config file used when no file is given on the command line. It's fine if it doesn't exist.
+const DEFAULT_CONFIG_FILE = "gocg.yaml"
+
+func LoadConfig(path string) (*Config, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &Config{}
+	err = yaml.Unmarshal(buf, config)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %s", path, err)
+	}
+
+	for name := range config.Profiles {
+		profile, err := config.Profile(name)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err)
+		}
+		config.Profiles[name] = profile
+	}
+
+	if config.Default != "" {
+		if _, ok := config.Profiles[config.Default]; !ok {
+			return nil, fmt.Errorf("%s: default profile '%s' is not defined", path, config.Default)
+		}
+	}
+
+	return config, nil
+}
+
+// Returns the named profile with unset fields filled in from DefaultProfile.
+func (c *Config) Profile(name string) (Profile, error) {
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return Profile{}, fmt.Errorf("unknown profile '%s'", name)
+	}
+
+	defaults := DefaultProfile()
+	if profile.Transport == "" {
+		profile.Transport = defaults.Transport
+	}
+	if profile.SerialBaud == 0 {
+		profile.SerialBaud = defaults.SerialBaud
+	}
+	if profile.ReadTimeout == 0 {
+		profile.ReadTimeout = defaults.ReadTimeout
+	}
+	if profile.Bitrate == 0 {
+		profile.Bitrate = defaults.Bitrate
+	}
+	if profile.Motors == nil {
+		profile.Motors = map[string]Motor{}
+	}
+
+	if profile.Transport != "slcan" {
+		return Profile{}, fmt.Errorf("profile '%s': unsupported transport '%s'", name, profile.Transport)
+	}
+	if profile.HostId > 0x7F {
+		return Profile{}, fmt.Errorf("profile '%s': invalid host id 0x%02X", name, profile.HostId)
+	}
+
+	ids := map[byte]string{}
+	joints := map[string]string{}
+	for motorName, motor := range profile.Motors {
+		if motor.Id > 0x7F {
+			return Profile{}, fmt.Errorf("profile '%s': motor '%s' has invalid CAN id 0x%02X", name, motorName, motor.Id)
+		}
+		if other, ok := ids[motor.Id]; ok {
+			return Profile{}, fmt.Errorf("profile '%s': motors '%s' and '%s' share CAN id 0x%02X", name, motorName, other, motor.Id)
+		}
+		ids[motor.Id] = motorName
+
+		joint := motor.Joint
+		if joint == "" {
+			joint = motorName
+		}
+		if other, ok := joints[joint]; ok {
+			return Profile{}, fmt.Errorf("profile '%s': motors '%s' and '%s' share joint '%s'", name, motorName, other, joint)
+		}
+		joints[joint] = motorName
+	}
+
+	return profile, nil
+}
+
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Loaded configuration, nil if gocg runs without a configuration file.
+var LoadedConfig *Config
+
+// Loads the configuration file and activates a profile. An empty profile name selects the file's default profile.
+func Init(path string, profileName string) error {
+	config, err := LoadConfig(path)
+	if err != nil {
+		return err
+	}
+
+	if profileName == "" {
+		profileName = config.Default
+	}
+
+	LoadedConfig = config
+
+	if profileName == "" {
+		return nil
+	}
+
+	return UseProfile(profileName)
+}
+
+// Activates a profile from the loaded configuration file.
+func UseProfile(name string) error {
+	if LoadedConfig == nil {
+		return fmt.Errorf("no configuration file loaded")
+	}
+
+	profile, err := LoadedConfig.Profile(name)
+	if err != nil {
+		return err
+	}
+
+	Use(name, profile)
+	return nil
+}
@@ -0,0 +1,138 @@
+package parameters
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testConfig = `
+default: bench
+profiles:
+  bench:
+    adapter: /dev/ttyACM0
+    bitrate: 500000
+    host_id: 0x01
+    motors:
+      shoulder:
+        id: 0x7F
+        limits:
+          speed: 10
+      elbow:
+        id: 0x10
+`
+
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "gocg.yaml")
+	err := os.WriteFile(path, []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestInitUsesDefaultProfile(t *testing.T) {
+	defer Use("default", DefaultProfile())
+
+	err := Init(writeConfig(t, testConfig), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name, profile := ActiveProfile()
+	if name != "bench" {
+		t.Fatalf("Unexpected profile: %s", name)
+	}
+
+	if HostId != 0x01 {
+		t.Errorf("Unexpected host id: 0x%02X", HostId)
+	}
+
+	if profile.Bitrate != 500000 || profile.SerialBaud != 115200 || profile.ReadTimeout != 100*time.Millisecond {
+		t.Errorf("Defaults not applied: %+v", profile)
+	}
+
+	if MotorLimits(0x7F).Speed != 10 {
+		t.Errorf("Unexpected limits for 0x7F: %+v", MotorLimits(0x7F))
+	}
+}
+
+func TestMotorId(t *testing.T) {
+	defer Use("default", DefaultProfile())
+
+	err := Init(writeConfig(t, testConfig), "bench")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for arg, expected := range map[string]byte{"shoulder": 0x7F, "elbow": 0x10, "7F": 0x7F, "0x05": 0x05, "a": 0x0A} {
+		id, err := MotorId(arg)
+		if err != nil {
+			t.Fatalf("%s: %s", arg, err)
+		}
+		if id != expected {
+			t.Errorf("%s: expected 0x%02X, got 0x%02X", arg, expected, id)
+		}
+	}
+
+	if _, err := MotorId("wrist"); err == nil {
+		t.Errorf("Expected error for unknown motor name")
+	}
+
+	if MotorName(0x10) != "elbow" {
+		t.Errorf("Unexpected name for 0x10: '%s'", MotorName(0x10))
+	}
+}
+
+func TestDuplicateMotorIds(t *testing.T) {
+	_, err := LoadConfig(writeConfig(t, `
+profiles:
+  bench:
+    motors:
+      a:
+        id: 0x01
+      b:
+        id: 0x01
+`))
+	if err == nil {
+		t.Fatal("Expected error for motors sharing a CAN id")
+	}
+}
+
+func TestJoints(t *testing.T) {
+	defer Use("default", DefaultProfile())
+
+	err := Init(writeConfig(t, `
+profiles:
+  arm:
+    motors:
+      shoulder:
+        id: 0x01
+        joint: shoulder_pan_joint
+      elbow:
+        id: 0x02
+`), "arm")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	joints := Joints()
+	if len(joints) != 2 || joints[0] != (Joint{"elbow", 0x02}) || joints[1] != (Joint{"shoulder_pan_joint", 0x01}) {
+		t.Errorf("Unexpected joints: %+v", joints)
+	}
+
+	_, err = LoadConfig(writeConfig(t, `
+profiles:
+  arm:
+    motors:
+      a:
+        id: 0x01
+        joint: b
+      b:
+        id: 0x02
+`))
+	if err == nil {
+		t.Fatal("Expected error for motors sharing a joint name")
+	}
+}
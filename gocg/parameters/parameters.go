@@ -1,3 +1,158 @@
 package parameters
 
-const HostId byte = 0x00
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Host CAN id of the active profile. Defaults to 0x00 when no configuration file is loaded.
+var HostId byte = 0x00
+
+// Per-motor limits. A zero value means "use the limit documented for the CyberGear".
+type Limits struct {
+	Speed   float32 `yaml:"speed"`   // rad/s
+	Current float32 `yaml:"current"` // A
+	Torque  float32 `yaml:"torque"`  // Nm
+}
+
+type Motor struct {
+	Id     byte   `yaml:"id"`
+	Joint  string `yaml:"joint"` // Joint name for the joint state bridge, defaults to the motor name
+	Limits Limits `yaml:"limits"`
+}
+
+type Profile struct {
+	Adapter     string           `yaml:"adapter"`      // Serial port name of the CAN adapter, e.g. /dev/ttyACM0 or COM15
+	Transport   string           `yaml:"transport"`    // Only "slcan" is supported
+	SerialBaud  int              `yaml:"serial_baud"`  // Serial line speed towards the adapter
+	ReadTimeout time.Duration    `yaml:"read_timeout"` // Serial read timeout
+	Bitrate     int              `yaml:"bitrate"`      // CAN bitrate (bit/s)
+	HostId      byte             `yaml:"host_id"`
+	Motors      map[string]Motor `yaml:"motors"`
+}
+
+// The settings gocg used before configuration files were introduced.
+func DefaultProfile() Profile {
+	return Profile{
+		Transport:   "slcan",
+		SerialBaud:  115200,
+		ReadTimeout: 100 * time.Millisecond,
+		Bitrate:     1000000,
+		HostId:      0x00,
+		Motors:      map[string]Motor{},
+	}
+}
+
+// Guards activeProfileName and activeProfile, background loops and bridges look motors up while Use runs
+var activeMutex sync.RWMutex
+var activeProfileName = "default"
+var activeProfile = DefaultProfile()
+
+// Own copy of the profile, so the caller can't change the motors of the active profile behind the lock
+func (p Profile) clone() Profile {
+	motors := make(map[string]Motor, len(p.Motors))
+	for name, motor := range p.Motors {
+		motors[name] = motor
+	}
+	p.Motors = motors
+	return p
+}
+
+// Makes a copy of the profile the active one and updates HostId accordingly.
+func Use(name string, profile Profile) {
+	activeMutex.Lock()
+	defer activeMutex.Unlock()
+	activeProfileName = name
+	activeProfile = profile.clone()
+	HostId = profile.HostId
+}
+
+// Returns a copy of the active profile, changes to it take effect with Use.
+func ActiveProfile() (string, Profile) {
+	activeMutex.RLock()
+	defer activeMutex.RUnlock()
+	return activeProfileName, activeProfile.clone()
+}
+
+// Resolves a motor argument to a CAN id. The argument is either the name of a motor in the
+// active profile (e.g. "shoulder") or a hex CAN id (e.g. "7F").
+func MotorId(arg string) (byte, error) {
+	activeMutex.RLock()
+	defer activeMutex.RUnlock()
+
+	if motor, ok := activeProfile.Motors[arg]; ok {
+		return motor.Id, nil
+	}
+
+	id, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(arg), "0x"), 16, 8)
+	if err != nil {
+		return 0, fmt.Errorf("'%s' is neither a motor name in profile '%s' nor a hex CAN id", arg, activeProfileName)
+	}
+
+	return byte(id), nil
+}
+
+// Returns the name of the motor with the given CAN id, or an empty string if the motor isn't named in the active profile.
+func MotorName(id byte) string {
+	activeMutex.RLock()
+	defer activeMutex.RUnlock()
+
+	for name, motor := range activeProfile.Motors {
+		if motor.Id == id {
+			return name
+		}
+	}
+	return ""
+}
+
+// Returns the configured limits for the motor with the given CAN id.
+func MotorLimits(id byte) Limits {
+	activeMutex.RLock()
+	defer activeMutex.RUnlock()
+
+	for _, motor := range activeProfile.Motors {
+		if motor.Id == id {
+			return motor.Limits
+		}
+	}
+	return Limits{}
+}
+
+// Names of the motors in the active profile, sorted alphabetically.
+func MotorNames() []string {
+	activeMutex.RLock()
+	defer activeMutex.RUnlock()
+
+	names := make([]string, 0, len(activeProfile.Motors))
+	for name := range activeProfile.Motors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// A motor as a joint of the robot
+type Joint struct {
+	Name    string
+	MotorId byte
+}
+
+// Joints of the motors in the active profile, sorted by joint name.
+func Joints() []Joint {
+	activeMutex.RLock()
+	defer activeMutex.RUnlock()
+
+	joints := make([]Joint, 0, len(activeProfile.Motors))
+	for name, motor := range activeProfile.Motors {
+		if motor.Joint != "" {
+			name = motor.Joint
+		}
+		joints = append(joints, Joint{name, motor.Id})
+	}
+	sort.Slice(joints, func(i, j int) bool { return joints[i].Name < joints[j].Name })
+	return joints
+}
@@ -0,0 +1,119 @@
+// Go client of the gocg CyberGear gRPC service
+package rpc
+
+import (
+	"context"
+	"gocg/rpc/pb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Connection to a gocg daemon ('gocg -grpc <address>'). Motors are given by name from the daemon's profile or by
+// hex CAN id.
+type Client struct {
+	conn *grpc.ClientConn
+	pb.CyberGearClient
+}
+
+// Connects without TLS, gocg serves plain gRPC
+func Dial(address string, opts ...grpc.DialOption) (*Client, error) {
+	opts = append([]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, opts...)
+	conn, err := grpc.Dial(address, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{conn: conn, CyberGearClient: pb.NewCyberGearClient(conn)}, nil
+}
+
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+func (c *Client) EnableMotor(ctx context.Context, motor string) (*pb.MotorStatus, error) {
+	return c.Enable(ctx, &pb.MotorRequest{Motor: motor})
+}
+
+func (c *Client) DisableMotor(ctx context.Context, motor string) (*pb.MotorStatus, error) {
+	return c.Disable(ctx, &pb.MotorRequest{Motor: motor})
+}
+
+func (c *Client) Status(ctx context.Context, motor string) (*pb.MotorStatus, error) {
+	return c.GetStatus(ctx, &pb.MotorRequest{Motor: motor})
+}
+
+// Switches to speed mode and sets the speed (rad/s)
+func (c *Client) SetSpeed(ctx context.Context, motor string, speed float64) (*pb.MotorStatus, error) {
+	return c.setInMode(ctx, motor, pb.Mode_MODE_SPEED, speed)
+}
+
+// Switches to position mode and moves to the angle (rad)
+func (c *Client) SetPosition(ctx context.Context, motor string, angle float64) (*pb.MotorStatus, error) {
+	return c.setInMode(ctx, motor, pb.Mode_MODE_POSITION, angle)
+}
+
+// Switches to current mode and sets the current (A)
+func (c *Client) SetCurrent(ctx context.Context, motor string, current float64) (*pb.MotorStatus, error) {
+	return c.setInMode(ctx, motor, pb.Mode_MODE_CURRENT, current)
+}
+
+func (c *Client) setInMode(ctx context.Context, motor string, mode pb.Mode, value float64) (*pb.MotorStatus, error) {
+	_, err := c.SetMode(ctx, &pb.SetModeRequest{Motor: motor, Mode: mode})
+	if err != nil {
+		return nil, err
+	}
+	return c.SetSetpoint(ctx, &pb.SetpointRequest{Motor: motor, Mode: mode, Value: value})
+}
+
+func (c *Client) Read(ctx context.Context, motor string, parameter string) (*pb.Parameter, error) {
+	return c.ReadParameter(ctx, &pb.ParameterRequest{Motor: motor, Parameter: parameter})
+}
+
+func (c *Client) Write(ctx context.Context, motor string, parameter string, value float64) (*pb.Parameter, error) {
+	return c.WriteParameter(ctx, &pb.WriteParameterRequest{Motor: motor, Parameter: parameter, Value: &pb.WriteParameterRequest_Number{Number: value}})
+}
+
+// Calls handle for every feedback frame of the motor ("" for all motors) until ctx is done, the stream fails or
+// handle returns an error
+func (c *Client) Feedback(ctx context.Context, motor string, handle func(*pb.MotorStatus) error) error {
+	stream, err := c.StreamFeedback(ctx, &pb.StreamRequest{Motor: motor})
+	if err != nil {
+		return err
+	}
+
+	for {
+		status, err := stream.Recv()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		err = handle(status)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Calls handle whenever a fault of the motor ("" for all motors) is raised or cleared
+func (c *Client) Faults(ctx context.Context, motor string, handle func(*pb.FaultEvent) error) error {
+	stream, err := c.StreamFaults(ctx, &pb.StreamRequest{Motor: motor})
+	if err != nil {
+		return err
+	}
+
+	for {
+		event, err := stream.Recv()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		err = handle(event)
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,1197 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.30.0
+// 	protoc        (unknown)
+// source: cybergear.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Mode int32
+
+const (
+	Mode_MODE_UNSPECIFIED    Mode = 0
+	Mode_MODE_SPEED          Mode = 1
+	Mode_MODE_POSITION       Mode = 2
+	Mode_MODE_CURRENT        Mode = 3
+	Mode_MODE_MOTION_CONTROL Mode = 4 // Type 1 frames, enabled with zero gains
+)
+
+// Enum value maps for Mode.
+var (
+	Mode_name = map[int32]string{
+		0: "MODE_UNSPECIFIED",
+		1: "MODE_SPEED",
+		2: "MODE_POSITION",
+		3: "MODE_CURRENT",
+		4: "MODE_MOTION_CONTROL",
+	}
+	Mode_value = map[string]int32{
+		"MODE_UNSPECIFIED":    0,
+		"MODE_SPEED":          1,
+		"MODE_POSITION":       2,
+		"MODE_CURRENT":        3,
+		"MODE_MOTION_CONTROL": 4,
+	}
+)
+
+func (x Mode) Enum() *Mode {
+	p := new(Mode)
+	*p = x
+	return p
+}
+
+func (x Mode) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Mode) Descriptor() protoreflect.EnumDescriptor {
+	return file_cybergear_proto_enumTypes[0].Descriptor()
+}
+
+func (Mode) Type() protoreflect.EnumType {
+	return &file_cybergear_proto_enumTypes[0]
+}
+
+func (x Mode) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Mode.Descriptor instead.
+func (Mode) EnumDescriptor() ([]byte, []int) {
+	return file_cybergear_proto_rawDescGZIP(), []int{0}
+}
+
+type MotorRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Motor string `protobuf:"bytes,1,opt,name=motor,proto3" json:"motor,omitempty"` // Name or hex CAN id
+}
+
+func (x *MotorRequest) Reset() {
+	*x = MotorRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cybergear_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *MotorRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MotorRequest) ProtoMessage() {}
+
+func (x *MotorRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cybergear_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MotorRequest.ProtoReflect.Descriptor instead.
+func (*MotorRequest) Descriptor() ([]byte, []int) {
+	return file_cybergear_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *MotorRequest) GetMotor() string {
+	if x != nil {
+		return x.Motor
+	}
+	return ""
+}
+
+type ListMotorsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *ListMotorsRequest) Reset() {
+	*x = ListMotorsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cybergear_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListMotorsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListMotorsRequest) ProtoMessage() {}
+
+func (x *ListMotorsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cybergear_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListMotorsRequest.ProtoReflect.Descriptor instead.
+func (*ListMotorsRequest) Descriptor() ([]byte, []int) {
+	return file_cybergear_proto_rawDescGZIP(), []int{1}
+}
+
+type ListMotorsResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Motors []*MotorStatus `protobuf:"bytes,1,rep,name=motors,proto3" json:"motors,omitempty"`
+}
+
+func (x *ListMotorsResponse) Reset() {
+	*x = ListMotorsResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cybergear_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListMotorsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListMotorsResponse) ProtoMessage() {}
+
+func (x *ListMotorsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_cybergear_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListMotorsResponse.ProtoReflect.Descriptor instead.
+func (*ListMotorsResponse) Descriptor() ([]byte, []int) {
+	return file_cybergear_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ListMotorsResponse) GetMotors() []*MotorStatus {
+	if x != nil {
+		return x.Motors
+	}
+	return nil
+}
+
+type MotorStatus struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	MotorId     string   `protobuf:"bytes,1,opt,name=motor_id,json=motorId,proto3" json:"motor_id,omitempty"` // Hex CAN id
+	Name        string   `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Angle       float64  `protobuf:"fixed64,3,opt,name=angle,proto3" json:"angle,omitempty"`             // rad
+	Speed       float64  `protobuf:"fixed64,4,opt,name=speed,proto3" json:"speed,omitempty"`             // rad/s
+	Torque      float64  `protobuf:"fixed64,5,opt,name=torque,proto3" json:"torque,omitempty"`           // Nm
+	Temperature float64  `protobuf:"fixed64,6,opt,name=temperature,proto3" json:"temperature,omitempty"` // C
+	Mode        string   `protobuf:"bytes,7,opt,name=mode,proto3" json:"mode,omitempty"`                 // reset, calibration or run. Empty if there was no feedback yet
+	Faults      []string `protobuf:"bytes,8,rep,name=faults,proto3" json:"faults,omitempty"`
+}
+
+func (x *MotorStatus) Reset() {
+	*x = MotorStatus{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cybergear_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *MotorStatus) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MotorStatus) ProtoMessage() {}
+
+func (x *MotorStatus) ProtoReflect() protoreflect.Message {
+	mi := &file_cybergear_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MotorStatus.ProtoReflect.Descriptor instead.
+func (*MotorStatus) Descriptor() ([]byte, []int) {
+	return file_cybergear_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *MotorStatus) GetMotorId() string {
+	if x != nil {
+		return x.MotorId
+	}
+	return ""
+}
+
+func (x *MotorStatus) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *MotorStatus) GetAngle() float64 {
+	if x != nil {
+		return x.Angle
+	}
+	return 0
+}
+
+func (x *MotorStatus) GetSpeed() float64 {
+	if x != nil {
+		return x.Speed
+	}
+	return 0
+}
+
+func (x *MotorStatus) GetTorque() float64 {
+	if x != nil {
+		return x.Torque
+	}
+	return 0
+}
+
+func (x *MotorStatus) GetTemperature() float64 {
+	if x != nil {
+		return x.Temperature
+	}
+	return 0
+}
+
+func (x *MotorStatus) GetMode() string {
+	if x != nil {
+		return x.Mode
+	}
+	return ""
+}
+
+func (x *MotorStatus) GetFaults() []string {
+	if x != nil {
+		return x.Faults
+	}
+	return nil
+}
+
+type SetModeRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Motor string `protobuf:"bytes,1,opt,name=motor,proto3" json:"motor,omitempty"`
+	Mode  Mode   `protobuf:"varint,2,opt,name=mode,proto3,enum=gocg.cybergear.Mode" json:"mode,omitempty"`
+}
+
+func (x *SetModeRequest) Reset() {
+	*x = SetModeRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cybergear_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SetModeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetModeRequest) ProtoMessage() {}
+
+func (x *SetModeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cybergear_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetModeRequest.ProtoReflect.Descriptor instead.
+func (*SetModeRequest) Descriptor() ([]byte, []int) {
+	return file_cybergear_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *SetModeRequest) GetMotor() string {
+	if x != nil {
+		return x.Motor
+	}
+	return ""
+}
+
+func (x *SetModeRequest) GetMode() Mode {
+	if x != nil {
+		return x.Mode
+	}
+	return Mode_MODE_UNSPECIFIED
+}
+
+type SetpointRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Motor string  `protobuf:"bytes,1,opt,name=motor,proto3" json:"motor,omitempty"`
+	Mode  Mode    `protobuf:"varint,2,opt,name=mode,proto3,enum=gocg.cybergear.Mode" json:"mode,omitempty"` // Speed (rad/s), position (rad) or current (A)
+	Value float64 `protobuf:"fixed64,3,opt,name=value,proto3" json:"value,omitempty"`
+}
+
+func (x *SetpointRequest) Reset() {
+	*x = SetpointRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cybergear_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SetpointRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetpointRequest) ProtoMessage() {}
+
+func (x *SetpointRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cybergear_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetpointRequest.ProtoReflect.Descriptor instead.
+func (*SetpointRequest) Descriptor() ([]byte, []int) {
+	return file_cybergear_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *SetpointRequest) GetMotor() string {
+	if x != nil {
+		return x.Motor
+	}
+	return ""
+}
+
+func (x *SetpointRequest) GetMode() Mode {
+	if x != nil {
+		return x.Mode
+	}
+	return Mode_MODE_UNSPECIFIED
+}
+
+func (x *SetpointRequest) GetValue() float64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+type ParameterRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Motor     string `protobuf:"bytes,1,opt,name=motor,proto3" json:"motor,omitempty"`
+	Parameter string `protobuf:"bytes,2,opt,name=parameter,proto3" json:"parameter,omitempty"` // Name or hex index
+}
+
+func (x *ParameterRequest) Reset() {
+	*x = ParameterRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cybergear_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ParameterRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ParameterRequest) ProtoMessage() {}
+
+func (x *ParameterRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cybergear_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ParameterRequest.ProtoReflect.Descriptor instead.
+func (*ParameterRequest) Descriptor() ([]byte, []int) {
+	return file_cybergear_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ParameterRequest) GetMotor() string {
+	if x != nil {
+		return x.Motor
+	}
+	return ""
+}
+
+func (x *ParameterRequest) GetParameter() string {
+	if x != nil {
+		return x.Parameter
+	}
+	return ""
+}
+
+type WriteParameterRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Motor     string `protobuf:"bytes,1,opt,name=motor,proto3" json:"motor,omitempty"`
+	Parameter string `protobuf:"bytes,2,opt,name=parameter,proto3" json:"parameter,omitempty"`
+	// Types that are assignable to Value:
+	//	*WriteParameterRequest_Number
+	//	*WriteParameterRequest_Text
+	Value isWriteParameterRequest_Value `protobuf_oneof:"value"`
+}
+
+func (x *WriteParameterRequest) Reset() {
+	*x = WriteParameterRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cybergear_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *WriteParameterRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WriteParameterRequest) ProtoMessage() {}
+
+func (x *WriteParameterRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cybergear_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WriteParameterRequest.ProtoReflect.Descriptor instead.
+func (*WriteParameterRequest) Descriptor() ([]byte, []int) {
+	return file_cybergear_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *WriteParameterRequest) GetMotor() string {
+	if x != nil {
+		return x.Motor
+	}
+	return ""
+}
+
+func (x *WriteParameterRequest) GetParameter() string {
+	if x != nil {
+		return x.Parameter
+	}
+	return ""
+}
+
+func (m *WriteParameterRequest) GetValue() isWriteParameterRequest_Value {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+func (x *WriteParameterRequest) GetNumber() float64 {
+	if x, ok := x.GetValue().(*WriteParameterRequest_Number); ok {
+		return x.Number
+	}
+	return 0
+}
+
+func (x *WriteParameterRequest) GetText() string {
+	if x, ok := x.GetValue().(*WriteParameterRequest_Text); ok {
+		return x.Text
+	}
+	return ""
+}
+
+type isWriteParameterRequest_Value interface {
+	isWriteParameterRequest_Value()
+}
+
+type WriteParameterRequest_Number struct {
+	Number float64 `protobuf:"fixed64,3,opt,name=number,proto3,oneof"`
+}
+
+type WriteParameterRequest_Text struct {
+	Text string `protobuf:"bytes,4,opt,name=text,proto3,oneof"` // Refused, string parameters are read only
+}
+
+func (*WriteParameterRequest_Number) isWriteParameterRequest_Value() {}
+
+func (*WriteParameterRequest_Text) isWriteParameterRequest_Value() {}
+
+type Parameter struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	MotorId     string `protobuf:"bytes,1,opt,name=motor_id,json=motorId,proto3" json:"motor_id,omitempty"`
+	Index       string `protobuf:"bytes,2,opt,name=index,proto3" json:"index,omitempty"`
+	Name        string `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	Type        string `protobuf:"bytes,4,opt,name=type,proto3" json:"type,omitempty"`
+	Writable    bool   `protobuf:"varint,5,opt,name=writable,proto3" json:"writable,omitempty"`
+	Persistence string `protobuf:"bytes,6,opt,name=persistence,proto3" json:"persistence,omitempty"` // volatile or persistent
+	// Types that are assignable to Value:
+	//	*Parameter_Number
+	//	*Parameter_Text
+	Value isParameter_Value `protobuf_oneof:"value"`
+}
+
+func (x *Parameter) Reset() {
+	*x = Parameter{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cybergear_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Parameter) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Parameter) ProtoMessage() {}
+
+func (x *Parameter) ProtoReflect() protoreflect.Message {
+	mi := &file_cybergear_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Parameter.ProtoReflect.Descriptor instead.
+func (*Parameter) Descriptor() ([]byte, []int) {
+	return file_cybergear_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Parameter) GetMotorId() string {
+	if x != nil {
+		return x.MotorId
+	}
+	return ""
+}
+
+func (x *Parameter) GetIndex() string {
+	if x != nil {
+		return x.Index
+	}
+	return ""
+}
+
+func (x *Parameter) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Parameter) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Parameter) GetWritable() bool {
+	if x != nil {
+		return x.Writable
+	}
+	return false
+}
+
+func (x *Parameter) GetPersistence() string {
+	if x != nil {
+		return x.Persistence
+	}
+	return ""
+}
+
+func (m *Parameter) GetValue() isParameter_Value {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+func (x *Parameter) GetNumber() float64 {
+	if x, ok := x.GetValue().(*Parameter_Number); ok {
+		return x.Number
+	}
+	return 0
+}
+
+func (x *Parameter) GetText() string {
+	if x, ok := x.GetValue().(*Parameter_Text); ok {
+		return x.Text
+	}
+	return ""
+}
+
+type isParameter_Value interface {
+	isParameter_Value()
+}
+
+type Parameter_Number struct {
+	Number float64 `protobuf:"fixed64,7,opt,name=number,proto3,oneof"`
+}
+
+type Parameter_Text struct {
+	Text string `protobuf:"bytes,8,opt,name=text,proto3,oneof"` // Not set, string parameters aren't read
+}
+
+func (*Parameter_Number) isParameter_Value() {}
+
+func (*Parameter_Text) isParameter_Value() {}
+
+type StreamRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Motor string `protobuf:"bytes,1,opt,name=motor,proto3" json:"motor,omitempty"` // Empty for all motors
+}
+
+func (x *StreamRequest) Reset() {
+	*x = StreamRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cybergear_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StreamRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamRequest) ProtoMessage() {}
+
+func (x *StreamRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cybergear_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamRequest.ProtoReflect.Descriptor instead.
+func (*StreamRequest) Descriptor() ([]byte, []int) {
+	return file_cybergear_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *StreamRequest) GetMotor() string {
+	if x != nil {
+		return x.Motor
+	}
+	return ""
+}
+
+type FaultEvent struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	MotorId      string   `protobuf:"bytes,1,opt,name=motor_id,json=motorId,proto3" json:"motor_id,omitempty"`
+	TimeUnixNano int64    `protobuf:"varint,2,opt,name=time_unix_nano,json=timeUnixNano,proto3" json:"time_unix_nano,omitempty"`
+	Raised       []string `protobuf:"bytes,3,rep,name=raised,proto3" json:"raised,omitempty"`
+	Cleared      []string `protobuf:"bytes,4,rep,name=cleared,proto3" json:"cleared,omitempty"`
+	Active       []string `protobuf:"bytes,5,rep,name=active,proto3" json:"active,omitempty"`
+}
+
+func (x *FaultEvent) Reset() {
+	*x = FaultEvent{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cybergear_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FaultEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FaultEvent) ProtoMessage() {}
+
+func (x *FaultEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_cybergear_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FaultEvent.ProtoReflect.Descriptor instead.
+func (*FaultEvent) Descriptor() ([]byte, []int) {
+	return file_cybergear_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *FaultEvent) GetMotorId() string {
+	if x != nil {
+		return x.MotorId
+	}
+	return ""
+}
+
+func (x *FaultEvent) GetTimeUnixNano() int64 {
+	if x != nil {
+		return x.TimeUnixNano
+	}
+	return 0
+}
+
+func (x *FaultEvent) GetRaised() []string {
+	if x != nil {
+		return x.Raised
+	}
+	return nil
+}
+
+func (x *FaultEvent) GetCleared() []string {
+	if x != nil {
+		return x.Cleared
+	}
+	return nil
+}
+
+func (x *FaultEvent) GetActive() []string {
+	if x != nil {
+		return x.Active
+	}
+	return nil
+}
+
+var File_cybergear_proto protoreflect.FileDescriptor
+
+var file_cybergear_proto_rawDesc = []byte{
+	0x0a, 0x0f, 0x63, 0x79, 0x62, 0x65, 0x72, 0x67, 0x65, 0x61, 0x72, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x12, 0x0e, 0x67, 0x6f, 0x63, 0x67, 0x2e, 0x63, 0x79, 0x62, 0x65, 0x72, 0x67, 0x65, 0x61,
+	0x72, 0x22, 0x24, 0x0a, 0x0c, 0x4d, 0x6f, 0x74, 0x6f, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x12, 0x14, 0x0a, 0x05, 0x6d, 0x6f, 0x74, 0x6f, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x05, 0x6d, 0x6f, 0x74, 0x6f, 0x72, 0x22, 0x13, 0x0a, 0x11, 0x4c, 0x69, 0x73, 0x74, 0x4d,
+	0x6f, 0x74, 0x6f, 0x72, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x49, 0x0a, 0x12,
+	0x4c, 0x69, 0x73, 0x74, 0x4d, 0x6f, 0x74, 0x6f, 0x72, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x12, 0x33, 0x0a, 0x06, 0x6d, 0x6f, 0x74, 0x6f, 0x72, 0x73, 0x18, 0x01, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x1b, 0x2e, 0x67, 0x6f, 0x63, 0x67, 0x2e, 0x63, 0x79, 0x62, 0x65, 0x72, 0x67,
+	0x65, 0x61, 0x72, 0x2e, 0x4d, 0x6f, 0x74, 0x6f, 0x72, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52,
+	0x06, 0x6d, 0x6f, 0x74, 0x6f, 0x72, 0x73, 0x22, 0xce, 0x01, 0x0a, 0x0b, 0x4d, 0x6f, 0x74, 0x6f,
+	0x72, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x19, 0x0a, 0x08, 0x6d, 0x6f, 0x74, 0x6f, 0x72,
+	0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x6d, 0x6f, 0x74, 0x6f, 0x72,
+	0x49, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x61, 0x6e, 0x67, 0x6c, 0x65, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x01, 0x52, 0x05, 0x61, 0x6e, 0x67, 0x6c, 0x65, 0x12, 0x14, 0x0a, 0x05,
+	0x73, 0x70, 0x65, 0x65, 0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x01, 0x52, 0x05, 0x73, 0x70, 0x65,
+	0x65, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x74, 0x6f, 0x72, 0x71, 0x75, 0x65, 0x18, 0x05, 0x20, 0x01,
+	0x28, 0x01, 0x52, 0x06, 0x74, 0x6f, 0x72, 0x71, 0x75, 0x65, 0x12, 0x20, 0x0a, 0x0b, 0x74, 0x65,
+	0x6d, 0x70, 0x65, 0x72, 0x61, 0x74, 0x75, 0x72, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x01, 0x52,
+	0x0b, 0x74, 0x65, 0x6d, 0x70, 0x65, 0x72, 0x61, 0x74, 0x75, 0x72, 0x65, 0x12, 0x12, 0x0a, 0x04,
+	0x6d, 0x6f, 0x64, 0x65, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6d, 0x6f, 0x64, 0x65,
+	0x12, 0x16, 0x0a, 0x06, 0x66, 0x61, 0x75, 0x6c, 0x74, 0x73, 0x18, 0x08, 0x20, 0x03, 0x28, 0x09,
+	0x52, 0x06, 0x66, 0x61, 0x75, 0x6c, 0x74, 0x73, 0x22, 0x50, 0x0a, 0x0e, 0x53, 0x65, 0x74, 0x4d,
+	0x6f, 0x64, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x6d, 0x6f,
+	0x74, 0x6f, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x6d, 0x6f, 0x74, 0x6f, 0x72,
+	0x12, 0x28, 0x0a, 0x04, 0x6d, 0x6f, 0x64, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x14,
+	0x2e, 0x67, 0x6f, 0x63, 0x67, 0x2e, 0x63, 0x79, 0x62, 0x65, 0x72, 0x67, 0x65, 0x61, 0x72, 0x2e,
+	0x4d, 0x6f, 0x64, 0x65, 0x52, 0x04, 0x6d, 0x6f, 0x64, 0x65, 0x22, 0x67, 0x0a, 0x0f, 0x53, 0x65,
+	0x74, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x14, 0x0a,
+	0x05, 0x6d, 0x6f, 0x74, 0x6f, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x6d, 0x6f,
+	0x74, 0x6f, 0x72, 0x12, 0x28, 0x0a, 0x04, 0x6d, 0x6f, 0x64, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x0e, 0x32, 0x14, 0x2e, 0x67, 0x6f, 0x63, 0x67, 0x2e, 0x63, 0x79, 0x62, 0x65, 0x72, 0x67, 0x65,
+	0x61, 0x72, 0x2e, 0x4d, 0x6f, 0x64, 0x65, 0x52, 0x04, 0x6d, 0x6f, 0x64, 0x65, 0x12, 0x14, 0x0a,
+	0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x01, 0x52, 0x05, 0x76, 0x61,
+	0x6c, 0x75, 0x65, 0x22, 0x46, 0x0a, 0x10, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x65, 0x74, 0x65, 0x72,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x6d, 0x6f, 0x74, 0x6f, 0x72,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x6d, 0x6f, 0x74, 0x6f, 0x72, 0x12, 0x1c, 0x0a,
+	0x09, 0x70, 0x61, 0x72, 0x61, 0x6d, 0x65, 0x74, 0x65, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x09, 0x70, 0x61, 0x72, 0x61, 0x6d, 0x65, 0x74, 0x65, 0x72, 0x22, 0x84, 0x01, 0x0a, 0x15,
+	0x57, 0x72, 0x69, 0x74, 0x65, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x65, 0x74, 0x65, 0x72, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x6d, 0x6f, 0x74, 0x6f, 0x72, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x6d, 0x6f, 0x74, 0x6f, 0x72, 0x12, 0x1c, 0x0a, 0x09, 0x70,
+	0x61, 0x72, 0x61, 0x6d, 0x65, 0x74, 0x65, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09,
+	0x70, 0x61, 0x72, 0x61, 0x6d, 0x65, 0x74, 0x65, 0x72, 0x12, 0x18, 0x0a, 0x06, 0x6e, 0x75, 0x6d,
+	0x62, 0x65, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x01, 0x48, 0x00, 0x52, 0x06, 0x6e, 0x75, 0x6d,
+	0x62, 0x65, 0x72, 0x12, 0x14, 0x0a, 0x04, 0x74, 0x65, 0x78, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28,
+	0x09, 0x48, 0x00, 0x52, 0x04, 0x74, 0x65, 0x78, 0x74, 0x42, 0x07, 0x0a, 0x05, 0x76, 0x61, 0x6c,
+	0x75, 0x65, 0x22, 0xdb, 0x01, 0x0a, 0x09, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x65, 0x74, 0x65, 0x72,
+	0x12, 0x19, 0x0a, 0x08, 0x6d, 0x6f, 0x74, 0x6f, 0x72, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x07, 0x6d, 0x6f, 0x74, 0x6f, 0x72, 0x49, 0x64, 0x12, 0x14, 0x0a, 0x05, 0x69,
+	0x6e, 0x64, 0x65, 0x78, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x69, 0x6e, 0x64, 0x65,
+	0x78, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x04, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x77, 0x72, 0x69,
+	0x74, 0x61, 0x62, 0x6c, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x08, 0x52, 0x08, 0x77, 0x72, 0x69,
+	0x74, 0x61, 0x62, 0x6c, 0x65, 0x12, 0x20, 0x0a, 0x0b, 0x70, 0x65, 0x72, 0x73, 0x69, 0x73, 0x74,
+	0x65, 0x6e, 0x63, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x70, 0x65, 0x72, 0x73,
+	0x69, 0x73, 0x74, 0x65, 0x6e, 0x63, 0x65, 0x12, 0x18, 0x0a, 0x06, 0x6e, 0x75, 0x6d, 0x62, 0x65,
+	0x72, 0x18, 0x07, 0x20, 0x01, 0x28, 0x01, 0x48, 0x00, 0x52, 0x06, 0x6e, 0x75, 0x6d, 0x62, 0x65,
+	0x72, 0x12, 0x14, 0x0a, 0x04, 0x74, 0x65, 0x78, 0x74, 0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x48,
+	0x00, 0x52, 0x04, 0x74, 0x65, 0x78, 0x74, 0x42, 0x07, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65,
+	0x22, 0x25, 0x0a, 0x0d, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x12, 0x14, 0x0a, 0x05, 0x6d, 0x6f, 0x74, 0x6f, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x05, 0x6d, 0x6f, 0x74, 0x6f, 0x72, 0x22, 0x97, 0x01, 0x0a, 0x0a, 0x46, 0x61, 0x75, 0x6c,
+	0x74, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x12, 0x19, 0x0a, 0x08, 0x6d, 0x6f, 0x74, 0x6f, 0x72, 0x5f,
+	0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x6d, 0x6f, 0x74, 0x6f, 0x72, 0x49,
+	0x64, 0x12, 0x24, 0x0a, 0x0e, 0x74, 0x69, 0x6d, 0x65, 0x5f, 0x75, 0x6e, 0x69, 0x78, 0x5f, 0x6e,
+	0x61, 0x6e, 0x6f, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0c, 0x74, 0x69, 0x6d, 0x65, 0x55,
+	0x6e, 0x69, 0x78, 0x4e, 0x61, 0x6e, 0x6f, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x61, 0x69, 0x73, 0x65,
+	0x64, 0x18, 0x03, 0x20, 0x03, 0x28, 0x09, 0x52, 0x06, 0x72, 0x61, 0x69, 0x73, 0x65, 0x64, 0x12,
+	0x18, 0x0a, 0x07, 0x63, 0x6c, 0x65, 0x61, 0x72, 0x65, 0x64, 0x18, 0x04, 0x20, 0x03, 0x28, 0x09,
+	0x52, 0x07, 0x63, 0x6c, 0x65, 0x61, 0x72, 0x65, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x61, 0x63, 0x74,
+	0x69, 0x76, 0x65, 0x18, 0x05, 0x20, 0x03, 0x28, 0x09, 0x52, 0x06, 0x61, 0x63, 0x74, 0x69, 0x76,
+	0x65, 0x2a, 0x6a, 0x0a, 0x04, 0x4d, 0x6f, 0x64, 0x65, 0x12, 0x14, 0x0a, 0x10, 0x4d, 0x4f, 0x44,
+	0x45, 0x5f, 0x55, 0x4e, 0x53, 0x50, 0x45, 0x43, 0x49, 0x46, 0x49, 0x45, 0x44, 0x10, 0x00, 0x12,
+	0x0e, 0x0a, 0x0a, 0x4d, 0x4f, 0x44, 0x45, 0x5f, 0x53, 0x50, 0x45, 0x45, 0x44, 0x10, 0x01, 0x12,
+	0x11, 0x0a, 0x0d, 0x4d, 0x4f, 0x44, 0x45, 0x5f, 0x50, 0x4f, 0x53, 0x49, 0x54, 0x49, 0x4f, 0x4e,
+	0x10, 0x02, 0x12, 0x10, 0x0a, 0x0c, 0x4d, 0x4f, 0x44, 0x45, 0x5f, 0x43, 0x55, 0x52, 0x52, 0x45,
+	0x4e, 0x54, 0x10, 0x03, 0x12, 0x17, 0x0a, 0x13, 0x4d, 0x4f, 0x44, 0x45, 0x5f, 0x4d, 0x4f, 0x54,
+	0x49, 0x4f, 0x4e, 0x5f, 0x43, 0x4f, 0x4e, 0x54, 0x52, 0x4f, 0x4c, 0x10, 0x04, 0x32, 0x87, 0x06,
+	0x0a, 0x09, 0x43, 0x79, 0x62, 0x65, 0x72, 0x47, 0x65, 0x61, 0x72, 0x12, 0x53, 0x0a, 0x0a, 0x4c,
+	0x69, 0x73, 0x74, 0x4d, 0x6f, 0x74, 0x6f, 0x72, 0x73, 0x12, 0x21, 0x2e, 0x67, 0x6f, 0x63, 0x67,
+	0x2e, 0x63, 0x79, 0x62, 0x65, 0x72, 0x67, 0x65, 0x61, 0x72, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x4d,
+	0x6f, 0x74, 0x6f, 0x72, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x22, 0x2e, 0x67,
+	0x6f, 0x63, 0x67, 0x2e, 0x63, 0x79, 0x62, 0x65, 0x72, 0x67, 0x65, 0x61, 0x72, 0x2e, 0x4c, 0x69,
+	0x73, 0x74, 0x4d, 0x6f, 0x74, 0x6f, 0x72, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x12, 0x43, 0x0a, 0x06, 0x45, 0x6e, 0x61, 0x62, 0x6c, 0x65, 0x12, 0x1c, 0x2e, 0x67, 0x6f, 0x63,
+	0x67, 0x2e, 0x63, 0x79, 0x62, 0x65, 0x72, 0x67, 0x65, 0x61, 0x72, 0x2e, 0x4d, 0x6f, 0x74, 0x6f,
+	0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1b, 0x2e, 0x67, 0x6f, 0x63, 0x67, 0x2e,
+	0x63, 0x79, 0x62, 0x65, 0x72, 0x67, 0x65, 0x61, 0x72, 0x2e, 0x4d, 0x6f, 0x74, 0x6f, 0x72, 0x53,
+	0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x44, 0x0a, 0x07, 0x44, 0x69, 0x73, 0x61, 0x62, 0x6c, 0x65,
+	0x12, 0x1c, 0x2e, 0x67, 0x6f, 0x63, 0x67, 0x2e, 0x63, 0x79, 0x62, 0x65, 0x72, 0x67, 0x65, 0x61,
+	0x72, 0x2e, 0x4d, 0x6f, 0x74, 0x6f, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1b,
+	0x2e, 0x67, 0x6f, 0x63, 0x67, 0x2e, 0x63, 0x79, 0x62, 0x65, 0x72, 0x67, 0x65, 0x61, 0x72, 0x2e,
+	0x4d, 0x6f, 0x74, 0x6f, 0x72, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x46, 0x0a, 0x09, 0x47,
+	0x65, 0x74, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x1c, 0x2e, 0x67, 0x6f, 0x63, 0x67, 0x2e,
+	0x63, 0x79, 0x62, 0x65, 0x72, 0x67, 0x65, 0x61, 0x72, 0x2e, 0x4d, 0x6f, 0x74, 0x6f, 0x72, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1b, 0x2e, 0x67, 0x6f, 0x63, 0x67, 0x2e, 0x63, 0x79,
+	0x62, 0x65, 0x72, 0x67, 0x65, 0x61, 0x72, 0x2e, 0x4d, 0x6f, 0x74, 0x6f, 0x72, 0x53, 0x74, 0x61,
+	0x74, 0x75, 0x73, 0x12, 0x46, 0x0a, 0x07, 0x53, 0x65, 0x74, 0x4d, 0x6f, 0x64, 0x65, 0x12, 0x1e,
+	0x2e, 0x67, 0x6f, 0x63, 0x67, 0x2e, 0x63, 0x79, 0x62, 0x65, 0x72, 0x67, 0x65, 0x61, 0x72, 0x2e,
+	0x53, 0x65, 0x74, 0x4d, 0x6f, 0x64, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1b,
+	0x2e, 0x67, 0x6f, 0x63, 0x67, 0x2e, 0x63, 0x79, 0x62, 0x65, 0x72, 0x67, 0x65, 0x61, 0x72, 0x2e,
+	0x4d, 0x6f, 0x74, 0x6f, 0x72, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x4b, 0x0a, 0x0b, 0x53,
+	0x65, 0x74, 0x53, 0x65, 0x74, 0x70, 0x6f, 0x69, 0x6e, 0x74, 0x12, 0x1f, 0x2e, 0x67, 0x6f, 0x63,
+	0x67, 0x2e, 0x63, 0x79, 0x62, 0x65, 0x72, 0x67, 0x65, 0x61, 0x72, 0x2e, 0x53, 0x65, 0x74, 0x70,
+	0x6f, 0x69, 0x6e, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1b, 0x2e, 0x67, 0x6f,
+	0x63, 0x67, 0x2e, 0x63, 0x79, 0x62, 0x65, 0x72, 0x67, 0x65, 0x61, 0x72, 0x2e, 0x4d, 0x6f, 0x74,
+	0x6f, 0x72, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x4c, 0x0a, 0x0d, 0x52, 0x65, 0x61, 0x64,
+	0x50, 0x61, 0x72, 0x61, 0x6d, 0x65, 0x74, 0x65, 0x72, 0x12, 0x20, 0x2e, 0x67, 0x6f, 0x63, 0x67,
+	0x2e, 0x63, 0x79, 0x62, 0x65, 0x72, 0x67, 0x65, 0x61, 0x72, 0x2e, 0x50, 0x61, 0x72, 0x61, 0x6d,
+	0x65, 0x74, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x19, 0x2e, 0x67, 0x6f,
+	0x63, 0x67, 0x2e, 0x63, 0x79, 0x62, 0x65, 0x72, 0x67, 0x65, 0x61, 0x72, 0x2e, 0x50, 0x61, 0x72,
+	0x61, 0x6d, 0x65, 0x74, 0x65, 0x72, 0x12, 0x52, 0x0a, 0x0e, 0x57, 0x72, 0x69, 0x74, 0x65, 0x50,
+	0x61, 0x72, 0x61, 0x6d, 0x65, 0x74, 0x65, 0x72, 0x12, 0x25, 0x2e, 0x67, 0x6f, 0x63, 0x67, 0x2e,
+	0x63, 0x79, 0x62, 0x65, 0x72, 0x67, 0x65, 0x61, 0x72, 0x2e, 0x57, 0x72, 0x69, 0x74, 0x65, 0x50,
+	0x61, 0x72, 0x61, 0x6d, 0x65, 0x74, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
+	0x19, 0x2e, 0x67, 0x6f, 0x63, 0x67, 0x2e, 0x63, 0x79, 0x62, 0x65, 0x72, 0x67, 0x65, 0x61, 0x72,
+	0x2e, 0x50, 0x61, 0x72, 0x61, 0x6d, 0x65, 0x74, 0x65, 0x72, 0x12, 0x4e, 0x0a, 0x0e, 0x53, 0x74,
+	0x72, 0x65, 0x61, 0x6d, 0x46, 0x65, 0x65, 0x64, 0x62, 0x61, 0x63, 0x6b, 0x12, 0x1d, 0x2e, 0x67,
+	0x6f, 0x63, 0x67, 0x2e, 0x63, 0x79, 0x62, 0x65, 0x72, 0x67, 0x65, 0x61, 0x72, 0x2e, 0x53, 0x74,
+	0x72, 0x65, 0x61, 0x6d, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1b, 0x2e, 0x67, 0x6f,
+	0x63, 0x67, 0x2e, 0x63, 0x79, 0x62, 0x65, 0x72, 0x67, 0x65, 0x61, 0x72, 0x2e, 0x4d, 0x6f, 0x74,
+	0x6f, 0x72, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x30, 0x01, 0x12, 0x4b, 0x0a, 0x0c, 0x53, 0x74,
+	0x72, 0x65, 0x61, 0x6d, 0x46, 0x61, 0x75, 0x6c, 0x74, 0x73, 0x12, 0x1d, 0x2e, 0x67, 0x6f, 0x63,
+	0x67, 0x2e, 0x63, 0x79, 0x62, 0x65, 0x72, 0x67, 0x65, 0x61, 0x72, 0x2e, 0x53, 0x74, 0x72, 0x65,
+	0x61, 0x6d, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1a, 0x2e, 0x67, 0x6f, 0x63, 0x67,
+	0x2e, 0x63, 0x79, 0x62, 0x65, 0x72, 0x67, 0x65, 0x61, 0x72, 0x2e, 0x46, 0x61, 0x75, 0x6c, 0x74,
+	0x45, 0x76, 0x65, 0x6e, 0x74, 0x30, 0x01, 0x42, 0x0d, 0x5a, 0x0b, 0x67, 0x6f, 0x63, 0x67, 0x2f,
+	0x72, 0x70, 0x63, 0x2f, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_cybergear_proto_rawDescOnce sync.Once
+	file_cybergear_proto_rawDescData = file_cybergear_proto_rawDesc
+)
+
+func file_cybergear_proto_rawDescGZIP() []byte {
+	file_cybergear_proto_rawDescOnce.Do(func() {
+		file_cybergear_proto_rawDescData = protoimpl.X.CompressGZIP(file_cybergear_proto_rawDescData)
+	})
+	return file_cybergear_proto_rawDescData
+}
+
+var file_cybergear_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_cybergear_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_cybergear_proto_goTypes = []interface{}{
+	(Mode)(0),                     // 0: gocg.cybergear.Mode
+	(*MotorRequest)(nil),          // 1: gocg.cybergear.MotorRequest
+	(*ListMotorsRequest)(nil),     // 2: gocg.cybergear.ListMotorsRequest
+	(*ListMotorsResponse)(nil),    // 3: gocg.cybergear.ListMotorsResponse
+	(*MotorStatus)(nil),           // 4: gocg.cybergear.MotorStatus
+	(*SetModeRequest)(nil),        // 5: gocg.cybergear.SetModeRequest
+	(*SetpointRequest)(nil),       // 6: gocg.cybergear.SetpointRequest
+	(*ParameterRequest)(nil),      // 7: gocg.cybergear.ParameterRequest
+	(*WriteParameterRequest)(nil), // 8: gocg.cybergear.WriteParameterRequest
+	(*Parameter)(nil),             // 9: gocg.cybergear.Parameter
+	(*StreamRequest)(nil),         // 10: gocg.cybergear.StreamRequest
+	(*FaultEvent)(nil),            // 11: gocg.cybergear.FaultEvent
+}
+var file_cybergear_proto_depIdxs = []int32{
+	4,  // 0: gocg.cybergear.ListMotorsResponse.motors:type_name -> gocg.cybergear.MotorStatus
+	0,  // 1: gocg.cybergear.SetModeRequest.mode:type_name -> gocg.cybergear.Mode
+	0,  // 2: gocg.cybergear.SetpointRequest.mode:type_name -> gocg.cybergear.Mode
+	2,  // 3: gocg.cybergear.CyberGear.ListMotors:input_type -> gocg.cybergear.ListMotorsRequest
+	1,  // 4: gocg.cybergear.CyberGear.Enable:input_type -> gocg.cybergear.MotorRequest
+	1,  // 5: gocg.cybergear.CyberGear.Disable:input_type -> gocg.cybergear.MotorRequest
+	1,  // 6: gocg.cybergear.CyberGear.GetStatus:input_type -> gocg.cybergear.MotorRequest
+	5,  // 7: gocg.cybergear.CyberGear.SetMode:input_type -> gocg.cybergear.SetModeRequest
+	6,  // 8: gocg.cybergear.CyberGear.SetSetpoint:input_type -> gocg.cybergear.SetpointRequest
+	7,  // 9: gocg.cybergear.CyberGear.ReadParameter:input_type -> gocg.cybergear.ParameterRequest
+	8,  // 10: gocg.cybergear.CyberGear.WriteParameter:input_type -> gocg.cybergear.WriteParameterRequest
+	10, // 11: gocg.cybergear.CyberGear.StreamFeedback:input_type -> gocg.cybergear.StreamRequest
+	10, // 12: gocg.cybergear.CyberGear.StreamFaults:input_type -> gocg.cybergear.StreamRequest
+	3,  // 13: gocg.cybergear.CyberGear.ListMotors:output_type -> gocg.cybergear.ListMotorsResponse
+	4,  // 14: gocg.cybergear.CyberGear.Enable:output_type -> gocg.cybergear.MotorStatus
+	4,  // 15: gocg.cybergear.CyberGear.Disable:output_type -> gocg.cybergear.MotorStatus
+	4,  // 16: gocg.cybergear.CyberGear.GetStatus:output_type -> gocg.cybergear.MotorStatus
+	4,  // 17: gocg.cybergear.CyberGear.SetMode:output_type -> gocg.cybergear.MotorStatus
+	4,  // 18: gocg.cybergear.CyberGear.SetSetpoint:output_type -> gocg.cybergear.MotorStatus
+	9,  // 19: gocg.cybergear.CyberGear.ReadParameter:output_type -> gocg.cybergear.Parameter
+	9,  // 20: gocg.cybergear.CyberGear.WriteParameter:output_type -> gocg.cybergear.Parameter
+	4,  // 21: gocg.cybergear.CyberGear.StreamFeedback:output_type -> gocg.cybergear.MotorStatus
+	11, // 22: gocg.cybergear.CyberGear.StreamFaults:output_type -> gocg.cybergear.FaultEvent
+	13, // [13:23] is the sub-list for method output_type
+	3,  // [3:13] is the sub-list for method input_type
+	3,  // [3:3] is the sub-list for extension type_name
+	3,  // [3:3] is the sub-list for extension extendee
+	0,  // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_cybergear_proto_init() }
+func file_cybergear_proto_init() {
+	if File_cybergear_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_cybergear_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*MotorRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cybergear_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListMotorsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cybergear_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListMotorsResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cybergear_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*MotorStatus); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cybergear_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SetModeRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cybergear_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SetpointRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cybergear_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ParameterRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cybergear_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*WriteParameterRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cybergear_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Parameter); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cybergear_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*StreamRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cybergear_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FaultEvent); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	file_cybergear_proto_msgTypes[7].OneofWrappers = []interface{}{
+		(*WriteParameterRequest_Number)(nil),
+		(*WriteParameterRequest_Text)(nil),
+	}
+	file_cybergear_proto_msgTypes[8].OneofWrappers = []interface{}{
+		(*Parameter_Number)(nil),
+		(*Parameter_Text)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_cybergear_proto_rawDesc,
+			NumEnums:      1,
+			NumMessages:   11,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_cybergear_proto_goTypes,
+		DependencyIndexes: file_cybergear_proto_depIdxs,
+		EnumInfos:         file_cybergear_proto_enumTypes,
+		MessageInfos:      file_cybergear_proto_msgTypes,
+	}.Build()
+	File_cybergear_proto = out.File
+	file_cybergear_proto_rawDesc = nil
+	file_cybergear_proto_goTypes = nil
+	file_cybergear_proto_depIdxs = nil
+}
@@ -0,0 +1,511 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: cybergear.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	CyberGear_ListMotors_FullMethodName     = "/gocg.cybergear.CyberGear/ListMotors"
+	CyberGear_Enable_FullMethodName         = "/gocg.cybergear.CyberGear/Enable"
+	CyberGear_Disable_FullMethodName        = "/gocg.cybergear.CyberGear/Disable"
+	CyberGear_GetStatus_FullMethodName      = "/gocg.cybergear.CyberGear/GetStatus"
+	CyberGear_SetMode_FullMethodName        = "/gocg.cybergear.CyberGear/SetMode"
+	CyberGear_SetSetpoint_FullMethodName    = "/gocg.cybergear.CyberGear/SetSetpoint"
+	CyberGear_ReadParameter_FullMethodName  = "/gocg.cybergear.CyberGear/ReadParameter"
+	CyberGear_WriteParameter_FullMethodName = "/gocg.cybergear.CyberGear/WriteParameter"
+	CyberGear_StreamFeedback_FullMethodName = "/gocg.cybergear.CyberGear/StreamFeedback"
+	CyberGear_StreamFaults_FullMethodName   = "/gocg.cybergear.CyberGear/StreamFaults"
+)
+
+// CyberGearClient is the client API for CyberGear service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type CyberGearClient interface {
+	// Motors of the profile and motors seen on the bus, with their last feedback
+	ListMotors(ctx context.Context, in *ListMotorsRequest, opts ...grpc.CallOption) (*ListMotorsResponse, error)
+	Enable(ctx context.Context, in *MotorRequest, opts ...grpc.CallOption) (*MotorStatus, error)
+	Disable(ctx context.Context, in *MotorRequest, opts ...grpc.CallOption) (*MotorStatus, error)
+	// Requests a feedback frame (communication type 15)
+	GetStatus(ctx context.Context, in *MotorRequest, opts ...grpc.CallOption) (*MotorStatus, error)
+	// Switches the run mode and enables the motor
+	SetMode(ctx context.Context, in *SetModeRequest, opts ...grpc.CallOption) (*MotorStatus, error)
+	// Writes the setpoint of the current mode, checked against the limits of the profile
+	SetSetpoint(ctx context.Context, in *SetpointRequest, opts ...grpc.CallOption) (*MotorStatus, error)
+	ReadParameter(ctx context.Context, in *ParameterRequest, opts ...grpc.CallOption) (*Parameter, error)
+	// Writes a parameter and returns the value read back
+	WriteParameter(ctx context.Context, in *WriteParameterRequest, opts ...grpc.CallOption) (*Parameter, error)
+	// Every feedback frame of the motor (all motors if none is given)
+	StreamFeedback(ctx context.Context, in *StreamRequest, opts ...grpc.CallOption) (CyberGear_StreamFeedbackClient, error)
+	// Faults as they are raised and cleared
+	StreamFaults(ctx context.Context, in *StreamRequest, opts ...grpc.CallOption) (CyberGear_StreamFaultsClient, error)
+}
+
+type cyberGearClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCyberGearClient(cc grpc.ClientConnInterface) CyberGearClient {
+	return &cyberGearClient{cc}
+}
+
+func (c *cyberGearClient) ListMotors(ctx context.Context, in *ListMotorsRequest, opts ...grpc.CallOption) (*ListMotorsResponse, error) {
+	out := new(ListMotorsResponse)
+	err := c.cc.Invoke(ctx, CyberGear_ListMotors_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cyberGearClient) Enable(ctx context.Context, in *MotorRequest, opts ...grpc.CallOption) (*MotorStatus, error) {
+	out := new(MotorStatus)
+	err := c.cc.Invoke(ctx, CyberGear_Enable_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cyberGearClient) Disable(ctx context.Context, in *MotorRequest, opts ...grpc.CallOption) (*MotorStatus, error) {
+	out := new(MotorStatus)
+	err := c.cc.Invoke(ctx, CyberGear_Disable_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cyberGearClient) GetStatus(ctx context.Context, in *MotorRequest, opts ...grpc.CallOption) (*MotorStatus, error) {
+	out := new(MotorStatus)
+	err := c.cc.Invoke(ctx, CyberGear_GetStatus_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cyberGearClient) SetMode(ctx context.Context, in *SetModeRequest, opts ...grpc.CallOption) (*MotorStatus, error) {
+	out := new(MotorStatus)
+	err := c.cc.Invoke(ctx, CyberGear_SetMode_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cyberGearClient) SetSetpoint(ctx context.Context, in *SetpointRequest, opts ...grpc.CallOption) (*MotorStatus, error) {
+	out := new(MotorStatus)
+	err := c.cc.Invoke(ctx, CyberGear_SetSetpoint_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cyberGearClient) ReadParameter(ctx context.Context, in *ParameterRequest, opts ...grpc.CallOption) (*Parameter, error) {
+	out := new(Parameter)
+	err := c.cc.Invoke(ctx, CyberGear_ReadParameter_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cyberGearClient) WriteParameter(ctx context.Context, in *WriteParameterRequest, opts ...grpc.CallOption) (*Parameter, error) {
+	out := new(Parameter)
+	err := c.cc.Invoke(ctx, CyberGear_WriteParameter_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cyberGearClient) StreamFeedback(ctx context.Context, in *StreamRequest, opts ...grpc.CallOption) (CyberGear_StreamFeedbackClient, error) {
+	stream, err := c.cc.NewStream(ctx, &CyberGear_ServiceDesc.Streams[0], CyberGear_StreamFeedback_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &cyberGearStreamFeedbackClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type CyberGear_StreamFeedbackClient interface {
+	Recv() (*MotorStatus, error)
+	grpc.ClientStream
+}
+
+type cyberGearStreamFeedbackClient struct {
+	grpc.ClientStream
+}
+
+func (x *cyberGearStreamFeedbackClient) Recv() (*MotorStatus, error) {
+	m := new(MotorStatus)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *cyberGearClient) StreamFaults(ctx context.Context, in *StreamRequest, opts ...grpc.CallOption) (CyberGear_StreamFaultsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &CyberGear_ServiceDesc.Streams[1], CyberGear_StreamFaults_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &cyberGearStreamFaultsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type CyberGear_StreamFaultsClient interface {
+	Recv() (*FaultEvent, error)
+	grpc.ClientStream
+}
+
+type cyberGearStreamFaultsClient struct {
+	grpc.ClientStream
+}
+
+func (x *cyberGearStreamFaultsClient) Recv() (*FaultEvent, error) {
+	m := new(FaultEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// CyberGearServer is the server API for CyberGear service.
+// All implementations must embed UnimplementedCyberGearServer
+// for forward compatibility
+type CyberGearServer interface {
+	// Motors of the profile and motors seen on the bus, with their last feedback
+	ListMotors(context.Context, *ListMotorsRequest) (*ListMotorsResponse, error)
+	Enable(context.Context, *MotorRequest) (*MotorStatus, error)
+	Disable(context.Context, *MotorRequest) (*MotorStatus, error)
+	// Requests a feedback frame (communication type 15)
+	GetStatus(context.Context, *MotorRequest) (*MotorStatus, error)
+	// Switches the run mode and enables the motor
+	SetMode(context.Context, *SetModeRequest) (*MotorStatus, error)
+	// Writes the setpoint of the current mode, checked against the limits of the profile
+	SetSetpoint(context.Context, *SetpointRequest) (*MotorStatus, error)
+	ReadParameter(context.Context, *ParameterRequest) (*Parameter, error)
+	// Writes a parameter and returns the value read back
+	WriteParameter(context.Context, *WriteParameterRequest) (*Parameter, error)
+	// Every feedback frame of the motor (all motors if none is given)
+	StreamFeedback(*StreamRequest, CyberGear_StreamFeedbackServer) error
+	// Faults as they are raised and cleared
+	StreamFaults(*StreamRequest, CyberGear_StreamFaultsServer) error
+	mustEmbedUnimplementedCyberGearServer()
+}
+
+// UnimplementedCyberGearServer must be embedded to have forward compatible implementations.
+type UnimplementedCyberGearServer struct {
+}
+
+func (UnimplementedCyberGearServer) ListMotors(context.Context, *ListMotorsRequest) (*ListMotorsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListMotors not implemented")
+}
+func (UnimplementedCyberGearServer) Enable(context.Context, *MotorRequest) (*MotorStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Enable not implemented")
+}
+func (UnimplementedCyberGearServer) Disable(context.Context, *MotorRequest) (*MotorStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Disable not implemented")
+}
+func (UnimplementedCyberGearServer) GetStatus(context.Context, *MotorRequest) (*MotorStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStatus not implemented")
+}
+func (UnimplementedCyberGearServer) SetMode(context.Context, *SetModeRequest) (*MotorStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetMode not implemented")
+}
+func (UnimplementedCyberGearServer) SetSetpoint(context.Context, *SetpointRequest) (*MotorStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetSetpoint not implemented")
+}
+func (UnimplementedCyberGearServer) ReadParameter(context.Context, *ParameterRequest) (*Parameter, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReadParameter not implemented")
+}
+func (UnimplementedCyberGearServer) WriteParameter(context.Context, *WriteParameterRequest) (*Parameter, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method WriteParameter not implemented")
+}
+func (UnimplementedCyberGearServer) StreamFeedback(*StreamRequest, CyberGear_StreamFeedbackServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamFeedback not implemented")
+}
+func (UnimplementedCyberGearServer) StreamFaults(*StreamRequest, CyberGear_StreamFaultsServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamFaults not implemented")
+}
+func (UnimplementedCyberGearServer) mustEmbedUnimplementedCyberGearServer() {}
+
+// UnsafeCyberGearServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CyberGearServer will
+// result in compilation errors.
+type UnsafeCyberGearServer interface {
+	mustEmbedUnimplementedCyberGearServer()
+}
+
+func RegisterCyberGearServer(s grpc.ServiceRegistrar, srv CyberGearServer) {
+	s.RegisterService(&CyberGear_ServiceDesc, srv)
+}
+
+func _CyberGear_ListMotors_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListMotorsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CyberGearServer).ListMotors(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CyberGear_ListMotors_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CyberGearServer).ListMotors(ctx, req.(*ListMotorsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CyberGear_Enable_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MotorRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CyberGearServer).Enable(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CyberGear_Enable_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CyberGearServer).Enable(ctx, req.(*MotorRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CyberGear_Disable_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MotorRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CyberGearServer).Disable(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CyberGear_Disable_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CyberGearServer).Disable(ctx, req.(*MotorRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CyberGear_GetStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MotorRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CyberGearServer).GetStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CyberGear_GetStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CyberGearServer).GetStatus(ctx, req.(*MotorRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CyberGear_SetMode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetModeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CyberGearServer).SetMode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CyberGear_SetMode_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CyberGearServer).SetMode(ctx, req.(*SetModeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CyberGear_SetSetpoint_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetpointRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CyberGearServer).SetSetpoint(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CyberGear_SetSetpoint_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CyberGearServer).SetSetpoint(ctx, req.(*SetpointRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CyberGear_ReadParameter_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ParameterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CyberGearServer).ReadParameter(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CyberGear_ReadParameter_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CyberGearServer).ReadParameter(ctx, req.(*ParameterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CyberGear_WriteParameter_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WriteParameterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CyberGearServer).WriteParameter(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CyberGear_WriteParameter_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CyberGearServer).WriteParameter(ctx, req.(*WriteParameterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CyberGear_StreamFeedback_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CyberGearServer).StreamFeedback(m, &cyberGearStreamFeedbackServer{stream})
+}
+
+type CyberGear_StreamFeedbackServer interface {
+	Send(*MotorStatus) error
+	grpc.ServerStream
+}
+
+type cyberGearStreamFeedbackServer struct {
+	grpc.ServerStream
+}
+
+func (x *cyberGearStreamFeedbackServer) Send(m *MotorStatus) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _CyberGear_StreamFaults_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CyberGearServer).StreamFaults(m, &cyberGearStreamFaultsServer{stream})
+}
+
+type CyberGear_StreamFaultsServer interface {
+	Send(*FaultEvent) error
+	grpc.ServerStream
+}
+
+type cyberGearStreamFaultsServer struct {
+	grpc.ServerStream
+}
+
+func (x *cyberGearStreamFaultsServer) Send(m *FaultEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// CyberGear_ServiceDesc is the grpc.ServiceDesc for CyberGear service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var CyberGear_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "gocg.cybergear.CyberGear",
+	HandlerType: (*CyberGearServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListMotors",
+			Handler:    _CyberGear_ListMotors_Handler,
+		},
+		{
+			MethodName: "Enable",
+			Handler:    _CyberGear_Enable_Handler,
+		},
+		{
+			MethodName: "Disable",
+			Handler:    _CyberGear_Disable_Handler,
+		},
+		{
+			MethodName: "GetStatus",
+			Handler:    _CyberGear_GetStatus_Handler,
+		},
+		{
+			MethodName: "SetMode",
+			Handler:    _CyberGear_SetMode_Handler,
+		},
+		{
+			MethodName: "SetSetpoint",
+			Handler:    _CyberGear_SetSetpoint_Handler,
+		},
+		{
+			MethodName: "ReadParameter",
+			Handler:    _CyberGear_ReadParameter_Handler,
+		},
+		{
+			MethodName: "WriteParameter",
+			Handler:    _CyberGear_WriteParameter_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamFeedback",
+			Handler:       _CyberGear_StreamFeedback_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "StreamFaults",
+			Handler:       _CyberGear_StreamFaults_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "cybergear.proto",
+}
@@ -0,0 +1,4 @@
+// Generated gRPC and protobuf code of the CyberGear service
+package pb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative cybergear.proto
@@ -0,0 +1,388 @@
+package slcan
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	CR  byte = '\r'
+	BEL byte = 0x07 // SLCAN error reply
+)
+
+// SLCAN "Sn" commands for the standard CAN bitrates (bit/s)
+var Bitrates = map[int]string{
+	10000:   "S0",
+	20000:   "S1",
+	50000:   "S2",
+	100000:  "S3",
+	125000:  "S4",
+	250000:  "S5",
+	500000:  "S6",
+	800000:  "S7",
+	1000000: "S8",
+}
+
+// Supported bitrates in ascending order
+func BitrateList() []int {
+	list := make([]int, 0, len(Bitrates))
+	for bitrate := range Bitrates {
+		list = append(list, bitrate)
+	}
+	sort.Ints(list)
+	return list
+}
+
+var ErrNoReply = errors.New("no reply from adapter")
+
+// Returned when the adapter answers a command with BEL
+type AdapterError struct {
+	Command string
+}
+
+func (e *AdapterError) Error() string {
+	return fmt.Sprintf("adapter rejected command '%s' (BEL)", e.Command)
+}
+
+// SLCAN status flags ('F' command)
+type StatusFlags byte
+
+const (
+	STATUS_RX_FIFO_FULL     StatusFlags = 1 << 0
+	STATUS_TX_FIFO_FULL     StatusFlags = 1 << 1
+	STATUS_ERROR_WARNING    StatusFlags = 1 << 2
+	STATUS_DATA_OVERRUN     StatusFlags = 1 << 3
+	STATUS_ERROR_PASSIVE    StatusFlags = 1 << 5
+	STATUS_ARBITRATION_LOST StatusFlags = 1 << 6
+	STATUS_BUS_ERROR        StatusFlags = 1 << 7
+)
+
+var statusFlagNames = []struct {
+	flag StatusFlags
+	name string
+}{
+	{STATUS_RX_FIFO_FULL, "RX FIFO full"},
+	{STATUS_TX_FIFO_FULL, "TX FIFO full"},
+	{STATUS_ERROR_WARNING, "error warning"},
+	{STATUS_DATA_OVERRUN, "data overrun"},
+	{STATUS_ERROR_PASSIVE, "error passive"},
+	{STATUS_ARBITRATION_LOST, "arbitration lost"},
+	{STATUS_BUS_ERROR, "bus error"},
+}
+
+func (s StatusFlags) String() string {
+	if s == 0 {
+		return "OK"
+	}
+
+	var names []string
+	for _, n := range statusFlagNames {
+		if s&n.flag != 0 {
+			names = append(names, n.name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// Lawicel SLCAN adapter (e.g. MKS CANable) on a serial line.
+//
+// Not every firmware answers the setup commands. The CANable firmware is silent on success, so a missing
+// reply to a setup command is accepted, while a BEL reply is always reported as an AdapterError.
+type Adapter struct {
+	port         io.ReadWriteCloser
+	replyTimeout time.Duration
+	pending      []byte   // Received bytes not yet returned as a line
+	frames       [][]byte // Frame lines received while waiting for a command reply
+	timestamp    uint16   // Timestamp of the last frame returned by ReadFrameLine (ms, 0 - 59999)
+}
+
+func NewAdapter(port io.ReadWriteCloser) *Adapter {
+	return &Adapter{port: port, replyTimeout: 100 * time.Millisecond}
+}
+
+func (a *Adapter) SetReplyTimeout(timeout time.Duration) {
+	a.replyTimeout = timeout
+}
+
+func (a *Adapter) Close() error {
+	return a.port.Close()
+}
+
+// Writes a line to the adapter, adding the terminating CR
+func (a *Adapter) WriteLine(line []byte) error {
+	buf := append(append([]byte{}, line...), CR)
+	n, err := a.port.Write(buf)
+	if err != nil {
+		return err
+	}
+	if n != len(buf) {
+		return fmt.Errorf("error writing to adapter. %d bytes sent of %d", n, len(buf))
+	}
+	return nil
+}
+
+// Discards everything received so far. Returns the number of frames discarded that had already been read from the
+// port (the port's own buffer is flushed unseen).
+func (a *Adapter) Flush() int {
+	if flusher, ok := a.port.(interface{ Flush() error }); ok {
+		flusher.Flush()
+	}
+
+	discarded := len(a.frames)
+	for _, line := range strings.Split(string(a.pending), string(CR)) {
+		if IsFrameLine([]byte(line)) {
+			discarded++
+		}
+	}
+
+	a.pending = nil
+	a.frames = nil
+	return discarded
+}
+
+// Returns the next line from the adapter including its terminator (CR or BEL).
+// Returns ErrNoReply if no complete line is received within the timeout.
+func (a *Adapter) ReadLine(timeout time.Duration) ([]byte, error) {
+	deadline := time.Now().Add(timeout)
+	readBuffer := make([]byte, 64)
+
+	for {
+		for i, b := range a.pending {
+			if b == CR || b == BEL {
+				line := append([]byte{}, a.pending[:i+1]...)
+				a.pending = a.pending[i+1:]
+				return line, nil
+			}
+		}
+
+		if time.Now().After(deadline) {
+			return nil, ErrNoReply
+		}
+
+		n, err := a.port.Read(readBuffer)
+		if n > 0 {
+			a.pending = append(a.pending, readBuffer[:n]...)
+			continue
+		}
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if n == 0 && err == nil {
+			// Port without read timeout
+			time.Sleep(time.Millisecond)
+		}
+	}
+}
+
+// Returns the next CAN frame line (t, T, r or R). Adapter replies received meanwhile are dropped.
+// If timestamps are turned on, the timestamp is removed from the line and available from LastTimestamp.
+func (a *Adapter) ReadFrameLine(timeout time.Duration) ([]byte, error) {
+	if len(a.frames) > 0 {
+		line := a.frames[0]
+		a.frames = a.frames[1:]
+		return a.stripTimestamp(line), nil
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		line, err := a.ReadLine(time.Until(deadline))
+		if err != nil {
+			return nil, err
+		}
+		if IsFrameLine(line) {
+			return a.stripTimestamp(line), nil
+		}
+	}
+}
+
+func (a *Adapter) LastTimestamp() uint16 {
+	return a.timestamp
+}
+
+// Removes the 4 hex digit timestamp the adapter adds to received frames when timestamps are on.
+func (a *Adapter) stripTimestamp(line []byte) []byte {
+	length := frameLineLength(line)
+	if length == 0 || len(line) != length+4 {
+		return line
+	}
+
+	var ts uint16
+	_, err := fmt.Sscanf(string(line[length-1:length+3]), "%04X", &ts)
+	if err != nil {
+		return line
+	}
+	a.timestamp = ts
+
+	return append(line[:length-1:length-1], CR)
+}
+
+// Length of a frame line including CR but without timestamp. 0 if the line isn't a frame line or is too short to tell.
+func frameLineLength(line []byte) int {
+	if !IsFrameLine(line) {
+		return 0
+	}
+
+	idLength := 8
+	if CANFrameType(line[0]) == STANDARD_FRAME || CANFrameType(line[0]) == STANDARD_RTR_FRAME {
+		idLength = 3
+	}
+	if len(line) < 1+idLength+1 {
+		return 0
+	}
+
+	dlc := int(line[idLength+1] - '0')
+	if dlc < 0 || dlc > 8 {
+		return 0
+	}
+	if CANFrameType(line[0]) == EXTENDED_RTR_FRAME || CANFrameType(line[0]) == STANDARD_RTR_FRAME {
+		dlc = 0
+	}
+
+	return 1 + idLength + 1 + 2*dlc + 1
+}
+
+// Size in bits of the CAN frame of a frame line, without stuff bits: 47 bits of overhead for standard and 67 for
+// extended frames, plus 8 per data byte. 0 if the line isn't a frame line.
+func FrameBits(line []byte) int {
+	length := frameLineLength(line)
+	if length == 0 {
+		return 0
+	}
+
+	bits := 67
+	idLength := 8
+	if CANFrameType(line[0]) == STANDARD_FRAME || CANFrameType(line[0]) == STANDARD_RTR_FRAME {
+		bits = 47
+		idLength = 3
+	}
+	dataLength := (length - 1 - idLength - 1 - 1) / 2
+	return bits + 8*dataLength
+}
+
+// A frame line (without timestamp) that is shorter or longer than its DLC says has lost characters, which is what a
+// serial buffer overrun looks like
+func Truncated(line []byte) bool {
+	return IsFrameLine(line) && frameLineLength(line) != len(line)
+}
+
+func IsFrameLine(line []byte) bool {
+	if len(line) == 0 {
+		return false
+	}
+	switch CANFrameType(line[0]) {
+	case EXTENDED_FRAME, STANDARD_FRAME, EXTENDED_RTR_FRAME, STANDARD_RTR_FRAME:
+		return true
+	}
+	return false
+}
+
+// Sends a command and returns the reply without its terminator. Frames received while waiting are kept for ReadFrameLine.
+func (a *Adapter) Command(cmd string) ([]byte, error) {
+	err := a.WriteLine([]byte(cmd))
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(a.replyTimeout)
+	for {
+		line, err := a.ReadLine(time.Until(deadline))
+		if err != nil {
+			return nil, err
+		}
+		if IsFrameLine(line) {
+			a.frames = append(a.frames, line)
+			continue
+		}
+		if line[len(line)-1] == BEL {
+			return nil, &AdapterError{Command: cmd}
+		}
+		return line[:len(line)-1], nil
+	}
+}
+
+// Sends a setup command. Silence is taken as success.
+func (a *Adapter) setup(cmd string) error {
+	_, err := a.Command(cmd)
+	if err == ErrNoReply {
+		return nil
+	}
+	return err
+}
+
+// Sends a query command and checks that the reply starts with the expected character.
+func (a *Adapter) query(cmd string) (string, error) {
+	reply, err := a.Command(cmd)
+	if err != nil {
+		return "", err
+	}
+	if len(reply) == 0 || reply[0] != cmd[0] {
+		return "", fmt.Errorf("unexpected reply to '%s': '%s'", cmd, reply)
+	}
+	return string(reply[1:]), nil
+}
+
+// Sets the CAN bitrate (bit/s). Only allowed while the CAN channel is closed.
+func (a *Adapter) SetBitrate(bitrate int) error {
+	cmd, ok := Bitrates[bitrate]
+	if !ok {
+		return fmt.Errorf("unsupported CAN bitrate %d. Supported bitrates are %v", bitrate, BitrateList())
+	}
+	return a.setup(cmd)
+}
+
+// Opens the CAN channel in normal mode (send and receive)
+func (a *Adapter) Open() error {
+	return a.setup("O")
+}
+
+// Opens the CAN channel in listen-only mode. The adapter neither sends frames nor acknowledges frames on the bus.
+func (a *Adapter) OpenListenOnly() error {
+	return a.setup("L")
+}
+
+// Closes the CAN channel
+func (a *Adapter) CloseChannel() error {
+	return a.setup("C")
+}
+
+// Turns timestamps on received frames on or off. Only allowed while the CAN channel is closed.
+func (a *Adapter) SetTimestamps(on bool) error {
+	if on {
+		return a.setup("Z1")
+	}
+	return a.setup("Z0")
+}
+
+// Hardware and software version ('V' command, reply "Vhhss")
+func (a *Adapter) Version() (string, error) {
+	return a.query("V")
+}
+
+// Detailed firmware version ('v' command)
+func (a *Adapter) DetailedVersion() (string, error) {
+	return a.query("v")
+}
+
+// Serial number ('N' command, reply "Nxxxx")
+func (a *Adapter) SerialNumber() (string, error) {
+	return a.query("N")
+}
+
+// Status flags ('F' command, reply "Fxx"). Only available while the CAN channel is open.
+func (a *Adapter) StatusFlags() (StatusFlags, error) {
+	reply, err := a.query("F")
+	if err != nil {
+		return 0, err
+	}
+
+	var flags byte
+	_, err = fmt.Sscanf(reply, "%02X", &flags)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected status flags '%s'", reply)
+	}
+	return StatusFlags(flags), nil
+}
@@ -0,0 +1,133 @@
+package slcan
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+// Port replying with a canned response to every write
+type cannedPort struct {
+	replies map[string]string
+	rx      bytes.Buffer
+	tx      bytes.Buffer
+}
+
+func (p *cannedPort) Read(b []byte) (int, error) {
+	return p.rx.Read(b)
+}
+
+func (p *cannedPort) Write(b []byte) (int, error) {
+	p.tx.Write(b)
+	p.rx.WriteString(p.replies[string(b)])
+	return len(b), nil
+}
+
+func (p *cannedPort) Close() error {
+	return nil
+}
+
+func newTestAdapter(replies map[string]string) *Adapter {
+	a := NewAdapter(&cannedPort{replies: replies})
+	a.SetReplyTimeout(10 * time.Millisecond)
+	return a
+}
+
+func TestAdapterQuery(t *testing.T) {
+	a := newTestAdapter(map[string]string{"V\r": "V1013\r", "N\r": "NA123\r", "F\r": "F28\r"})
+
+	version, err := a.Version()
+	if err != nil || version != "1013" {
+		t.Errorf("Unexpected version: '%s' (%v)", version, err)
+	}
+
+	serialNumber, err := a.SerialNumber()
+	if err != nil || serialNumber != "A123" {
+		t.Errorf("Unexpected serial number: '%s' (%v)", serialNumber, err)
+	}
+
+	flags, err := a.StatusFlags()
+	if err != nil || flags != STATUS_DATA_OVERRUN|STATUS_ERROR_PASSIVE {
+		t.Errorf("Unexpected status flags: %s (%v)", flags, err)
+	}
+}
+
+func TestAdapterBEL(t *testing.T) {
+	a := newTestAdapter(map[string]string{"S8\r": "\a", "O\r": ""})
+
+	var adapterErr *AdapterError
+	if err := a.SetBitrate(1000000); !errors.As(err, &adapterErr) {
+		t.Errorf("Expected AdapterError, got %v", err)
+	}
+
+	// Silent firmware
+	if err := a.Open(); err != nil {
+		t.Errorf("Expected silence to be accepted, got %v", err)
+	}
+
+	if _, err := a.Version(); err != ErrNoReply {
+		t.Errorf("Expected ErrNoReply, got %v", err)
+	}
+}
+
+func TestAdapterKeepsFramesReceivedDuringCommand(t *testing.T) {
+	frame := "T0200007F8" + "0000000000000000" + "\r"
+	a := newTestAdapter(map[string]string{"V\r": frame + "V1013\r"})
+
+	if _, err := a.Version(); err != nil {
+		t.Fatal(err)
+	}
+
+	line, err := a.ReadFrameLine(10 * time.Millisecond)
+	if err != nil || string(line) != frame {
+		t.Errorf("Unexpected frame line: '%s' (%v)", line, err)
+	}
+}
+
+func TestAdapterStripsTimestamp(t *testing.T) {
+	frame := "T0200007F8" + "0000000000000000"
+	a := newTestAdapter(map[string]string{"Z1\r": "\r" + frame + "EA5F\r"})
+
+	if err := a.SetTimestamps(true); err != nil {
+		t.Fatal(err)
+	}
+
+	line, err := a.ReadFrameLine(10 * time.Millisecond)
+	if err != nil || string(line) != frame+"\r" {
+		t.Fatalf("Unexpected frame line: '%s' (%v)", line, err)
+	}
+
+	if a.LastTimestamp() != 0xEA5F {
+		t.Errorf("Unexpected timestamp: %d", a.LastTimestamp())
+	}
+}
+
+func TestTruncated(t *testing.T) {
+	for line, truncated := range map[string]bool{
+		"T0200007F80000000000000000\r": false,
+		"T0200007F8000000000000000\r":  true,
+		"T0200007F0\r":                 false,
+		"t1230\r":                      false,
+		"t12\r":                        true,
+		"z\r":                          false,
+	} {
+		if Truncated([]byte(line)) != truncated {
+			t.Errorf("Truncated(%q) should be %v", line, truncated)
+		}
+	}
+}
+
+func TestFrameBits(t *testing.T) {
+	for line, bits := range map[string]int{
+		"T0200007F80000000000000000\r": 131,
+		"T0300007F0":                   67,
+		"t1232AABB\r":                  63,
+		"r1238\r":                      47,
+		"V1013\r":                      0,
+	} {
+		if FrameBits([]byte(line)) != bits {
+			t.Errorf("FrameBits(%q): expected %d, actual %d", line, bits, FrameBits([]byte(line)))
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package slcan
+
+import (
+	"encoding/hex"
+	"fmt"
+	"gocg/cybergear"
+	"strconv"
+)
+
+// SLCAN ASCII encoding of a CyberGear frame, without the terminating CR: T<8 hex digit id><dlc><2 hex digits per byte>
+//
+// For some reason, the SLCan implementation for MKS CANable and / or cybergear doesn't seem to be interested in the
+// checksum, so none is added.
+func Encode(frame *cybergear.Frame) []byte {
+	line := fmt.Sprintf("%c%08X%d%X", EXTENDED_FRAME, frame.Id(), frame.Len(), frame.Data())
+	return []byte(line)
+}
+
+// Decodes an extended SLCAN frame line (with or without trailing CR) into a CyberGear frame.
+// Remote frames are decoded with their DLC but without payload.
+func Decode(line []byte) (*cybergear.Frame, error) {
+	if len(line) > 0 && line[len(line)-1] == CR {
+		line = line[:len(line)-1]
+	}
+
+	if len(line) == 0 {
+		return nil, fmt.Errorf("no response frame received")
+	}
+
+	switch CANFrameType(line[CAN_FRAME_TYPE_INDEX]) {
+	case EXTENDED_FRAME, EXTENDED_RTR_FRAME:
+	case STANDARD_FRAME:
+		return nil, fmt.Errorf("standard CAN frame not supported")
+	case STANDARD_RTR_FRAME:
+		return nil, fmt.Errorf("standard RTR CAN frame not supported")
+	default:
+		return nil, fmt.Errorf("invalid frame received : %+v", line)
+	}
+
+	if len(line) < EXTENDED_HEADER_SIZE {
+		return nil, fmt.Errorf("frame too short: '%s'", line)
+	}
+
+	id, err := strconv.ParseUint(string(line[1:9]), 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CAN id in '%s'", line)
+	}
+
+	frame := &cybergear.Frame{}
+	err = frame.SetId(uint32(id))
+	if err != nil {
+		return nil, err
+	}
+
+	dlc := int(line[9]) - '0'
+	err = frame.SetLen(dlc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid DLC in '%s'", line)
+	}
+
+	if CANFrameType(line[CAN_FRAME_TYPE_INDEX]) == EXTENDED_RTR_FRAME {
+		return frame, nil
+	}
+
+	if len(line) != EXTENDED_HEADER_SIZE+2*dlc {
+		return nil, fmt.Errorf("frame length doesn't match DLC %d: '%s'", dlc, line)
+	}
+
+	data, err := hex.DecodeString(string(line[EXTENDED_HEADER_SIZE:]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid data in '%s'", line)
+	}
+
+	err = frame.SetData(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return frame, nil
+}
@@ -0,0 +1,278 @@
+package slcan
+
+import (
+	"gocg/cybergear"
+	"math"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestEncodeNoPayload(t *testing.T) {
+	frame, err := cybergear.EnableMotorCmd(0x64, 0x7F)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []byte{0x54, 0x30, 0x33, 0x30, 0x30, 0x36, 0x34, 0x37, 0x46, 0x30}
+	if !slices.Equal(expected, Encode(frame)) {
+		t.Fatalf("Wrong frame. Expected: %s, Actual: %s", expected, Encode(frame))
+	}
+}
+
+func TestEncodeWithFullPayload(t *testing.T) {
+	frame, err := cybergear.WriteParameterCmd(0x00, 0x7F, cybergear.PARAMETER_SPD_REF, 1.12)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []byte{0x54, 0x31, 0x32, 0x30, 0x30, 0x30, 0x30, 0x37, 0x46, 0x38, 0x30, 0x41, 0x37, 0x30, 0x30, 0x30, 0x30, 0x30, 0x32, 0x39, 0x35, 0x43, 0x38, 0x46, 0x33, 0x46}
+	if !slices.Equal(expected, Encode(frame)) {
+		t.Fatalf("Wrong frame. Expected: %s, Actual: %s", expected, Encode(frame))
+	}
+}
+
+func TestEncodePartialPayload(t *testing.T) {
+	frame := cybergear.NewFrame(cybergear.COMMUNICATION_GET_STATUS)
+	frame.SetData([]byte{0x01, 0x02, 0x03})
+
+	b := Encode(frame)
+	if len(b) != EXTENDED_HEADER_SIZE+2*3 {
+		t.Fatalf("Unexpected length of serialized frame (%d). DLC=3 => header + 6 characters should be serialized", len(b))
+	}
+}
+
+func TestDecode(t *testing.T) {
+	frame, err := Decode([]byte("T1200007F80A700000295C8F3F\r"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if frame.CommunicationType() != cybergear.COMMUNICATION_WRITE_SINGLE_PARAM || frame.TargetId() != 0x7F || frame.Uint16(0) != 0x700A || frame.Float32(4) != 1.12 {
+		t.Fatalf("Unexpected frame: %s", frame)
+	}
+
+	for _, line := range []string{"", "T1200007F", "T1200007F9", "T1200007F80A70", "T1200007G0", "t1230", "X"} {
+		if _, err := Decode([]byte(line)); err == nil {
+			t.Errorf("Expected error decoding '%s'", line)
+		}
+	}
+}
+
+// The angle spans [-4pi, 4pi] rad, speed [-30, 30] rad/s and torque [-12, 12] Nm
+func TestFeedbackRanges(t *testing.T) {
+	for _, c := range []struct {
+		line                 string
+		angle, speed, torque float64
+	}{
+		{"T02807F0080000000000000000\r", -4 * math.Pi, -30, -12},
+		{"T02807F008FFFFFFFFFFFF0000\r", 4 * math.Pi, 30, 12},
+	} {
+		frame, err := HandleIncomingFrame([]byte(c.line))
+		if err != nil {
+			t.Fatal(err)
+		}
+		f := frame.(*MotorFeedback)
+		if math.Abs(float64(f.Angle())-c.angle) > 1e-5 || math.Abs(float64(f.Speed())-c.speed) > 1e-5 || math.Abs(float64(f.Torque())-c.torque) > 1e-5 {
+			t.Errorf("%q decoded as %s", c.line, f)
+		}
+	}
+}
+
+func TestHandleIncomingFeedbackFrame(t *testing.T) {
+	// Motor 7F to host 00, run mode, overtemperature. Angle 0 rad, speed 0 rad/s, torque 0 Nm, 27.2 C
+	frame, err := HandleIncomingFrame([]byte("T02847F00880007FFF7FFF0110\r"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	feedback, ok := frame.(*MotorFeedback)
+	if !ok {
+		t.Fatalf("Unexpected frame type %T", frame)
+	}
+
+	if feedback.MotorId() != 0x7F || feedback.HostId() != 0x00 {
+		t.Errorf("Unexpected ids: motor %02X, host %02X", feedback.MotorId(), feedback.HostId())
+	}
+
+	if feedback.Mode() != OperatingMode || !feedback.Fault() || !feedback.overtemperature || feedback.overcurrent {
+		t.Errorf("Unexpected status: mode %d, faults %v", feedback.Mode(), feedback.Faults())
+	}
+
+	if feedback.Temperature() != 27.2 || feedback.Angle() < -0.001 || feedback.Angle() > 0.001 {
+		t.Errorf("Unexpected values: %s", feedback)
+	}
+}
+
+func TestHandleIncomingDeviceIdFrame(t *testing.T) {
+	// Motor 7F answering a device id request
+	frame, err := HandleIncomingFrame([]byte("T00007FFE87F00000053554256\r"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, ok := frame.(*DeviceIdFrame)
+	if !ok || id.MotorId() != 0x7F || id.McuId() != "7F00000053554256" {
+		t.Fatalf("Unexpected frame %v", frame)
+	}
+
+	// The request itself isn't a device id frame
+	if _, err := HandleIncomingFrame([]byte("T0000017F0\r")); err == nil {
+		t.Error("Expected error decoding a device id request")
+	}
+}
+
+func TestHandleIncomingFaultFrame(t *testing.T) {
+	// Motor 7F to host 01, overvoltage and not calibrated, overtemperature warning
+	frame, err := HandleIncomingFrame([]byte("T15007F0188800000001000000\r"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fault, ok := frame.(*FaultFrame)
+	if !ok {
+		t.Fatalf("Unexpected frame type %T", frame)
+	}
+
+	if fault.MotorId() != 0x7F || fault.HostId() != 0x01 {
+		t.Errorf("Unexpected ids: motor %02X, host %02X", fault.MotorId(), fault.HostId())
+	}
+
+	if fault.Raw() != FAULT_OVERVOLTAGE|FAULT_NOT_CALIBRATED || strings.Join(fault.Faults(), ", ") != "overvoltage, not calibrated" ||
+		strings.Join(fault.Warnings(), ", ") != "overtemperature warning" {
+		t.Errorf("Unexpected faults: %s", fault)
+	}
+}
+
+// Frames of every builder, for random ids and values, come out of Encode and Decode as they went in
+func TestBuilderRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	between := func(min float64, max float64) float64 {
+		return min + r.Float64()*(max-min)
+	}
+
+	roundTrip := func(frame *cybergear.Frame, err error) *cybergear.Frame {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := Decode(append(Encode(frame), CR))
+		if err != nil {
+			t.Fatalf("%s: %v", frame, err)
+		}
+		if *decoded != *frame {
+			t.Fatalf("%s decoded as %s", frame, decoded)
+		}
+		return decoded
+	}
+
+	checkIds := func(frame *cybergear.Frame, communicationType cybergear.CommunicationType, hostId byte, motorId byte) {
+		t.Helper()
+		if frame.CommunicationType() != communicationType || frame.HostId() != hostId || frame.TargetId() != motorId {
+			t.Fatalf("Expected type %d from %02X to %02X: %s", communicationType, hostId, motorId, frame)
+		}
+	}
+
+	var settable []cybergear.ParameterInfo
+	for _, p := range cybergear.Parameters() {
+		if p.Writable && !p.Config() {
+			settable = append(settable, p)
+		}
+	}
+
+	for i := 0; i < 1000; i++ {
+		hostId := byte(r.Intn(cybergear.MAX_CAN_ID + 1))
+		motorId := byte(r.Intn(cybergear.MAX_CAN_ID + 1))
+
+		frame := roundTrip(cybergear.EnableMotorCmd(hostId, motorId))
+		checkIds(frame, cybergear.COMMUNICATION_ENABLE_DEVICE, hostId, motorId)
+		frame = roundTrip(cybergear.DisableMotorCmd(hostId, motorId))
+		checkIds(frame, cybergear.COMMUNICATION_DISABLE_DEVICE, hostId, motorId)
+		frame = roundTrip(cybergear.GetStatusCmd(hostId, motorId))
+		checkIds(frame, cybergear.COMMUNICATION_GET_STATUS, hostId, motorId)
+
+		mode := cybergear.SPEED_MODE
+		switch r.Intn(4) {
+		case 0:
+			mode = cybergear.OPEARATION_CONTROL_MODE
+		case 1:
+			mode = cybergear.LOCATION_MODE
+		case 2:
+			mode = cybergear.CURRENT_MODE
+		}
+		frame = roundTrip(cybergear.SetRunMode(hostId, motorId, mode))
+		if frame.Uint16(0) != uint16(cybergear.PARAMETER_RUN_MODE) || frame.Uint32(4) != uint32(mode) {
+			t.Fatalf("Run mode %d: %s", mode, frame)
+		}
+
+		speed := float32(between(-30, 30))
+		frame = roundTrip(cybergear.WriteParameterCmd(hostId, motorId, cybergear.PARAMETER_SPD_REF, speed))
+		if frame.Uint16(0) != uint16(cybergear.PARAMETER_SPD_REF) || frame.Float32(4) != speed {
+			t.Fatalf("Speed %g: %s", speed, frame)
+		}
+
+		frame = roundTrip(cybergear.ReadSingleParameterFrame(hostId, motorId, cybergear.PARAMETER_MECH_POS))
+		if frame.Uint16(0) != uint16(cybergear.PARAMETER_MECH_POS) {
+			t.Fatalf("Read PARAMETER_MECH_POS: %s", frame)
+		}
+
+		p := settable[r.Intn(len(settable))]
+		frame = roundTrip(cybergear.ReadParameterCmd(hostId, motorId, p))
+		if frame.Uint16(0) != p.Index || frame.Len() != 8 {
+			t.Fatalf("Read %s: %s", p.Name, frame)
+		}
+
+		min, max := -1000.0, 1000.0
+		switch {
+		case p.Ranged:
+			min, max = p.Min, p.Max
+		case p.Type == cybergear.PARAMETER_TYPE_UINT8:
+			min, max = 0, math.MaxUint8
+		case p.Type == cybergear.PARAMETER_TYPE_UINT16 || p.Type == cybergear.PARAMETER_TYPE_UINT32:
+			min = 0
+		}
+		value := float64(float32(between(min, max)))
+		if p.Type != cybergear.PARAMETER_TYPE_FLOAT {
+			value = math.Round(value)
+		}
+		// float32 rounding can step over the range
+		if p.InRange(value) {
+			frame = roundTrip(cybergear.WriteParameterValueCmd(hostId, motorId, p, value))
+			if frame.Uint16(0) != p.Index || p.Decode(frame.Uint32(4)) != value {
+				t.Fatalf("Write %s = %g: %s", p.Name, value, frame)
+			}
+		}
+
+		communicationType := cybergear.CommunicationType(r.Intn(0x20))
+		data := uint16(r.Intn(0x10000))
+		payload := make([]byte, r.Intn(9))
+		r.Read(payload)
+		frame = roundTrip(cybergear.CustomFrameCmd(communicationType, motorId, data, payload))
+		checkIds(frame, communicationType, byte(data), motorId)
+		if frame.DataArea() != data || !slices.Equal(frame.Data(), payload) {
+			t.Fatalf("Custom frame %04X % X: %s", data, payload, frame)
+		}
+
+		// Motion control values come back within half a step of the 16 bit scale
+		angle, speedRef, kp, kd, torque := between(-4*math.Pi, 4*math.Pi), between(-30, 30), between(0, 500), between(0, 5), between(-12, 12)
+		frame = roundTrip(cybergear.MotionControlCmd(motorId, float32(angle), float32(speedRef), float32(kp), float32(kd), float32(torque)))
+		for _, v := range []struct {
+			name     string
+			value    float64
+			raw      uint16
+			min, max float64
+		}{
+			{"angle", angle, frame.BigEndianUint16(0), -4 * math.Pi, 4 * math.Pi},
+			{"speed", speedRef, frame.BigEndianUint16(2), -30, 30},
+			{"kp", kp, frame.BigEndianUint16(4), 0, 500},
+			{"kd", kd, frame.BigEndianUint16(6), 0, 5},
+			{"torque", torque, frame.DataArea(), -12, 12},
+		} {
+			step := (v.max - v.min) / 65535
+			if decoded := float64(v.raw)*step + v.min; math.Abs(decoded-v.value) > step*0.51 {
+				t.Fatalf("Motion control %s %g decoded as %g: %s", v.name, v.value, decoded, frame)
+			}
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package slcan
+
+import (
+	"fmt"
+	"gocg/cybergear"
+)
+
+// Target id of a device id reply (communication type 0) instead of the host CAN id
+const DEVICE_ID_TARGET = 0xFE
+
+// Reply to a device id request (communication type 0), with the 64 bit MCU unique identifier
+type DeviceIdFrame struct {
+	hostId  byte // Always DEVICE_ID_TARGET
+	motorId byte // Motor CAN Id
+	mcuId   [8]byte
+}
+
+func (f *DeviceIdFrame) CyberGearFrameType() cybergear.CommunicationType {
+	return cybergear.COMMUNICATION_FETCH_DEVICE_ID
+}
+
+func (f *DeviceIdFrame) HostId() byte {
+	return f.hostId
+}
+
+func (f *DeviceIdFrame) MotorId() byte {
+	return f.motorId
+}
+
+// MCU unique identifier, byte 0 - 7 of the payload as sent
+func (f *DeviceIdFrame) McuId() string {
+	return fmt.Sprintf("%X", f.mcuId[:])
+}
+
+func (f *DeviceIdFrame) String() string {
+	return fmt.Sprintf("Motor %02X: MCU id %s", f.motorId, f.McuId())
+}
+
+func (f *DeviceIdFrame) Unmarshal(frame *cybergear.Frame) error {
+	if frame.CommunicationType() != cybergear.COMMUNICATION_FETCH_DEVICE_ID {
+		return fmt.Errorf("not a device id frame (type %d)", int(frame.CommunicationType()))
+	}
+
+	// The request has the host CAN id in bit 8 - 15 and the motor CAN id in bit 0 - 7, the reply the motor CAN id
+	// and 0xFE
+	if frame.TargetId() != DEVICE_ID_TARGET {
+		return fmt.Errorf("device id request, not a reply (target %02X)", frame.TargetId())
+	}
+
+	if frame.Len() != 8 {
+		return fmt.Errorf("invalid device id frame length (%d)", frame.Len())
+	}
+
+	f.motorId = frame.HostId()
+	f.hostId = frame.TargetId()
+	copy(f.mcuId[:], frame.Data())
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package slcan
+
+import (
+	"encoding/binary"
+	"fmt"
+	"gocg/cybergear"
+	"strings"
+)
+
+// Fault bits in byte 0 - 3 of the fault frame
+const (
+	FAULT_OVERTEMPERATURE     = 1 << 0
+	FAULT_DRIVER_CHIP         = 1 << 1
+	FAULT_UNDERVOLTAGE        = 1 << 2
+	FAULT_OVERVOLTAGE         = 1 << 3
+	FAULT_PHASE_B_OVERCURRENT = 1 << 4
+	FAULT_PHASE_C_OVERCURRENT = 1 << 5
+	FAULT_NOT_CALIBRATED      = 1 << 7
+	FAULT_OVERLOAD            = 0xFF << 8
+	FAULT_PHASE_A_OVERCURRENT = 1 << 16
+)
+
+// Warning bits in byte 4 - 7 of the fault frame
+const (
+	WARNING_OVERTEMPERATURE = 1 << 0
+)
+
+var faultNames = []struct {
+	mask uint32
+	name string
+}{
+	{FAULT_OVERTEMPERATURE, "overtemperature"},
+	{FAULT_DRIVER_CHIP, "driver chip fault"},
+	{FAULT_UNDERVOLTAGE, "undervoltage"},
+	{FAULT_OVERVOLTAGE, "overvoltage"},
+	{FAULT_PHASE_A_OVERCURRENT, "phase A overcurrent"},
+	{FAULT_PHASE_B_OVERCURRENT, "phase B overcurrent"},
+	{FAULT_PHASE_C_OVERCURRENT, "phase C overcurrent"},
+	{FAULT_NOT_CALIBRATED, "not calibrated"},
+	{FAULT_OVERLOAD, "overload"},
+}
+
+// Fault report a motor sends on its own (communication type 21), with every fault and warning active at the time
+type FaultFrame struct {
+	hostId   byte // Host CAN Id
+	motorId  byte // Motor CAN Id
+	faults   uint32
+	warnings uint32
+}
+
+func (f *FaultFrame) CyberGearFrameType() cybergear.CommunicationType {
+	return cybergear.COMMUNICATION_ERROR_REPORT
+}
+
+func (f *FaultFrame) HostId() byte {
+	return f.hostId
+}
+
+func (f *FaultFrame) MotorId() byte {
+	return f.motorId
+}
+
+// Fault bits (FAULT_*)
+func (f *FaultFrame) Raw() uint32 {
+	return f.faults
+}
+
+// Warning bits (WARNING_*)
+func (f *FaultFrame) RawWarnings() uint32 {
+	return f.warnings
+}
+
+func (f *FaultFrame) Faults() []string {
+	var faults []string
+	for _, fault := range faultNames {
+		if f.faults&fault.mask != 0 {
+			faults = append(faults, fault.name)
+		}
+	}
+	return faults
+}
+
+func (f *FaultFrame) Warnings() []string {
+	var warnings []string
+	if f.warnings&WARNING_OVERTEMPERATURE != 0 {
+		warnings = append(warnings, "overtemperature warning")
+	}
+	return warnings
+}
+
+func (f *FaultFrame) String() string {
+	active := append(f.Faults(), f.Warnings()...)
+	if len(active) == 0 {
+		return fmt.Sprintf("Motor %02X -> host %02X: no faults", f.motorId, f.hostId)
+	}
+	return fmt.Sprintf("Motor %02X -> host %02X: [red]faults : %s[-]", f.motorId, f.hostId, strings.Join(active, ", "))
+}
+
+func (f *FaultFrame) Unmarshal(frame *cybergear.Frame) error {
+	if frame.CommunicationType() != cybergear.COMMUNICATION_ERROR_REPORT {
+		return fmt.Errorf("not a fault frame (type %d)", int(frame.CommunicationType()))
+	}
+
+	if frame.Len() != 8 {
+		return fmt.Errorf("invalid fault frame length (%d)", frame.Len())
+	}
+
+	// bit 8 - 15 motor CAN ID, bit 0 - 7 host CAN ID, like the feedback frame
+	f.motorId = frame.HostId()
+	f.hostId = frame.TargetId()
+	f.faults = binary.LittleEndian.Uint32(frame.Data()[0:4])
+	f.warnings = binary.LittleEndian.Uint32(frame.Data()[4:8])
+
+	return nil
+}
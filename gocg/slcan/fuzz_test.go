@@ -0,0 +1,181 @@
+package slcan
+
+import (
+	"bytes"
+	"gocg/cybergear"
+	"testing"
+	"time"
+)
+
+// Frame lines as they come from the adapter, including broken ones
+var seedLines = []string{
+	"T02847F00880007FFF7FFF0110\r",
+	"T1100007F8057000000CDCC4C3E\r",
+	"T0900007F8001200000000000\r",
+	"T1200007F80A700000295C8F3F",
+	"T0300017F0\r",
+	"R0300017F0\r",
+	"t1230\r",
+	"t7FF80102030405060708\r",
+	"r1232\r",
+	"T02847F00880007FFF7FFF01101A2B\r",
+	"T02847F0088",
+	"T\r",
+	"t",
+	"\r",
+	"",
+}
+
+func FuzzDecode(f *testing.F) {
+	for _, line := range seedLines {
+		f.Add([]byte(line))
+	}
+	f.Fuzz(func(t *testing.T, line []byte) {
+		frame, err := Decode(line)
+		if err != nil {
+			return
+		}
+		if frame.Len() > 8 || frame.Id() > cybergear.MAX_EXTENDED_ID {
+			t.Fatalf("Invalid frame %s from '%s'", frame, line)
+		}
+
+		// Data frames survive a round trip
+		if line[CAN_FRAME_TYPE_INDEX] == byte(EXTENDED_FRAME) {
+			again, err := Decode(Encode(frame))
+			if err != nil || *again != *frame {
+				t.Fatalf("'%s' decoded as %s, then as %s (%v)", line, frame, again, err)
+			}
+		}
+	})
+}
+
+func FuzzHandleIncomingFrame(f *testing.F) {
+	for _, line := range seedLines {
+		f.Add([]byte(line))
+	}
+	f.Fuzz(func(t *testing.T, line []byte) {
+		frame, err := HandleIncomingFrame(line)
+		if err != nil {
+			return
+		}
+		_ = frame.String()
+		if feedback, ok := frame.(*MotorFeedback); ok {
+			checkFeedback(t, feedback)
+		}
+	})
+}
+
+func FuzzHandleFrame(f *testing.F) {
+	f.Add(uint32(0x02847F00), []byte{0x80, 0x00, 0x7F, 0xFF, 0x7F, 0xFF, 0x01, 0x10})
+	f.Add(uint32(0x1100007F), []byte{0x05, 0x70, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00})
+	f.Add(uint32(0x0900007F), []byte{0x00, 0x20, 0x01, 0x00, 0x63, 0x67, 0x00, 0x00})
+	f.Add(uint32(0x0200007F), []byte{})
+	f.Add(uint32(0x15007F01), []byte{0x88, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00})
+	f.Add(uint32(0x00007FFE), []byte{0x7F, 0x00, 0x00, 0x00, 0x53, 0x55, 0x42, 0x56})
+	f.Fuzz(func(t *testing.T, id uint32, data []byte) {
+		frame := &cybergear.Frame{}
+		if frame.SetId(id) != nil || frame.SetData(data) != nil {
+			return
+		}
+
+		decoded, err := HandleFrame(frame)
+		if err != nil {
+			return
+		}
+		_ = decoded.String()
+		if decoded.HostId() != frame.TargetId() || decoded.MotorId() != frame.HostId() {
+			t.Fatalf("Ids of %s decoded as host %02X, motor %02X", frame, decoded.HostId(), decoded.MotorId())
+		}
+
+		switch decoded := decoded.(type) {
+		case *MotorFeedback:
+			checkFeedback(t, decoded)
+		case *ParameterFrame:
+			if decoded.Index() != frame.Uint16(0) || decoded.Raw() != frame.Uint32(4) {
+				t.Fatalf("%s decoded as %s", frame, decoded)
+			}
+		}
+	})
+}
+
+// The feedback values are within the ranges of the protocol
+func checkFeedback(t *testing.T, f *MotorFeedback) {
+	t.Helper()
+	const pi = 3.14159275 // float32(math.Pi) rounds up
+	switch {
+	case f.Angle() < -4*pi || f.Angle() > 4*pi,
+		f.Speed() < -30 || f.Speed() > 30,
+		f.Torque() < -12 || f.Torque() > 12,
+		f.Temperature() < 0 || f.Temperature() > 6553.5,
+		f.Mode() < ResetMode || f.Mode() > 3:
+		t.Fatalf("Feedback out of range: %s, mode %d", f, f.Mode())
+	}
+}
+
+func FuzzInspect(f *testing.F) {
+	for _, line := range seedLines {
+		f.Add([]byte(line))
+	}
+	f.Fuzz(func(t *testing.T, line []byte) {
+		info, err := Inspect(line)
+		if err != nil {
+			return
+		}
+		_ = info.Describe(0x00)
+		if info.Extended() {
+			_ = info.MotorId(0x00)
+		}
+		if len(info.Data) > 8 {
+			t.Fatalf("'%s' has %d data bytes", line, len(info.Data))
+		}
+	})
+}
+
+// Line helpers run on everything the adapter sends, before any decoder
+func FuzzFrameLine(f *testing.F) {
+	for _, line := range seedLines {
+		f.Add([]byte(line))
+	}
+	f.Fuzz(func(t *testing.T, line []byte) {
+		original := append([]byte{}, line...)
+
+		bits := FrameBits(line)
+		if bits != 0 && (bits < 47 || bits > 67+64) {
+			t.Fatalf("'%s' has %d bits", line, bits)
+		}
+		Truncated(line)
+
+		a := &Adapter{}
+		stripped := a.stripTimestamp(line)
+		if len(stripped) > len(line) {
+			t.Fatalf("'%s' grew to '%s'", line, stripped)
+		}
+		if !bytes.Equal(line, original) {
+			t.Fatalf("'%s' modified to '%s'", original, line)
+		}
+	})
+}
+
+// Whatever the adapter sends in reply to a command, up to the decoded frames
+func FuzzAdapterOutput(f *testing.F) {
+	f.Add([]byte("F00\r"))
+	f.Add([]byte("T02847F00880007FFF7FFF0110\rF28\r"))
+	f.Add([]byte("T02847F00880007FFF7FFF01101A2B\rt1230\a"))
+	f.Add([]byte("F\r\r\a"))
+	f.Fuzz(func(t *testing.T, output []byte) {
+		a := NewAdapter(&cannedPort{replies: map[string]string{"F\r": string(output)}})
+		a.SetReplyTimeout(time.Millisecond)
+		a.StatusFlags()
+
+		for {
+			line, err := a.ReadFrameLine(0)
+			if err != nil {
+				return
+			}
+			if !IsFrameLine(line) {
+				t.Fatalf("ReadFrameLine returned '%q'", line)
+			}
+			HandleIncomingFrame(line)
+		}
+	})
+}
@@ -0,0 +1,150 @@
+package slcan
+
+import (
+	"encoding/hex"
+	"fmt"
+	"gocg/cybergear"
+	"strconv"
+	"strings"
+)
+
+// A CAN frame line from the adapter split into its fields. Used for sniffing, where frames in both
+// directions and of every communication type show up.
+type FrameInfo struct {
+	FrameType CANFrameType
+	Id        uint32 // 29 bit (extended) or 11 bit (standard) CAN id
+	Data      []byte
+	Frame     *cybergear.Frame // Extended frames only
+	Decoded   Frame            // Set if one of the frame decoders knows the frame
+}
+
+func (i *FrameInfo) CommunicationType() cybergear.CommunicationType {
+	return i.Frame.CommunicationType()
+}
+
+func (i *FrameInfo) DataArea() uint16 {
+	return i.Frame.DataArea()
+}
+
+func (i *FrameInfo) TargetId() byte {
+	return i.Frame.TargetId()
+}
+
+// Frames sent by a motor carry the motor CAN id in bit 8 - 15, frames sent to a motor in bit 0 - 7.
+func (i *FrameInfo) FromMotor(hostId byte) bool {
+	switch i.CommunicationType() {
+	case cybergear.COMMUNICATION_STATUS_REPORT, cybergear.COMMUNICATION_ERROR_REPORT:
+		return true
+	case cybergear.COMMUNICATION_FETCH_DEVICE_ID:
+		return i.TargetId() == 0xFE
+	case cybergear.COMMUNICATION_READ_SINGLE_PARAM, cybergear.COMMUNICATION_READ_CONFIG_PARAM:
+		return i.TargetId() == hostId
+	}
+	return false
+}
+
+// CAN id of the motor the frame was sent by or sent to
+func (i *FrameInfo) MotorId(hostId byte) byte {
+	if i.FromMotor(hostId) {
+		return i.Frame.HostId()
+	}
+	return i.TargetId()
+}
+
+func (i *FrameInfo) Extended() bool {
+	return i.FrameType == EXTENDED_FRAME || i.FrameType == EXTENDED_RTR_FRAME
+}
+
+// Splits a frame line (with or without trailing CR) into its fields. Extended data frames are passed on to
+// HandleFrame, and if it knows the frame, the decoded frame is available in Decoded.
+func Inspect(line []byte) (*FrameInfo, error) {
+	line = []byte(strings.TrimRight(string(line), "\r"))
+	if !IsFrameLine(line) {
+		return nil, fmt.Errorf("not a frame: '%s'", line)
+	}
+
+	info := &FrameInfo{FrameType: CANFrameType(line[0])}
+
+	if info.Extended() {
+		frame, err := Decode(line)
+		if err != nil {
+			return nil, err
+		}
+		info.Frame = frame
+		info.Id = frame.Id()
+		info.Data = frame.Data()
+
+		if info.FrameType == EXTENDED_FRAME {
+			decoded, err := HandleFrame(frame)
+			if err == nil {
+				info.Decoded = decoded
+			}
+		}
+		return info, nil
+	}
+
+	if len(line) < 1+3+1 {
+		return nil, fmt.Errorf("frame too short: '%s'", line)
+	}
+
+	id, err := strconv.ParseUint(string(line[1:4]), 16, 11)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CAN id in '%s'", line)
+	}
+	info.Id = uint32(id)
+
+	dlc := int(line[4]) - '0'
+	if dlc < 0 || dlc > 8 {
+		return nil, fmt.Errorf("invalid DLC in '%s'", line)
+	}
+
+	if info.FrameType == STANDARD_RTR_FRAME {
+		return info, nil
+	}
+
+	if len(line) != 1+3+1+2*dlc {
+		return nil, fmt.Errorf("frame length doesn't match DLC %d: '%s'", dlc, line)
+	}
+	info.Data, err = hex.DecodeString(string(line[5:]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid data in '%s'", line)
+	}
+
+	return info, nil
+}
+
+// One line description of the frame
+func (i *FrameInfo) Describe(hostId byte) string {
+	if !i.Extended() {
+		return fmt.Sprintf("standard frame %03X [%d] % X", i.Id, len(i.Data), i.Data)
+	}
+
+	var s string
+	commType := i.CommunicationType()
+	if i.FromMotor(hostId) {
+		s = fmt.Sprintf("%-14s %02X -> %02X", commType, i.MotorId(hostId), i.TargetId())
+	} else {
+		s = fmt.Sprintf("%-14s %02X -> %02X", commType, i.Frame.HostId(), i.TargetId())
+	}
+
+	if i.Decoded != nil {
+		return s + " : " + i.Decoded.String()
+	}
+
+	switch commType {
+	case cybergear.COMMUNICATION_READ_SINGLE_PARAM, cybergear.COMMUNICATION_WRITE_SINGLE_PARAM,
+		cybergear.COMMUNICATION_READ_CONFIG_PARAM, cybergear.COMMUNICATION_WRITE_CONFIG_PARAM:
+		if i.Frame.Len() == 8 {
+			return s + fmt.Sprintf(" : index 0x%04X, value % X", i.Frame.Uint16(0), i.Data[4:])
+		}
+	case cybergear.COMMUNICATION_MOTION_CONTROL_COMMAND:
+		return s + fmt.Sprintf(" : torque 0x%04X, data % X", i.DataArea(), i.Data)
+	case cybergear.COMMUNICATION_SET_CAN_ID:
+		return s + fmt.Sprintf(" : new CAN id %02X", i.DataArea()>>8)
+	}
+
+	if len(i.Data) > 0 {
+		s += fmt.Sprintf(" : % X", i.Data)
+	}
+	return s
+}
@@ -3,7 +3,8 @@ package slcan
 import (
 	"fmt"
 	"gocg/cybergear"
-	"strconv"
+	"math"
+	"strings"
 )
 
 type MotorMode int32
@@ -42,89 +43,114 @@ func (f *MotorFeedback) MotorId() byte {
 	return f.motorId
 }
 
-func (f *MotorFeedback) ParseByte(ascii []byte) (byte, error) {
-	num, err := strconv.ParseInt(string(ascii), 16, 16)
-	return byte(num), err
+// Current angle (rad)
+func (f *MotorFeedback) Angle() float32 {
+	return f.currentAngle
 }
 
-func (f *MotorFeedback) ParseInt(ascii []byte) (int32, error) {
-	var err error
-	num, err := strconv.ParseUint(string(ascii), 16, 16)
-	return int32(num), err
+// Current angular velocity (rad/s)
+func (f *MotorFeedback) Speed() float32 {
+	return f.currentSpeed
 }
 
-func (f *MotorFeedback) Unmarshal(frameBuffer []byte) error {
-	var err error
+// Current torque (Nm)
+func (f *MotorFeedback) Torque() float32 {
+	return f.currentTorque
+}
 
-	f.hostId, err = f.ParseByte(frameBuffer[HOST_ID_INDEX : HOST_ID_INDEX+2])
-	if err != nil {
-		return err
-	}
-	f.motorId, err = f.ParseByte(frameBuffer[MOTOR_ID_INDEX : MOTOR_ID_INDEX+2])
-	if err != nil {
-		return err
-	}
-	var dlc byte
-	dlc, err = f.ParseByte(frameBuffer[DLC_INDEX : DLC_INDEX+2])
-	if err != nil {
-		return err
+// Current temperature (degrees Celsius)
+func (f *MotorFeedback) Temperature() float32 {
+	return f.currentTemperature
+}
+
+// Fault bits in data area 2 of the feedback frame
+const (
+	FEEDBACK_UNDERVOLTAGE      = 1 << 8
+	FEEDBACK_OVERCURRENT       = 1 << 9
+	FEEDBACK_OVERTEMPERATURE   = 1 << 10
+	FEEDBACK_MAGNETIC_ENCODING = 1 << 11
+	FEEDBACK_HALL_ENCODING     = 1 << 12
+	FEEDBACK_NOT_CALIBRATED    = 1 << 13
+)
+
+func (f *MotorFeedback) Unmarshal(frame *cybergear.Frame) error {
+	if frame.CommunicationType() != cybergear.COMMUNICATION_STATUS_REPORT {
+		return fmt.Errorf("not a feedback frame (type %d)", int(frame.CommunicationType()))
 	}
-	if dlc != 8 {
-		return fmt.Errorf("unexpected DLC (%d). Expected DLC of 8", dlc)
+
+	if frame.Len() != 8 {
+		return fmt.Errorf("unexpected DLC (%d). Expected DLC of 8", frame.Len())
 	}
 
-	var num int32
+	// bit 8 - 15 motor CAN ID, bit 0 - 7 host CAN ID
+	f.motorId = frame.HostId()
+	f.hostId = frame.TargetId()
+
+	// bit 16 - 21 faults, bit 22 - 23 mode (0: reset, 1: calibration: 2: run mode )
+	status := frame.DataArea()
+	f.undervoltage = status&FEEDBACK_UNDERVOLTAGE != 0
+	f.overcurrent = status&FEEDBACK_OVERCURRENT != 0
+	f.overtemperature = status&FEEDBACK_OVERTEMPERATURE != 0
+	f.magneticEncodingError = status&FEEDBACK_MAGNETIC_ENCODING != 0
+	f.hallEncoderError = status&FEEDBACK_HALL_ENCODING != 0
+	f.calibrationError = status&FEEDBACK_NOT_CALIBRATED != 0
+	f.mode = MotorMode(status >> 14 & 0x03)
+
 	// Data 00-01: Current angle [0-65535] == [-4PI, 4PI]
-	num, err = f.ParseInt(frameBuffer[CURRENT_ANGLE_INDEX : CURRENT_ANGLE_INDEX+4])
-	if err != nil {
-		return err
-	}
-	f.currentAngle = 8*float32(num)/65535 - 4
+	f.currentAngle = 8*math.Pi*float32(frame.BigEndianUint16(0))/65535 - 4*math.Pi
 
 	// Data 02-03: Current angular velocity [0-65535] == [-30 rad/s, 30 rad/s]
-	num, err = f.ParseInt(frameBuffer[CURRENT_ANGULAR_VELOCITY_INDEX : CURRENT_ANGULAR_VELOCITY_INDEX+4])
-	if err != nil {
-		return err
-	}
-	f.currentSpeed = 60*float32(num)/65535 - 30
+	f.currentSpeed = 60*float32(frame.BigEndianUint16(2))/65535 - 30
 
 	// Data 04-05: Current torque [0-65535] == [-12Nm, 12Nm]
-	num, err = f.ParseInt(frameBuffer[CURRENT_TORQUE_INDEX : CURRENT_TORQUE_INDEX+4])
-	if err != nil {
-		return err
-	}
-	f.currentTorque = 24*float32(num)/65535 - 12
+	f.currentTorque = 24*float32(frame.BigEndianUint16(4))/65535 - 12
 
 	// Data 06-07: Current temperature (C * 10)
-	num, err = f.ParseInt(frameBuffer[CURRENT_TEMPERATURE_INDEX : CURRENT_TEMPERATURE_INDEX+4])
-	if err != nil {
-		return err
-	}
-	f.currentTemperature = float32(num) / 10
-
-	/*
-		// 	// bit 28 - 24 communication type
-		// 	// bit 8 - 15 motor CAN ID
+	f.currentTemperature = float32(frame.BigEndianUint16(6)) / 10
 
-		TODO: Decode bit fields
-		// 	// bit 21 - not calibrated
-		// 	// bit 20 - hall encoding fault
-		// 	// bit 19 - magnetic encoding error
-		// 	// bit 18 - overtemperature
-		// 	// bit 17 - overcurrent
-		// 	// bit 16 - undervoltage
-		// 	// bit 22-23 mode (0: reset, 1: calibration: 2: run mode )
+	return nil
+}
 
-	*/
+func (f *MotorFeedback) Mode() MotorMode {
+	return f.mode
+}
 
-	return nil
+// True if any of the fault bits is set
+func (f *MotorFeedback) Fault() bool {
+	return f.calibrationError || f.hallEncoderError || f.magneticEncodingError || f.overtemperature || f.overcurrent || f.undervoltage
+}
 
+func (f *MotorFeedback) Faults() []string {
+	var faults []string
+	if f.calibrationError {
+		faults = append(faults, "not calibrated")
+	}
+	if f.hallEncoderError {
+		faults = append(faults, "hall encoding fault")
+	}
+	if f.magneticEncodingError {
+		faults = append(faults, "magnetic encoding fault")
+	}
+	if f.overtemperature {
+		faults = append(faults, "overtemperature")
+	}
+	if f.overcurrent {
+		faults = append(faults, "overcurrent")
+	}
+	if f.undervoltage {
+		faults = append(faults, "undervoltage")
+	}
+	return faults
 }
 
 func (f *MotorFeedback) String() string {
 	var s string
 
-	s += fmt.Sprintf("torque : %02.2f Nm", f.currentTorque)
+	s += fmt.Sprintf("angle : %02.2f rad, speed : %02.2f rad/s, torque : %02.2f Nm, temperature : %02.1f C",
+		f.currentAngle, f.currentSpeed, f.currentTorque, f.currentTemperature)
+	if f.Fault() {
+		s += fmt.Sprintf(", [red]faults : %s[-]", strings.Join(f.Faults(), ", "))
+	}
 
 	// s += "Motor status:\n"
 	// s += fmt.Sprintf("host id : 0x%02X\n", f.hostId)
@@ -5,19 +5,20 @@ import (
 	"gocg/cybergear"
 )
 
+// Reply to a parameter read (communication type 17 or 9)
 type ParameterFrame struct {
-	hostId        byte // Host CAN Id
-	motorId       byte // Motor CAN Id
-	parameter     int16
-	parameterData []byte
+	communicationType cybergear.CommunicationType
+	hostId            byte // Host CAN Id
+	motorId           byte // Motor CAN Id
+	index             uint16
+	data              [4]byte
 }
 
 func (f *ParameterFrame) CyberGearFrameType() cybergear.CommunicationType {
-	return cybergear.COMMUNICATION_STATUS_REPORT
+	return f.communicationType
 }
 
 func (f *ParameterFrame) HostId() byte {
-
 	return f.hostId
 }
 
@@ -25,21 +26,51 @@ func (f *ParameterFrame) MotorId() byte {
 	return f.motorId
 }
 
+func (f *ParameterFrame) Index() uint16 {
+	return f.index
+}
+
+// Byte 4 - 7 of the payload, little endian
+func (f *ParameterFrame) Raw() uint32 {
+	return uint32(f.data[0]) | uint32(f.data[1])<<8 | uint32(f.data[2])<<16 | uint32(f.data[3])<<24
+}
+
+func (f *ParameterFrame) Data() []byte {
+	return f.data[:]
+}
+
+// Value decoded according to the parameter table, false for unknown and string parameters
+func (f *ParameterFrame) Value() (float64, bool) {
+	p, ok := cybergear.ParameterByIndex(f.index)
+	if !ok || p.Type == cybergear.PARAMETER_TYPE_STRING {
+		return 0, false
+	}
+	return p.Decode(f.Raw()), true
+}
+
 func (f *ParameterFrame) String() string {
-	var s string
+	name := "unknown"
+	p, ok := cybergear.ParameterByIndex(f.index)
+	if ok {
+		name = p.Name
+	}
 
-	s += "Parameter:\n"
-	s += fmt.Sprintf("host id : 0x%02X\n", f.hostId)
-	s += fmt.Sprintf("motor id : 0x%02X\n", f.motorId)
-	s += fmt.Sprintf("parameter : %04X\n", f.parameter)
-	s += fmt.Sprintf("value : %+v\n", f.parameterData)
-	return s
+	if value, ok := f.Value(); ok {
+		return fmt.Sprintf("Motor %02X -> host %02X: parameter 0x%04X %s = %s", f.motorId, f.hostId, f.index, name, p.Format(value))
+	}
+	return fmt.Sprintf("Motor %02X -> host %02X: parameter 0x%04X %s = % X", f.motorId, f.hostId, f.index, name, f.data)
 }
 
-func (f *ParameterFrame) Unmarshal(frameBuffer []byte) error {
-	// TBD
+func (f *ParameterFrame) Unmarshal(frame *cybergear.Frame) error {
+	if frame.Len() != 8 {
+		return fmt.Errorf("invalid parameter frame length (%d)", frame.Len())
+	}
 
-	return fmt.Errorf("Parameter frame received: %s", f.String())
+	f.communicationType = frame.CommunicationType()
+	f.motorId = frame.HostId()
+	f.hostId = frame.TargetId()
+	f.index = frame.Uint16(0)
+	copy(f.data[:], frame.Data()[4:8])
 
-	// return nil
+	return nil
 }
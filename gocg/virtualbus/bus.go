@@ -0,0 +1,205 @@
+// In-memory CAN bus for running gocg without hardware. A host port speaks SLCAN like a USB adapter and simulated
+// CyberGear motors answer the frames sent to them. The bus can delay, lose and reorder frames.
+package virtualbus
+
+import (
+	"container/heap"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Extra delay of a reordered frame, so that the frames sent after it overtake it
+const REORDER_DELAY = 2 * time.Millisecond
+
+// CAN frame on the virtual bus
+type Frame struct {
+	Id       uint32 // 11 or 29 bit
+	Extended bool
+	Remote   bool
+	Data     []byte
+}
+
+type Config struct {
+	Latency time.Duration // Delay of every frame
+	Loss    float64       // Probability that a frame is lost [0, 1]
+	Reorder float64       // Probability that a frame is held back by REORDER_DELAY [0, 1]
+	Seed    int64         // Seed of the loss and reorder decisions
+}
+
+// Frames sent, lost and reordered since the bus was created
+type Stats struct {
+	Sent      int
+	Lost      int
+	Reordered int
+}
+
+// Connects the ports. Every frame a port sends is delivered to all other ports, in the order sent unless reordered.
+type Bus struct {
+	config  Config
+	mutex   sync.Mutex
+	random  *rand.Rand
+	ports   map[*Port]bool
+	queue   deliveries
+	seq     uint64
+	stats   Stats
+	wakeCh  chan struct{}
+	closeCh chan struct{}
+	closed  bool
+}
+
+// An endpoint on the bus
+type Port struct {
+	bus     *Bus
+	receive func(Frame)
+}
+
+type delivery struct {
+	due   time.Time
+	seq   uint64
+	from  *Port
+	frame Frame
+}
+
+// Min heap by due time, then by the order sent
+type deliveries []delivery
+
+func (d deliveries) Len() int { return len(d) }
+func (d deliveries) Less(i, j int) bool {
+	if d[i].due.Equal(d[j].due) {
+		return d[i].seq < d[j].seq
+	}
+	return d[i].due.Before(d[j].due)
+}
+func (d deliveries) Swap(i, j int)       { d[i], d[j] = d[j], d[i] }
+func (d *deliveries) Push(x interface{}) { *d = append(*d, x.(delivery)) }
+func (d *deliveries) Pop() interface{} {
+	old := *d
+	x := old[len(old)-1]
+	*d = old[:len(old)-1]
+	return x
+}
+
+func New(config Config) *Bus {
+	b := &Bus{
+		config:  config,
+		random:  rand.New(rand.NewSource(config.Seed)),
+		ports:   map[*Port]bool{},
+		wakeCh:  make(chan struct{}, 1),
+		closeCh: make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+// Stops delivering frames
+func (b *Bus) Close() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if !b.closed {
+		b.closed = true
+		close(b.closeCh)
+	}
+}
+
+func (b *Bus) Stats() Stats {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.stats
+}
+
+// Adds an endpoint. receive is called for every frame sent by the other ports, one frame at a time, and must not
+// block.
+func (b *Bus) Connect(receive func(Frame)) *Port {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	p := &Port{bus: b, receive: receive}
+	b.ports[p] = true
+	return p
+}
+
+func (p *Port) Disconnect() {
+	p.bus.mutex.Lock()
+	defer p.bus.mutex.Unlock()
+	delete(p.bus.ports, p)
+}
+
+// Puts a frame on the bus
+func (p *Port) Send(frame Frame) {
+	b := p.bus
+	frame.Data = append([]byte{}, frame.Data...)
+
+	b.mutex.Lock()
+	b.stats.Sent++
+	if b.config.Loss > 0 && b.random.Float64() < b.config.Loss {
+		b.stats.Lost++
+		b.mutex.Unlock()
+		return
+	}
+
+	due := time.Now().Add(b.config.Latency)
+	if b.config.Reorder > 0 && b.random.Float64() < b.config.Reorder {
+		b.stats.Reordered++
+		due = due.Add(REORDER_DELAY)
+	}
+
+	b.seq++
+	heap.Push(&b.queue, delivery{due: due, seq: b.seq, from: p, frame: frame})
+	b.mutex.Unlock()
+
+	select {
+	case b.wakeCh <- struct{}{}:
+	default:
+	}
+}
+
+// Delivers the frames when they are due
+func (b *Bus) run() {
+	for {
+		b.mutex.Lock()
+		now := time.Now()
+		var ready []delivery
+		for len(b.queue) > 0 && !b.queue[0].due.After(now) {
+			ready = append(ready, heap.Pop(&b.queue).(delivery))
+		}
+		wait := time.Duration(-1)
+		if len(b.queue) > 0 {
+			wait = b.queue[0].due.Sub(now)
+		}
+		ports := make([]*Port, 0, len(b.ports))
+		for p := range b.ports {
+			ports = append(ports, p)
+		}
+		b.mutex.Unlock()
+
+		for _, d := range ready {
+			for _, p := range ports {
+				if p != d.from {
+					p.receive(d.frame)
+				}
+			}
+		}
+		if len(ready) > 0 {
+			continue
+		}
+
+		var timer *time.Timer
+		var timerCh <-chan time.Time
+		if wait >= 0 {
+			timer = time.NewTimer(wait)
+			timerCh = timer.C
+		}
+
+		select {
+		case <-b.closeCh:
+			return
+		case <-b.wakeCh:
+		case <-timerCh:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
@@ -0,0 +1,335 @@
+package virtualbus
+
+import (
+	"gocg/cybergear"
+	"gocg/slcan"
+	"math"
+	"math/bits"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Port that keeps what it receives
+type recorder struct {
+	mutex    sync.Mutex
+	frames   []Frame
+	received []time.Time
+}
+
+func (r *recorder) receive(frame Frame) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.frames = append(r.frames, frame)
+	r.received = append(r.received, time.Now())
+}
+
+func (r *recorder) wait(t *testing.T, n int) []Frame {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		r.mutex.Lock()
+		if len(r.frames) >= n {
+			frames := append([]Frame{}, r.frames...)
+			r.mutex.Unlock()
+			return frames
+		}
+		r.mutex.Unlock()
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("Expected %d frames, got %d", n, len(r.frames))
+	return nil
+}
+
+func TestBusDelivery(t *testing.T) {
+	bus := New(Config{Latency: 5 * time.Millisecond})
+	defer bus.Close()
+
+	r := &recorder{}
+	bus.Connect(r.receive)
+	sender := bus.Connect(func(Frame) { t.Error("Sender received its own frame") })
+
+	sent := time.Now()
+	for i := 0; i < 10; i++ {
+		sender.Send(Frame{Id: uint32(i), Extended: true, Data: []byte{byte(i)}})
+	}
+
+	frames := r.wait(t, 10)
+	for i, frame := range frames {
+		if frame.Id != uint32(i) || frame.Data[0] != byte(i) {
+			t.Errorf("Frame %d out of order: %+v", i, frame)
+		}
+	}
+	if r.received[0].Sub(sent) < 5*time.Millisecond {
+		t.Errorf("Frame delivered after %s, expected the latency of 5ms", r.received[0].Sub(sent))
+	}
+}
+
+func TestBusLossAndReorder(t *testing.T) {
+	bus := New(Config{Loss: 0.2, Reorder: 0.2, Seed: 1})
+	defer bus.Close()
+
+	r := &recorder{}
+	bus.Connect(r.receive)
+	sender := bus.Connect(func(Frame) {})
+
+	for i := 0; i < 500; i++ {
+		sender.Send(Frame{Id: uint32(i), Extended: true})
+	}
+
+	stats := bus.Stats()
+	if stats.Sent != 500 || stats.Lost < 50 || stats.Lost > 150 || stats.Reordered < 50 || stats.Reordered > 150 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+
+	frames := r.wait(t, stats.Sent-stats.Lost)
+	reordered := 0
+	for i := 1; i < len(frames); i++ {
+		if frames[i].Id < frames[i-1].Id {
+			reordered++
+		}
+	}
+	if reordered == 0 {
+		t.Error("Expected frames out of order")
+	}
+}
+
+func TestParseLine(t *testing.T) {
+	for _, line := range []string{"T0200007F80102030405060708", "t12320102", "R0000000F2", "r7FF0"} {
+		frame, err := ParseLine(line)
+		if err != nil {
+			t.Errorf("%s: %v", line, err)
+			continue
+		}
+		if FormatLine(frame) != line {
+			t.Errorf("%s formatted as %s", line, FormatLine(frame))
+		}
+	}
+
+	for _, line := range []string{"", "X123", "T02000", "t8000", "T0200007F9", "T0200007F201", "t1231GG"} {
+		if _, err := ParseLine(line); err == nil {
+			t.Errorf("Expected error for '%s'", line)
+		}
+	}
+}
+
+func FuzzParseLine(f *testing.F) {
+	for _, line := range []string{"T0200007F80102030405060708", "t12320102", "R0000000F2", "r7FF0", "T02000", "t1231GG", ""} {
+		f.Add(line)
+	}
+	f.Fuzz(func(t *testing.T, line string) {
+		frame, err := ParseLine(line)
+		if err != nil {
+			return
+		}
+		again, err := ParseLine(FormatLine(frame))
+		if err != nil || again.Id != frame.Id || again.Extended != frame.Extended || again.Remote != frame.Remote ||
+			string(again.Data) != string(frame.Data) {
+			t.Fatalf("'%s' parsed as %+v, then as %+v (%v)", line, frame, again, err)
+		}
+	})
+}
+
+func newTestAdapter(t *testing.T, bus *Bus) *slcan.Adapter {
+	a := slcan.NewAdapter(bus.NewHostPort())
+	a.SetReplyTimeout(20 * time.Millisecond)
+	if err := a.SetBitrate(1000000); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Open(); err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+// Sends the frame and decodes the first reply
+func request(t *testing.T, a *slcan.Adapter, frame *cybergear.Frame) slcan.Frame {
+	t.Helper()
+	a.Flush()
+	if err := a.WriteLine(slcan.Encode(frame)); err != nil {
+		t.Fatal(err)
+	}
+	line, err := a.ReadFrameLine(100 * time.Millisecond)
+	if err != nil {
+		t.Fatalf("%s: %v", frame, err)
+	}
+	reply, err := slcan.HandleIncomingFrame(line)
+	if err != nil {
+		t.Fatalf("%s: %v", line, err)
+	}
+	return reply
+}
+
+func TestHostPortCommands(t *testing.T) {
+	bus := New(Config{})
+	defer bus.Close()
+	a := slcan.NewAdapter(bus.NewHostPort())
+	a.SetReplyTimeout(20 * time.Millisecond)
+
+	if _, err := a.StatusFlags(); err == nil {
+		t.Error("Expected BEL for F on a closed channel")
+	}
+	if version, err := a.Version(); err != nil || version != HOST_PORT_VERSION {
+		t.Errorf("Unexpected version '%s' (%v)", version, err)
+	}
+	if err := a.Open(); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SetBitrate(500000); err == nil {
+		t.Error("Expected BEL for a bitrate change on an open channel")
+	}
+	if flags, err := a.StatusFlags(); err != nil || flags != 0 {
+		t.Errorf("Unexpected status flags %s (%v)", flags, err)
+	}
+}
+
+func TestMotor(t *testing.T) {
+	bus := New(Config{Latency: time.Millisecond})
+	defer bus.Close()
+	motor := bus.AddMotor(0x7F)
+	a := newTestAdapter(t, bus)
+
+	frame, _ := cybergear.EnableMotorCmd(0x01, 0x7F)
+	feedback := request(t, a, frame).(*slcan.MotorFeedback)
+	if feedback.MotorId() != 0x7F || feedback.HostId() != 0x01 || feedback.Mode() != slcan.OperatingMode {
+		t.Errorf("Unexpected feedback %s", feedback)
+	}
+
+	// Speed mode
+	frame, _ = cybergear.DisableMotorCmd(0x01, 0x7F)
+	request(t, a, frame)
+	frame, _ = cybergear.SetRunMode(0x01, 0x7F, cybergear.SPEED_MODE)
+	request(t, a, frame)
+	frame, _ = cybergear.WriteParameterCmd(0x01, 0x7F, cybergear.PARAMETER_SPD_REF, 3)
+	request(t, a, frame)
+	frame, _ = cybergear.EnableMotorCmd(0x01, 0x7F)
+	request(t, a, frame)
+
+	time.Sleep(300 * time.Millisecond)
+	state := motor.State()
+	if math.Abs(state.Speed-3) > 0.1 || state.RunMode != int(cybergear.SPEED_MODE) || !state.Enabled {
+		t.Errorf("Unexpected state %+v", state)
+	}
+
+	p, _ := cybergear.LookupParameter("PARAMETER_MECH_VEL")
+	frame, _ = cybergear.ReadParameterCmd(0x01, 0x7F, p)
+	value, _ := request(t, a, frame).(*slcan.ParameterFrame).Value()
+	if math.Abs(value-3) > 0.1 {
+		t.Errorf("Unexpected speed %g", value)
+	}
+
+	// Faults stop the motor until cleared
+	motor.SetFaults(slcan.FEEDBACK_OVERTEMPERATURE)
+	frame, _ = cybergear.GetStatusCmd(0x01, 0x7F)
+	if feedback := request(t, a, frame).(*slcan.MotorFeedback); !feedback.Fault() {
+		t.Errorf("Expected fault in %s", feedback)
+	}
+	frame, _ = cybergear.CustomFrameCmd(cybergear.COMMUNICATION_DISABLE_DEVICE, 0x7F, 0x0001, []byte{1, 0, 0, 0, 0, 0, 0, 0})
+	if feedback := request(t, a, frame).(*slcan.MotorFeedback); feedback.Fault() {
+		t.Errorf("Expected faults cleared in %s", feedback)
+	}
+
+	// Fault reports come unrequested
+	motor.ReportFaults(slcan.FAULT_OVERVOLTAGE, slcan.WARNING_OVERTEMPERATURE)
+	line, err := a.ReadFrameLine(100 * time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply, err := slcan.HandleIncomingFrame(line)
+	if fault, ok := reply.(*slcan.FaultFrame); !ok || fault.MotorId() != 0x7F || fault.HostId() != 0x01 ||
+		fault.Raw() != slcan.FAULT_OVERVOLTAGE || fault.RawWarnings() != slcan.WARNING_OVERTEMPERATURE {
+		t.Errorf("Unexpected fault report %v (%v)", reply, err)
+	}
+}
+
+// Feedback frames of the simulated motor decode to its state, within half a step of the 16 bit scale
+func TestFeedbackRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	bus := New(Config{})
+	defer bus.Close()
+
+	for i := 0; i < 1000; i++ {
+		m := bus.AddMotor(byte(r.Intn(cybergear.MAX_CAN_ID + 1)))
+		m.hostId = byte(r.Intn(cybergear.MAX_CAN_ID + 1))
+		m.angle = -4*math.Pi + r.Float64()*8*math.Pi
+		m.speed = -30 + r.Float64()*60
+		m.torque = -12 + r.Float64()*24
+		m.enabled = r.Intn(2) == 1
+		m.faults = uint16(r.Intn(0x40)) << 8
+
+		frame := m.feedback()
+		m.Remove()
+		line := FormatLine(Frame{Id: frame.Id(), Extended: true, Data: frame.Data()}) + string(CR)
+		decoded, err := slcan.HandleIncomingFrame([]byte(line))
+		if err != nil {
+			t.Fatalf("%s: %v", line, err)
+		}
+		feedback := decoded.(*slcan.MotorFeedback)
+
+		mode := slcan.ResetMode
+		if m.enabled {
+			mode = slcan.OperatingMode
+		}
+		if feedback.MotorId() != m.id || feedback.HostId() != m.hostId || feedback.Mode() != mode || feedback.Fault() != (m.faults != 0) ||
+			len(feedback.Faults()) != bits.OnesCount16(m.faults) {
+			t.Fatalf("%s decoded as %s from %02X to %02X, mode %d", line, feedback, feedback.MotorId(), feedback.HostId(), feedback.Mode())
+		}
+
+		for _, v := range []struct {
+			name      string
+			value     float64
+			decoded   float32
+			halfRange float64
+		}{
+			{"angle", m.angle, feedback.Angle(), 4 * math.Pi},
+			{"speed", m.speed, feedback.Speed(), 30},
+			{"torque", m.torque, feedback.Torque(), 12},
+			{"temperature", MOTOR_TEMP, feedback.Temperature(), 0},
+		} {
+			// Half a step, plus float32 rounding
+			tolerance := v.halfRange/65535 + 1e-5
+			if math.Abs(float64(v.decoded)-v.value) > tolerance {
+				t.Fatalf("%s %g decoded as %g from %s", v.name, v.value, v.decoded, line)
+			}
+		}
+	}
+}
+
+// The simulated motor decodes motion control frames to the setpoints they were built from
+func TestMotionControlRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	bus := New(Config{})
+	defer bus.Close()
+	m := bus.AddMotor(0x7F)
+
+	for i := 0; i < 1000; i++ {
+		angle, speed, kp, kd, torque := -4*math.Pi+r.Float64()*8*math.Pi, -30+r.Float64()*60, r.Float64()*500, r.Float64()*5, -12+r.Float64()*24
+		frame, err := cybergear.MotionControlCmd(0x7F, float32(angle), float32(speed), float32(kp), float32(kd), float32(torque))
+		if err != nil {
+			t.Fatal(err)
+		}
+		m.receive(Frame{Id: frame.Id(), Extended: true, Data: frame.Data()})
+
+		m.mutex.Lock()
+		for _, v := range []struct {
+			name     string
+			value    float64
+			decoded  float64
+			min, max float64
+		}{
+			{"angle", angle, m.mitAngle, -4 * math.Pi, 4 * math.Pi},
+			{"speed", speed, m.mitSpeed, -30, 30},
+			{"kp", kp, m.mitKp, 0, 500},
+			{"kd", kd, m.mitKd, 0, 5},
+			{"torque", torque, m.mitTorque, -12, 12},
+		} {
+			// MotionControlCmd rounds to the nearest step, give or take float32 rounding
+			step := (v.max - v.min) / 65535
+			if math.Abs(v.decoded-v.value) > step*0.51 {
+				t.Errorf("%s %g decoded as %g from %s", v.name, v.value, v.decoded, frame)
+			}
+		}
+		m.mutex.Unlock()
+	}
+}
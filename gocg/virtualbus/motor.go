@@ -0,0 +1,437 @@
+package virtualbus
+
+import (
+	"encoding/binary"
+	"gocg/cybergear"
+	"math"
+	"sync"
+	"time"
+)
+
+// Mechanics of the simulated motor, at the output shaft
+const (
+	INERTIA         = 0.01 // kg m2
+	DAMPING         = 0.05 // Nm/(rad/s)
+	TORQUE_CONSTANT = 0.87 // Nm/A
+	SPEED_LOOP_WN   = 40.0 // rad/s, natural frequency of the closed speed loop
+	SPEED_LOOP_ZETA = 0.7
+	SIMULATION_STEP = time.Millisecond
+	MAX_SPEED       = 30.0 // rad/s
+	BUS_VOLTAGE     = 24.0 // V
+	MOTOR_TEMP      = 25.0 // C
+)
+
+// Feedback mode bits (bit 22 - 23 of the CAN id)
+const (
+	modeReset   = 0
+	modeRunning = 2
+)
+
+// Values of the parameters that aren't 0 (or the bottom of their range) out of the box
+var factoryDefaults = map[string]float64{
+	"CONFIG_WR_LIMIT_TORQUE":    12,
+	"CONFIG_WR_CUR_KP":          0.125,
+	"CONFIG_WR_CUR_KI":          0.0158,
+	"CONFIG_WR_CUR_FILT_GAIN":   0.1,
+	"CONFIG_WR_SPD_KP":          1,
+	"CONFIG_WR_SPD_KI":          0.002,
+	"CONFIG_WR_LOC_KP":          30,
+	"CONFIG_WR_SPD_FILT_GAIN":   0.1,
+	"CONFIG_WR_LIMIT_SPD":       2,
+	"CONFIG_WR_LIMIT_CUR":       23,
+	"CONFIG_WR_GEAR_RATIO":      7.75,
+	"CONFIG_WR_MOTOR_OVER_TEMP": 800,
+	"CONFIG_WR_OVER_TEMP_TIME":  20000,
+	"CONFIG_R_RATED_I":          7,
+	"CONFIG_R_LIMIT_I":          23,
+}
+
+// Simulated CyberGear motor. It answers the frames addressed to it like the firmware does and runs its control loops
+// on a rigid shaft with viscous damping.
+type Motor struct {
+	port   *Port
+	mutex  sync.Mutex
+	id     byte
+	hostId byte // Where feedback goes, the host id of the last frame that carried one
+
+	enabled bool
+	faults  uint16 // slcan.FEEDBACK_* bits
+
+	raw map[uint16]uint32 // Parameter values as stored in the motor
+
+	// Motion control (type 1) setpoints
+	mitAngle  float64
+	mitSpeed  float64
+	mitKp     float64
+	mitKd     float64
+	mitTorque float64
+
+	offset       float64 // Mechanical zero
+	angle        float64 // Shaft angle, not counting the offset
+	speed        float64
+	acceleration float64 // Of the speed loop reference model
+	torque       float64
+	updated      time.Time
+}
+
+// Snapshot of the simulated motor
+type State struct {
+	Angle   float64 // rad, from the mechanical zero
+	Speed   float64 // rad/s
+	Torque  float64 // Nm
+	Enabled bool
+	RunMode int // PARAMETER_RUN_MODE
+	Faults  uint16
+}
+
+// Adds a motor with factory settings at rest in position 0
+func (b *Bus) AddMotor(id byte) *Motor {
+	m := &Motor{
+		id:      id,
+		raw:     map[uint16]uint32{},
+		updated: time.Now(),
+	}
+
+	for _, p := range cybergear.Parameters() {
+		if p.Type == cybergear.PARAMETER_TYPE_STRING {
+			continue
+		}
+		value, ok := factoryDefaults[p.Name]
+		if !ok && !p.InRange(0) {
+			value = p.Min
+		}
+		m.raw[p.Index], _ = p.Encode(value)
+	}
+	m.raw[uint16(cybergear.CONFIG_WR_CAN_ID)] = uint32(id)
+
+	// The run area starts from the saved configuration
+	for _, p := range cybergear.Parameters() {
+		if counterpart, ok := cybergear.PersistentCounterpart(p); ok {
+			m.raw[p.Index] = m.raw[counterpart.Index]
+		}
+	}
+
+	m.port = b.Connect(m.receive)
+	return m
+}
+
+// Takes the motor off the bus
+func (m *Motor) Remove() {
+	m.port.Disconnect()
+}
+
+func (m *Motor) Id() byte {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.id
+}
+
+func (m *Motor) State() State {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.simulate(time.Now())
+	return State{
+		Angle:   m.angle - m.offset,
+		Speed:   m.speed,
+		Torque:  m.torque,
+		Enabled: m.enabled,
+		RunMode: int(m.raw[uint16(cybergear.PARAMETER_RUN_MODE)]),
+		Faults:  m.faults,
+	}
+}
+
+// Moves the shaft, as if turned by hand
+func (m *Motor) SetAngle(angle float64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.simulate(time.Now())
+	m.angle = angle + m.offset
+}
+
+// Raises the faults (slcan.FEEDBACK_* bits) until they are cleared with a disable frame
+func (m *Motor) SetFaults(faults uint16) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.faults = faults
+}
+
+// Sends a fault report (type 21) with the fault and warning bits (slcan.FAULT_* and slcan.WARNING_*), as the motor
+// does on its own when a fault is raised or cleared
+func (m *Motor) ReportFaults(faults uint32, warnings uint32) {
+	m.mutex.Lock()
+	frame := cybergear.NewFrame(cybergear.COMMUNICATION_ERROR_REPORT)
+	frame.SetDataArea(uint16(m.id))
+	frame.SetTargetId(m.hostId)
+	var data [8]byte
+	binary.LittleEndian.PutUint32(data[0:4], faults)
+	binary.LittleEndian.PutUint32(data[4:8], warnings)
+	frame.SetData(data[:])
+	m.mutex.Unlock()
+
+	m.port.Send(Frame{Id: frame.Id(), Extended: true, Data: frame.Data()})
+}
+
+// Value of a numeric parameter as the motor would report it, false for unknown and string parameters
+func (m *Motor) Parameter(index uint16) (float64, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	p, ok := cybergear.ParameterByIndex(index)
+	if !ok || p.Type == cybergear.PARAMETER_TYPE_STRING {
+		return 0, false
+	}
+	m.simulate(time.Now())
+	return p.Decode(m.read(p)), true
+}
+
+func (m *Motor) float(index uint16) float64 {
+	return float64(math.Float32frombits(m.raw[index]))
+}
+
+// Advances the simulation to now in SIMULATION_STEP steps
+func (m *Motor) simulate(now time.Time) {
+	dt := SIMULATION_STEP.Seconds()
+	for ; m.updated.Add(SIMULATION_STEP).Before(now); m.updated = m.updated.Add(SIMULATION_STEP) {
+		m.step(dt)
+	}
+}
+
+func (m *Motor) step(dt float64) {
+	limitTorque := m.float(uint16(cybergear.PARAMETER_IMIT_TORQUE))
+	limitCurrent := m.float(uint16(cybergear.PARAMETER_LIMIT_CUR))
+	position := m.angle - m.offset
+
+	torque := 0.0
+	speedLoop := false
+	speedRef := 0.0
+
+	if m.enabled && m.faults == 0 {
+		switch m.raw[uint16(cybergear.PARAMETER_RUN_MODE)] {
+		case uint32(cybergear.OPEARATION_CONTROL_MODE):
+			torque = m.mitKp*(m.mitAngle-position) + m.mitKd*(m.mitSpeed-m.speed) + m.mitTorque
+		case uint32(cybergear.LOCATION_MODE):
+			limitSpeed := m.float(uint16(cybergear.PARAMETER_LIMIT_SPD))
+			speedRef = clamp(m.float(uint16(cybergear.PARAMETER_LOC_KP))*(m.float(uint16(cybergear.PARAMETER_LOC_REF))-position), limitSpeed)
+			speedLoop = true
+		case uint32(cybergear.SPEED_MODE):
+			speedRef = m.float(uint16(cybergear.PARAMETER_SPD_REF))
+			speedLoop = true
+		case uint32(cybergear.CURRENT_MODE):
+			torque = clamp(m.float(uint16(cybergear.PARAMETER_IQ_REF)), limitCurrent) * TORQUE_CONSTANT
+		}
+	}
+
+	if speedLoop {
+		// Second order reference model of the closed speed loop, the torque is what it takes to follow it
+		speedRef = clamp(speedRef, MAX_SPEED)
+		m.acceleration += (SPEED_LOOP_WN*SPEED_LOOP_WN*(speedRef-m.speed) - 2*SPEED_LOOP_ZETA*SPEED_LOOP_WN*m.acceleration) * dt
+		torque = clamp(INERTIA*m.acceleration+DAMPING*m.speed, limitCurrent*TORQUE_CONSTANT)
+	}
+
+	m.torque = clamp(torque, limitTorque)
+	m.acceleration = (m.torque - DAMPING*m.speed) / INERTIA
+	m.speed = clamp(m.speed+m.acceleration*dt, MAX_SPEED)
+	m.angle += m.speed * dt
+}
+
+func clamp(value float64, limit float64) float64 {
+	return math.Max(-limit, math.Min(limit, value))
+}
+
+// Raw value of a parameter, measurements from the simulation
+func (m *Motor) read(p cybergear.ParameterInfo) uint32 {
+	var value float64
+	switch p.Index {
+	case uint16(cybergear.PARAMETER_MECH_POS), uint16(cybergear.CONFIG_R_MECH_POS):
+		value = m.angle - m.offset
+	case uint16(cybergear.PARAMETER_MECH_VEL), uint16(cybergear.CONFIG_R_MECH_VEL):
+		value = m.speed
+	case uint16(cybergear.PARAMETER_IQF), uint16(cybergear.CONFIG_R_IQF), uint16(cybergear.CONFIG_R_IQ):
+		value = m.torque / TORQUE_CONSTANT
+	case uint16(cybergear.CONFIG_R_TORQUE_FDB):
+		value = m.torque
+	case uint16(cybergear.PARAMETER_MECH_VBUS), uint16(cybergear.CONFIG_R_VBUS_V):
+		value = BUS_VOLTAGE
+	case uint16(cybergear.CONFIG_R_VBUS_MV):
+		value = BUS_VOLTAGE * 1000
+	case uint16(cybergear.CONFIG_R_MOTOR_TEMP), uint16(cybergear.CONFIG_R_MCU_TEMP), uint16(cybergear.CONFIG_R_BOARD_TEMP):
+		value = MOTOR_TEMP * 10
+	case uint16(cybergear.CONFIG_R_ROTATION), uint16(cybergear.PARAMETER_MECH_ROTATION):
+		value = math.Trunc((m.angle - m.offset) / (2 * math.Pi))
+	case uint16(cybergear.CONFIG_R_FAULT_STATUS):
+		value = float64(m.faults >> 8)
+	default:
+		return m.raw[p.Index]
+	}
+
+	switch p.Type {
+	case cybergear.PARAMETER_TYPE_FLOAT:
+		return math.Float32bits(float32(value))
+	case cybergear.PARAMETER_TYPE_INT16:
+		return uint32(uint16(int16(value)))
+	case cybergear.PARAMETER_TYPE_INT32:
+		return uint32(int32(value))
+	}
+	return uint32(value)
+}
+
+func (m *Motor) receive(frame Frame) {
+	if !frame.Extended || frame.Remote {
+		return
+	}
+
+	request := &cybergear.Frame{}
+	if request.SetId(frame.Id) != nil || request.SetData(frame.Data) != nil {
+		return
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if request.TargetId() != m.id {
+		return
+	}
+	m.simulate(time.Now())
+
+	var replies []*cybergear.Frame
+	switch request.CommunicationType() {
+	case cybergear.COMMUNICATION_FETCH_DEVICE_ID:
+		m.hostId = request.HostId()
+		replies = append(replies, m.deviceId())
+
+	case cybergear.COMMUNICATION_MOTION_CONTROL_COMMAND:
+		if request.Len() != 8 {
+			return
+		}
+		m.mitTorque = uint16ToFloat(request.DataArea(), -12, 12)
+		m.mitAngle = uint16ToFloat(request.BigEndianUint16(0), -4*math.Pi, 4*math.Pi)
+		m.mitSpeed = uint16ToFloat(request.BigEndianUint16(2), -30, 30)
+		m.mitKp = uint16ToFloat(request.BigEndianUint16(4), 0, 500)
+		m.mitKd = uint16ToFloat(request.BigEndianUint16(6), 0, 5)
+		replies = append(replies, m.feedback())
+
+	case cybergear.COMMUNICATION_ENABLE_DEVICE:
+		m.hostId = request.HostId()
+		m.enabled = true
+		replies = append(replies, m.feedback())
+
+	case cybergear.COMMUNICATION_DISABLE_DEVICE:
+		m.hostId = request.HostId()
+		m.enabled = false
+		if request.Len() > 0 && request.Data()[0] == 1 {
+			m.faults = 0
+		}
+		replies = append(replies, m.feedback())
+
+	case cybergear.COMMUNICATION_SET_MECHANICAL_ZERO_POSITION:
+		m.hostId = request.HostId()
+		m.offset = m.angle
+		replies = append(replies, m.feedback())
+
+	case cybergear.COMMUNICATION_SET_CAN_ID:
+		// bit 16 - 23 the new id, only while disabled
+		m.hostId = request.HostId()
+		if !m.enabled && request.DataArea()>>8 <= cybergear.MAX_CAN_ID {
+			m.id = byte(request.DataArea() >> 8)
+			m.raw[uint16(cybergear.CONFIG_WR_CAN_ID)] = uint32(m.id)
+		}
+		replies = append(replies, m.deviceId())
+
+	case cybergear.COMMUNICATION_GET_STATUS:
+		m.hostId = request.HostId()
+		replies = append(replies, m.feedback())
+
+	case cybergear.COMMUNICATION_READ_SINGLE_PARAM:
+		m.hostId = request.HostId()
+		replies = m.readParameter(request)
+
+	case cybergear.COMMUNICATION_WRITE_SINGLE_PARAM:
+		m.hostId = request.HostId()
+		m.writeParameter(request)
+		replies = append(replies, m.feedback())
+	}
+
+	for _, reply := range replies {
+		m.port.Send(Frame{Id: reply.Id(), Extended: true, Data: reply.Data()})
+	}
+}
+
+// Inverse of the float to uint16 mapping of the motion control frame
+func uint16ToFloat(value uint16, min float64, max float64) float64 {
+	return float64(value)*(max-min)/65535 + min
+}
+
+func floatToUint16(value float64, min float64, max float64) uint16 {
+	value = math.Max(min, math.Min(max, value))
+	return uint16(math.Round((value - min) * 65535 / (max - min)))
+}
+
+// Type 2: bit 8 - 15 motor id, fault and mode bits above, host id in the target field
+func (m *Motor) feedback() *cybergear.Frame {
+	mode := uint16(modeReset)
+	if m.enabled {
+		mode = modeRunning
+	}
+
+	frame := cybergear.NewFrame(cybergear.COMMUNICATION_STATUS_REPORT)
+	frame.SetDataArea(uint16(m.id) | m.faults&^0xFF | mode<<14)
+	frame.SetTargetId(m.hostId)
+	frame.SetBigEndianUint16(0, floatToUint16(m.angle-m.offset, -4*math.Pi, 4*math.Pi))
+	frame.SetBigEndianUint16(2, floatToUint16(m.speed, -30, 30))
+	frame.SetBigEndianUint16(4, floatToUint16(m.torque, -12, 12))
+	frame.SetBigEndianUint16(6, uint16(MOTOR_TEMP*10))
+	return frame
+}
+
+// Type 0: the motor id in bit 8 - 15, 0xFE as target and the 64 bit MCU id as payload
+func (m *Motor) deviceId() *cybergear.Frame {
+	frame := cybergear.NewFrame(cybergear.COMMUNICATION_FETCH_DEVICE_ID)
+	frame.SetHostId(m.id)
+	frame.SetTargetId(0xFE)
+	var mcuId [8]byte
+	binary.LittleEndian.PutUint64(mcuId[:], 0x5642555300000000|uint64(m.id))
+	frame.SetData(mcuId[:])
+	return frame
+}
+
+// Answers a run area parameter read (type 17). Config area reads (type 9) aren't documented and go unanswered.
+func (m *Motor) readParameter(request *cybergear.Frame) []*cybergear.Frame {
+	if request.Len() < 2 {
+		return nil
+	}
+	p, ok := cybergear.ParameterByIndex(request.Uint16(0))
+	if !ok || p.Config() {
+		return nil
+	}
+
+	frame := cybergear.NewFrame(cybergear.COMMUNICATION_READ_SINGLE_PARAM)
+	frame.SetHostId(m.id)
+	frame.SetTargetId(m.hostId)
+	frame.SetUint16(0, p.Index)
+	frame.SetUint32(4, m.read(p))
+	return []*cybergear.Frame{frame}
+}
+
+// Stores the value of a writable run area parameter. Config area writes, read only parameters and values out of range
+// are ignored, which the host notices when it reads the parameter back.
+func (m *Motor) writeParameter(request *cybergear.Frame) {
+	if request.Len() != 8 {
+		return
+	}
+	p, ok := cybergear.ParameterByIndex(request.Uint16(0))
+	if !ok || !p.Writable || p.Config() {
+		return
+	}
+	raw := request.Uint32(4)
+
+	if !p.InRange(p.Decode(raw)) {
+		return
+	}
+	if p.Index == uint16(cybergear.PARAMETER_RUN_MODE) && m.enabled {
+		// The run mode only changes while the motor is stopped
+		return
+	}
+	m.raw[p.Index] = raw
+}
@@ -0,0 +1,299 @@
+package virtualbus
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	CR  byte = '\r'
+	BEL byte = 0x07
+
+	DEFAULT_READ_TIMEOUT = 10 * time.Millisecond
+	HOST_PORT_VERSION    = "1013"
+)
+
+type channelState int
+
+const (
+	channelClosed channelState = iota
+	channelOpen
+	channelListen
+)
+
+// SLCAN adapter on the bus, used in place of the serial port of a USB adapter. Like the CANable firmware, it's silent
+// on success of setup commands and frames, and answers errors with BEL.
+type HostPort struct {
+	port        *Port
+	readTimeout time.Duration
+	mutex       sync.Mutex
+	state       channelState
+	bitrate     string
+	timestamps  bool
+	statusFlags byte
+	started     time.Time
+	tx          []byte // Written bytes not yet terminated by CR
+	rx          []byte
+	dataCh      chan struct{}
+	closed      bool
+}
+
+func (b *Bus) NewHostPort() *HostPort {
+	h := &HostPort{readTimeout: DEFAULT_READ_TIMEOUT, bitrate: "S8", started: time.Now(), dataCh: make(chan struct{}, 1)}
+	h.port = b.Connect(h.receive)
+	return h
+}
+
+// How long Read waits for data before it returns 0 bytes, like the read timeout of a serial port
+func (h *HostPort) SetReadTimeout(timeout time.Duration) {
+	h.readTimeout = timeout
+}
+
+// Status flags reported by the F command
+func (h *HostPort) SetStatusFlags(flags byte) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.statusFlags = flags
+}
+
+// CAN bitrate command last received (S0 - S8)
+func (h *HostPort) Bitrate() string {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.bitrate
+}
+
+func (h *HostPort) Read(buf []byte) (int, error) {
+	deadline := time.Now().Add(h.readTimeout)
+
+	for {
+		h.mutex.Lock()
+		if h.closed {
+			h.mutex.Unlock()
+			return 0, io.EOF
+		}
+		if len(h.rx) > 0 {
+			n := copy(buf, h.rx)
+			h.rx = h.rx[n:]
+			h.mutex.Unlock()
+			return n, nil
+		}
+		h.mutex.Unlock()
+
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			return 0, nil
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-h.dataCh:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+func (h *HostPort) Write(buf []byte) (int, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.closed {
+		return 0, io.ErrClosedPipe
+	}
+
+	h.tx = append(h.tx, buf...)
+	for {
+		i := indexOf(h.tx, CR)
+		if i < 0 {
+			break
+		}
+		line := string(h.tx[:i])
+		h.tx = h.tx[i+1:]
+		h.reply(h.command(line))
+	}
+	return len(buf), nil
+}
+
+// Discards the received data
+func (h *HostPort) Flush() error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.rx = nil
+	return nil
+}
+
+func (h *HostPort) Close() error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if !h.closed {
+		h.closed = true
+		h.port.Disconnect()
+	}
+	return nil
+}
+
+func indexOf(buf []byte, c byte) int {
+	for i, b := range buf {
+		if b == c {
+			return i
+		}
+	}
+	return -1
+}
+
+// Called with the mutex held
+func (h *HostPort) reply(s string) {
+	if s == "" {
+		return
+	}
+	h.rx = append(h.rx, s...)
+	select {
+	case h.dataCh <- struct{}{}:
+	default:
+	}
+}
+
+// Runs an SLCAN command and returns the reply
+func (h *HostPort) command(line string) string {
+	bel := string([]byte{BEL})
+	if line == "" {
+		return ""
+	}
+
+	switch line[0] {
+	case 'S':
+		if h.state != channelClosed || len(line) != 2 || line[1] < '0' || line[1] > '8' {
+			return bel
+		}
+		h.bitrate = line
+	case 'O', 'L':
+		if h.state != channelClosed || len(line) != 1 {
+			return bel
+		}
+		h.state = channelOpen
+		if line[0] == 'L' {
+			h.state = channelListen
+		}
+	case 'C':
+		h.state = channelClosed
+	case 'Z':
+		if h.state != channelClosed || (line != "Z0" && line != "Z1") {
+			return bel
+		}
+		h.timestamps = line == "Z1"
+	case 'V':
+		return "V" + HOST_PORT_VERSION + string(CR)
+	case 'v':
+		return "vvirtualbus" + string(CR)
+	case 'N':
+		return "NVBUS" + string(CR)
+	case 'F':
+		if h.state == channelClosed {
+			return bel
+		}
+		return fmt.Sprintf("F%02X%c", h.statusFlags, CR)
+	case 't', 'T', 'r', 'R':
+		if h.state != channelOpen {
+			return bel
+		}
+		frame, err := ParseLine(line)
+		if err != nil {
+			return bel
+		}
+		h.port.Send(frame)
+	default:
+		return bel
+	}
+	return ""
+}
+
+func (h *HostPort) receive(frame Frame) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.closed || h.state == channelClosed {
+		return
+	}
+
+	line := FormatLine(frame)
+	if h.timestamps {
+		line += fmt.Sprintf("%04X", time.Since(h.started).Milliseconds()%60000)
+	}
+	h.reply(line + string(CR))
+}
+
+// SLCAN frame line (without CR) of a frame: t<3 hex digit id><dlc><data>, T<8 hex digit id><dlc><data>, or r / R
+// for remote frames
+func FormatLine(frame Frame) string {
+	var line string
+	switch {
+	case frame.Extended && frame.Remote:
+		line = fmt.Sprintf("R%08X%d", frame.Id, len(frame.Data))
+	case frame.Extended:
+		line = fmt.Sprintf("T%08X%d%X", frame.Id, len(frame.Data), frame.Data)
+	case frame.Remote:
+		line = fmt.Sprintf("r%03X%d", frame.Id, len(frame.Data))
+	default:
+		line = fmt.Sprintf("t%03X%d%X", frame.Id, len(frame.Data), frame.Data)
+	}
+	return line
+}
+
+// Inverse of FormatLine. The data of a remote frame is DLC zero bytes.
+func ParseLine(line string) (Frame, error) {
+	if line == "" {
+		return Frame{}, fmt.Errorf("empty frame line")
+	}
+
+	frame := Frame{}
+	idLength := 3
+	switch line[0] {
+	case 'T':
+		frame.Extended, idLength = true, 8
+	case 'R':
+		frame.Extended, frame.Remote, idLength = true, true, 8
+	case 'r':
+		frame.Remote = true
+	case 't':
+	default:
+		return Frame{}, fmt.Errorf("not a frame line: '%s'", line)
+	}
+
+	if len(line) < 1+idLength+1 {
+		return Frame{}, fmt.Errorf("frame line too short: '%s'", line)
+	}
+
+	id, err := strconv.ParseUint(line[1:1+idLength], 16, 32)
+	if err != nil || (frame.Extended && id > 0x1FFFFFFF) || (!frame.Extended && id > 0x7FF) {
+		return Frame{}, fmt.Errorf("invalid CAN id in '%s'", line)
+	}
+	frame.Id = uint32(id)
+
+	dlc := int(line[1+idLength]) - '0'
+	if dlc < 0 || dlc > 8 {
+		return Frame{}, fmt.Errorf("invalid DLC in '%s'", line)
+	}
+
+	data := line[2+idLength:]
+	if frame.Remote {
+		if data != "" {
+			return Frame{}, fmt.Errorf("remote frame with data: '%s'", line)
+		}
+		frame.Data = make([]byte, dlc)
+		return frame, nil
+	}
+
+	if len(data) != 2*dlc {
+		return Frame{}, fmt.Errorf("frame length doesn't match DLC %d: '%s'", dlc, line)
+	}
+	frame.Data, err = hex.DecodeString(data)
+	if err != nil {
+		return Frame{}, fmt.Errorf("invalid data in '%s'", line)
+	}
+	return frame, nil
+}